@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// apiKeys maps API key SHA-256 hex digests to their Principal.
+type apiKeys map[string]Principal
+
+type apiKeysFile struct {
+	Keys []struct {
+		Name   string   `yaml:"name"`
+		SHA256 string   `yaml:"sha256"`
+		Scopes []string `yaml:"scopes"`
+	} `yaml:"keys"`
+}
+
+func loadAPIKeys(path string) (apiKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file apiKeysFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("API keys file %s: %w", path, err)
+	}
+
+	out := make(apiKeys, len(file.Keys))
+	for i, k := range file.Keys {
+		if k.Name == "" {
+			return nil, fmt.Errorf("API keys file %s: key %d: missing name", path, i)
+		}
+
+		if b, err := hex.DecodeString(k.SHA256); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("API keys file %s: key %s: sha256 must be a hex encoded SHA-256 digest", path, k.Name)
+		}
+
+		p := Principal{Subject: k.Name, Method: "api_key"}
+		for _, s := range k.Scopes {
+			scope, err := ParseScope(s)
+			if err != nil {
+				return nil, fmt.Errorf("API keys file %s: key %s: %w", path, k.Name, err)
+			}
+			p.Scopes = append(p.Scopes, scope)
+		}
+
+		out[k.SHA256] = p
+	}
+
+	return out, nil
+}
+
+func (k apiKeys) authenticate(key string) (Principal, error) {
+	p, ok := k[HashAPIKey(key)]
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: invalid API key", ErrUnauthenticated)
+	}
+	return p, nil
+}
+
+// HashAPIKey returns the hex encoded SHA-256 digest of an API key, as stored in the API keys file.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey returns a new random API key.
+func GenerateAPIKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
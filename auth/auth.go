@@ -0,0 +1,161 @@
+// Package auth includes the API key and JWT authentication shared by the servers.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/agukrapo/ports/config"
+)
+
+// Scope represents a permission granted to a Principal.
+type Scope string
+
+// Available scopes, ScopeAdmin grants every other scope.
+const (
+	ScopeRead  Scope = "ports:read"
+	ScopeWrite Scope = "ports:write"
+	ScopeAdmin Scope = "ports:admin"
+)
+
+// ParseScope parses a Scope name.
+func ParseScope(s string) (Scope, error) {
+	switch sc := Scope(s); sc {
+	case ScopeRead, ScopeWrite, ScopeAdmin:
+		return sc, nil
+	}
+	return "", fmt.Errorf("invalid scope %q", s)
+}
+
+var (
+	// ErrUnauthenticated is returned when the credentials are missing or invalid.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrForbidden is returned when the Principal lacks the required scope.
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Principal represents an authenticated caller.
+type Principal struct {
+	Subject string
+	Method  string
+	Scopes  []Scope
+}
+
+// Anonymous is the Principal of every request when authentication is disabled, it holds every scope.
+var Anonymous = Principal{Subject: "anonymous", Scopes: []Scope{ScopeAdmin}}
+
+// Has tells whether the Principal holds a scope.
+func (p Principal) Has(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Credentials represents the credentials presented by a request, either field may be empty.
+type Credentials struct {
+	// APIKey is the value of the X-API-Key header.
+	APIKey string
+	// Authorization is the value of the Authorization header.
+	Authorization string
+}
+
+// Authenticator verifies API keys and JWT bearer tokens.
+type Authenticator struct {
+	cfg  config.Auth
+	keys apiKeys
+	jwks *jwks
+}
+
+// New instantiates an Authenticator, loading the API keys and JWKS files set in the configuration.
+func New(cfg config.Auth) (*Authenticator, error) {
+	a := &Authenticator{cfg: cfg}
+
+	if cfg.APIKeysFile != "" {
+		keys, err := loadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+	}
+
+	if cfg.JWKSFile != "" {
+		set, err := loadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.jwks = set
+	}
+
+	return a, nil
+}
+
+// Enabled tells whether the Authenticator checks credentials, Authenticate returns Anonymous otherwise.
+func (a *Authenticator) Enabled() bool {
+	return a != nil && a.cfg.Enabled()
+}
+
+// Authenticate resolves the Principal presenting the Credentials.
+// Bearer tokens in the JWT compact form are verified against the JWKS, any other one is taken as an API key.
+func (a *Authenticator) Authenticate(c Credentials) (Principal, error) {
+	if !a.Enabled() {
+		return Anonymous, nil
+	}
+
+	if c.APIKey != "" {
+		return a.keys.authenticate(c.APIKey)
+	}
+
+	token, ok := bearer(c.Authorization)
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: missing credentials", ErrUnauthenticated)
+	}
+
+	if strings.Count(token, ".") == 2 {
+		if a.jwks == nil {
+			return Principal{}, fmt.Errorf("%w: JWT authentication disabled", ErrUnauthenticated)
+		}
+		return a.jwks.authenticate(token, a.cfg.Issuer, a.cfg.Audience)
+	}
+
+	return a.keys.authenticate(token)
+}
+
+// Authorize authenticates the Credentials and checks the resulting Principal holds a scope.
+func (a *Authenticator) Authorize(c Credentials, scope Scope) (Principal, error) {
+	p, err := a.Authenticate(c)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	if !p.Has(scope) {
+		return Principal{}, fmt.Errorf("%w: %s scope required", ErrForbidden, scope)
+	}
+
+	return p, nil
+}
+
+func bearer(header string) (string, bool) {
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying a Principal.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the Principal carried by ctx, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator_disabled(t *testing.T) {
+	a, err := New(config.Auth{})
+	require.NoError(t, err)
+	require.False(t, a.Enabled())
+
+	p, err := a.Authorize(Credentials{}, ScopeAdmin)
+	require.NoError(t, err)
+	require.Equal(t, Anonymous, p)
+}
+
+func TestAuthenticator_apiKey(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+keys:
+  - name: reader
+    sha256: `+HashAPIKey("read-key")+`
+    scopes: [ports:read]
+  - name: admin
+    sha256: `+HashAPIKey("admin-key")+`
+    scopes: [ports:admin]
+`), 0o600))
+
+	a, err := New(config.Auth{APIKeysFile: path})
+	require.NoError(t, err)
+
+	p, err := a.Authorize(Credentials{APIKey: "read-key"}, ScopeRead)
+	require.NoError(t, err)
+	require.Equal(t, "reader", p.Subject)
+
+	_, err = a.Authorize(Credentials{Authorization: "Bearer read-key"}, ScopeWrite)
+	require.ErrorIs(t, err, ErrForbidden)
+
+	_, err = a.Authorize(Credentials{Authorization: "bearer admin-key"}, ScopeWrite)
+	require.NoError(t, err)
+
+	_, err = a.Authorize(Credentials{APIKey: "wrong"}, ScopeRead)
+	require.ErrorIs(t, err, ErrUnauthenticated)
+
+	_, err = a.Authorize(Credentials{}, ScopeRead)
+	require.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestAuthenticator_jwt(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	set, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": "test",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, set, 0o600))
+
+	a, err := New(config.Auth{JWKSFile: path, Issuer: "issuer", Audience: "ports"})
+	require.NoError(t, err)
+
+	sign := func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		s, err := token.SignedString(key)
+		require.NoError(t, err)
+		return "Bearer " + s
+	}
+
+	exp := time.Now().Add(time.Hour).Unix()
+
+	p, err := a.Authorize(Credentials{Authorization: sign(jwt.MapClaims{
+		"sub": "user", "iss": "issuer", "aud": "ports", "exp": exp, "scope": "ports:read other:scope",
+	})}, ScopeRead)
+	require.NoError(t, err)
+	require.Equal(t, Principal{Subject: "user", Method: "jwt", Scopes: []Scope{ScopeRead}}, p)
+
+	tests := map[string]struct {
+		claims jwt.MapClaims
+		err    error
+	}{
+		"missing scope":    {jwt.MapClaims{"iss": "issuer", "aud": "ports", "exp": exp, "scp": []string{"ports:read"}}, ErrForbidden},
+		"expired":          {jwt.MapClaims{"iss": "issuer", "aud": "ports", "exp": time.Now().Add(-time.Hour).Unix(), "scope": "ports:write"}, ErrUnauthenticated},
+		"no expiration":    {jwt.MapClaims{"iss": "issuer", "aud": "ports", "scope": "ports:write"}, ErrUnauthenticated},
+		"invalid issuer":   {jwt.MapClaims{"iss": "other", "aud": "ports", "exp": exp, "scope": "ports:write"}, ErrUnauthenticated},
+		"invalid audience": {jwt.MapClaims{"iss": "issuer", "aud": "other", "exp": exp, "scope": "ports:write"}, ErrUnauthenticated},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := a.Authorize(Credentials{Authorization: sign(tt.claims)}, ScopeWrite)
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"exp": exp, "scope": "ports:admin"}).SignedString(other)
+	require.NoError(t, err)
+
+	_, err = a.Authorize(Credentials{Authorization: "Bearer " + forged}, ScopeRead)
+	require.ErrorIs(t, err, ErrUnauthenticated)
+}
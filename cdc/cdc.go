@@ -0,0 +1,181 @@
+// Package cdc includes the relay publishing the port change events written to the outbox.
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/rs/zerolog/log"
+)
+
+// pruneInterval is the time between the change log prunes.
+const pruneInterval = time.Hour
+
+// Event represents a port insert, update or delete.
+type Event struct {
+	ID int64 `json:"id"`
+	// Seq orders the Events by commit.
+	Seq    int64          `json:"seq"`
+	Key    string         `json:"key"`
+	Type   string         `json:"type"`
+	Fields []string       `json:"fields"`
+	Before *database.Port `json:"before,omitempty"`
+	After  *database.Port `json:"after,omitempty"`
+	Time   time.Time      `json:"time"`
+}
+
+// NewEvent decodes an outbox Change.
+func NewEvent(c *database.Change) (*Event, error) {
+	out := &Event{
+		ID:   c.ID,
+		Key:  c.Key,
+		Type: c.Type,
+		Time: c.CreatedAt,
+	}
+	if c.Seq != nil {
+		out.Seq = *c.Seq
+	}
+
+	if err := json.Unmarshal([]byte(c.Fields), &out.Fields); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if out.Before, err = decode(c.Before); err != nil {
+		return nil, err
+	}
+	if out.After, err = decode(c.After); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func decode(data string) (*database.Port, error) {
+	if data == "" {
+		return nil, nil
+	}
+
+	var out database.Port
+	if err := json.Unmarshal([]byte(data), &out); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// Publisher represents a destination of the change events.
+type Publisher interface {
+	// Publish delivers a batch of Events in order, a failure makes the whole batch be published again.
+	Publish(context.Context, []*Event) error
+	Close() error
+}
+
+// Store represents the storage of the outbox table, implemented by the database backend.
+type Store interface {
+	SequenceChanges(context.Context, int) (int, error)
+	PublishChanges(context.Context, int, func([]database.Change) error) (int, error)
+	PruneChanges(context.Context, time.Time, bool) (int64, error)
+}
+
+// Relay polls the outbox, sequencing the committed changes and publishing them with at-least-once delivery,
+// and prunes the change log.
+type Relay struct {
+	store     Store
+	publisher Publisher
+	interval  time.Duration
+	batch     int
+	retention time.Duration
+}
+
+// NewRelay instantiates a new Relay, a nil Publisher only pruning the change log.
+func NewRelay(store Store, publisher Publisher, interval time.Duration, batch int, retention time.Duration) *Relay {
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		interval:  interval,
+		batch:     batch,
+		retention: retention,
+	}
+}
+
+// Run relays the changes every poll interval until the context is done.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		if r.publisher == nil {
+			if err := r.sequence(ctx); err != nil && ctx.Err() == nil {
+				log.Error().Err(err).Msg("Change log sequencing failed")
+			}
+		} else if _, err := r.Drain(ctx); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("Change events relay failed")
+		}
+
+		if r.retention > 0 && time.Since(pruned) >= pruneInterval {
+			r.prune(ctx)
+			pruned = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain sequences the committed changes, then publishes the pending ones in batches until none is left,
+// returning the number published.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	if err := r.sequence(ctx); err != nil {
+		return 0, err
+	}
+
+	var total int
+	for {
+		n, err := r.store.PublishChanges(ctx, r.batch, func(changes []database.Change) error {
+			events := make([]*Event, len(changes))
+			for i := range changes {
+				e, err := NewEvent(&changes[i])
+				if err != nil {
+					return err
+				}
+				events[i] = e
+			}
+
+			return r.publisher.Publish(ctx, events)
+		})
+		total += n
+		if err != nil || n < r.batch {
+			return total, err
+		}
+	}
+}
+
+// sequence stamps the committed changes in batches until none is left, making them readable by the followers.
+func (r *Relay) sequence(ctx context.Context) error {
+	for {
+		n, err := r.store.SequenceChanges(ctx, r.batch)
+		if err != nil || n < r.batch {
+			return err
+		}
+	}
+}
+
+// prune deletes the changes older than the retention, keeping the ones still to publish.
+func (r *Relay) prune(ctx context.Context) {
+	n, err := r.store.PruneChanges(ctx, time.Now().Add(-r.retention), r.publisher != nil)
+	if err != nil {
+		log.Error().Err(err).Msg("Change log prune failed")
+		return
+	}
+
+	if n > 0 {
+		log.Info().Int64("changes", n).Msg("Change log pruned")
+	}
+}
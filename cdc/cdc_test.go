@@ -0,0 +1,170 @@
+package cdc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/stretchr/testify/require"
+)
+
+func sqliteDB(t *testing.T) *database.Database {
+	cfg := config.Default().Database
+	cfg.DSN = "sqlite://" + filepath.Join(t.TempDir(), "ports.db")
+
+	m, err := database.NewMigrator(cfg)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+
+	db, err := database.New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	return db
+}
+
+func write(t *testing.T, db *database.Database) {
+	ctx := context.Background()
+
+	require.NoError(t, db.Merge(ctx, &database.Port{Key: "AEAJM", Name: "Ajman"}, database.MergeOptions{}))
+	require.NoError(t, db.Merge(ctx, &database.Port{Key: "AEAUH", Name: "Abu Dhabi"}, database.MergeOptions{}))
+	require.NoError(t, db.Merge(ctx, &database.Port{Key: "AEAJM", Name: "Ajman Port"}, database.MergeOptions{}))
+	require.NoError(t, db.Delete(ctx, "AEAUH"))
+}
+
+func TestRelay_file(t *testing.T) {
+	db := sqliteDB(t)
+	write(t, db)
+
+	path := filepath.Join(t.TempDir(), "changes.ndjson")
+	relay := NewRelay(db, NewFile(path), time.Second, 3, 0)
+
+	n, err := relay.Drain(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var events []Event
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &e))
+		events = append(events, e)
+	}
+	require.NoError(t, sc.Err())
+	require.Len(t, events, 4)
+
+	require.Equal(t, database.ChangeCreate, events[0].Type)
+	require.Nil(t, events[0].Before)
+	require.Equal(t, "Ajman", events[0].After.Name)
+
+	require.Equal(t, database.ChangeUpdate, events[2].Type)
+	require.Equal(t, []string{"name"}, events[2].Fields)
+	require.Equal(t, "Ajman", events[2].Before.Name)
+	require.Equal(t, "Ajman Port", events[2].After.Name)
+
+	require.Equal(t, database.ChangeDelete, events[3].Type)
+	require.Equal(t, "AEAUH", events[3].Key)
+	require.Nil(t, events[3].After)
+
+	n, err = relay.Drain(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestRelay_webhookRetry(t *testing.T) {
+	db := sqliteDB(t)
+	write(t, db)
+
+	var ids []string
+	fail := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get("X-Event-Id"))
+		if fail && len(ids) == 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	relay := NewRelay(db, NewWebhook(srv.URL), time.Second, 10, 0)
+
+	_, err := relay.Drain(context.Background())
+	require.Error(t, err)
+
+	fail = false
+	n, err := relay.Drain(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	require.Equal(t, []string{"1", "2", "1", "2", "3", "4"}, ids, "the failed batch is delivered again")
+}
+
+func TestRelay_prune(t *testing.T) {
+	db := sqliteDB(t)
+	write(t, db)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	NewRelay(db, nil, time.Second, 10, time.Nanosecond).Run(ctx)
+
+	first, last, err := db.ChangeLogBounds(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(4), first, "the last change is kept for the sequence to carry on")
+	require.Equal(t, int64(4), last)
+
+	require.NoError(t, db.Merge(context.Background(), &database.Port{Key: "AEAUH", Name: "Abu Dhabi"}, database.MergeOptions{}))
+	_, err = db.SequenceChanges(context.Background(), 10)
+	require.NoError(t, err)
+
+	changes, err := db.Changes(context.Background(), last, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, int64(5), *changes[0].Seq)
+}
+
+func TestRelay_pruneUnpublished(t *testing.T) {
+	db := sqliteDB(t)
+	write(t, db)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	NewRelay(db, NewWebhook(srv.URL), time.Second, 10, time.Nanosecond).Run(ctx)
+
+	first, last, err := db.ChangeLogBounds(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), first, "the changes still to publish are kept")
+	require.Equal(t, int64(4), last)
+}
+
+func TestOpen(t *testing.T) {
+	p, err := Open(config.Default().CDC)
+	require.NoError(t, err)
+	require.Nil(t, p)
+
+	cfg := config.Default().CDC
+	cfg.Publisher = "kafka"
+	_, err = Open(cfg)
+	require.Error(t, err)
+}
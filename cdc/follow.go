@@ -0,0 +1,102 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+)
+
+const (
+	followInterval = 500 * time.Millisecond
+	followBatch    = 500
+)
+
+var (
+	// ErrInvalidToken is returned when a resume token is malformed, or from another change log.
+	ErrInvalidToken = errors.New("invalid resume token")
+	// ErrExpiredToken is returned when the changes following a resume token were pruned.
+	ErrExpiredToken = errors.New("resume token expired")
+)
+
+// ChangeLog represents the storage of the change log, implemented by the service.
+type ChangeLog interface {
+	Changes(context.Context, int64, int) ([]database.Change, error)
+	ChangeLogBounds(context.Context) (int64, int64, error)
+}
+
+// Token returns the resume token following an Event.
+func (e *Event) Token() string {
+	return strconv.FormatInt(e.Seq, 10)
+}
+
+// Follow calls fn for every change made after the one identified by a resume token, or from now on when empty,
+// polling the change log until the context is done or fn fails.
+// Changes are followed in commit order once sequenced by a Relay, so none committed late is skipped.
+func Follow(ctx context.Context, log ChangeLog, token string, fn func(*Event) error) error {
+	after, err := start(ctx, log, token)
+	if err != nil {
+		return err
+	}
+
+	return poll(ctx, log, after, fn)
+}
+
+// poll calls fn for every change following a sequence number, until the context is done or fn fails.
+func poll(ctx context.Context, log ChangeLog, after int64, fn func(*Event) error) error {
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+
+	for {
+		changes, err := log.Changes(ctx, after, followBatch)
+		if err != nil {
+			return err
+		}
+
+		for i := range changes {
+			e, err := NewEvent(&changes[i])
+			if err != nil {
+				return err
+			}
+
+			if err := fn(e); err != nil {
+				return err
+			}
+			after = e.Seq
+		}
+
+		if len(changes) < followBatch {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// start returns the sequence number of the change a resume token follows.
+func start(ctx context.Context, log ChangeLog, token string) (int64, error) {
+	first, last, err := log.ChangeLogBounds(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if token == "" {
+		return last, nil
+	}
+
+	after, err := strconv.ParseInt(token, 10, 64)
+	if err != nil || after < 0 || (last > 0 && after > last) {
+		return 0, fmt.Errorf("%w %q", ErrInvalidToken, token)
+	}
+
+	if first > after+1 {
+		return 0, ErrExpiredToken
+	}
+
+	return after, nil
+}
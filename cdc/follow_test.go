@@ -0,0 +1,132 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/stretchr/testify/require"
+)
+
+// changeLog represents an in-memory ChangeLog.
+type changeLog struct {
+	mu      sync.Mutex
+	changes []database.Change
+}
+
+// add appends a change as sequenced once committed.
+func (l *changeLog) add(id int64, created time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seq := id
+	if n := len(l.changes); n > 0 {
+		seq = *l.changes[n-1].Seq + 1
+	}
+
+	l.changes = append(l.changes, database.Change{ID: id, Seq: &seq, Key: "K", Type: database.ChangeCreate, Fields: "[]", CreatedAt: created})
+}
+
+func (l *changeLog) Changes(_ context.Context, after int64, limit int) ([]database.Change, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var out []database.Change
+	for _, c := range l.changes {
+		if *c.Seq > after && len(out) < limit {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (l *changeLog) ChangeLogBounds(context.Context) (int64, int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.changes) == 0 {
+		return 0, 0, nil
+	}
+	return *l.changes[0].Seq, *l.changes[len(l.changes)-1].Seq, nil
+}
+
+var errStop = errors.New("stop")
+
+// follow returns the IDs of the first n events followed from a token.
+func follow(t *testing.T, log ChangeLog, token string, n int) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var ids []int64
+	err := Follow(ctx, log, token, func(e *Event) error {
+		ids = append(ids, e.ID)
+		require.Equal(t, e.Seq, mustParse(t, e.Token()))
+		if len(ids) == n {
+			return errStop
+		}
+		return nil
+	})
+	if errors.Is(err, errStop) {
+		err = nil
+	}
+
+	return ids, err
+}
+
+func mustParse(t *testing.T, token string) int64 {
+	id, err := start(context.Background(), &changeLog{}, token)
+	require.NoError(t, err)
+	return id
+}
+
+func TestFollow(t *testing.T) {
+	old := time.Now().Add(-time.Minute)
+
+	log := &changeLog{}
+	for id := int64(3); id <= 5; id++ {
+		log.add(id, old)
+	}
+
+	ids, err := follow(t, log, "3", 2)
+	require.NoError(t, err)
+	require.Equal(t, []int64{4, 5}, ids)
+
+	ids, err = follow(t, log, "2", 3)
+	require.NoError(t, err)
+	require.Equal(t, []int64{3, 4, 5}, ids)
+
+	_, err = follow(t, log, "1", 1)
+	require.ErrorIs(t, err, ErrExpiredToken)
+
+	for _, token := range []string{"x", "-1", "9"} {
+		_, err = follow(t, log, token, 1)
+		require.ErrorIs(t, err, ErrInvalidToken)
+	}
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		log.add(6, time.Now())
+	}()
+
+	ids, err = follow(t, log, "", 1)
+	require.NoError(t, err)
+	require.Equal(t, []int64{6}, ids, "an empty token follows the new changes only")
+}
+
+func TestFollow_commitOrder(t *testing.T) {
+	log := &changeLog{}
+	log.add(2, time.Now())
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		// A transaction taking its ID first commits last, long after its change was created.
+		log.add(1, time.Now().Add(-time.Minute))
+	}()
+
+	ids, err := follow(t, log, "1", 2)
+	require.NoError(t, err)
+	require.Equal(t, []int64{2, 1}, ids, "changes are followed in commit order")
+}
@@ -0,0 +1,190 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// hubBuffer is the number of events queued per Hub subscriber, one falling further behind catches up from the change log.
+const hubBuffer = followBatch
+
+// errOverflow is returned to a subscriber whose queue is full.
+var errOverflow = errors.New("subscriber queue full")
+
+// Hub follows the change log once for all of its subscribers, fanning the events out to them.
+// Its follower polls the change log only while there are subscribers.
+type Hub struct {
+	log ChangeLog
+
+	mu   sync.Mutex
+	subs map[*subscriber]struct{}
+	// run is the running follower, nil when there is none.
+	run *hubRun
+	// after is the sequence number of the last change fanned out.
+	after int64
+}
+
+type hubRun struct {
+	cancel context.CancelFunc
+}
+
+type subscriber struct {
+	events chan *Event
+	// err is set before events is closed, when the subscriber is dropped.
+	err error
+}
+
+// NewHub instantiates a new Hub.
+func NewHub(log ChangeLog) *Hub {
+	return &Hub{log: log, subs: make(map[*subscriber]struct{})}
+}
+
+// Follow calls fn for every change made after the one identified by a resume token, or from now on when empty,
+// until the context is done or fn fails, as the Follow function does.
+// The changes fanned out before subscribing, or while fn was too slow to keep up, are read from the change log.
+// The Events are shared by the subscribers, fn must not modify them.
+func (h *Hub) Follow(ctx context.Context, token string, fn func(*Event) error) error {
+	after, err := start(ctx, h.log, token)
+	if err != nil {
+		return err
+	}
+
+	for {
+		sub, until := h.subscribe(after)
+
+		after, err = replay(ctx, h.log, after, until, fn)
+		if err == nil {
+			after, err = receive(ctx, sub, after, fn)
+		}
+		h.unsubscribe(sub)
+
+		if !errors.Is(err, errOverflow) {
+			return err
+		}
+	}
+}
+
+// subscribe adds a subscriber, starting the follower from a sequence number when not running,
+// and returns the sequence number of the last change fanned out.
+func (h *Hub) subscribe(after int64) (*subscriber, int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.run == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		h.run = &hubRun{cancel: cancel}
+		h.after = after
+		go h.follow(ctx, h.run, after)
+	}
+
+	sub := &subscriber{events: make(chan *Event, hubBuffer)}
+	h.subs[sub] = struct{}{}
+
+	return sub, h.after
+}
+
+// unsubscribe removes a subscriber, unless already dropped, stopping the follower once there are none.
+func (h *Hub) unsubscribe(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subs, sub)
+
+	if len(h.subs) == 0 && h.run != nil {
+		h.run.cancel()
+		h.run = nil
+	}
+}
+
+// follow fans the changes out to the subscribers until stopped, dropping the ones too slow to take them,
+// and all of them when failing.
+func (h *Hub) follow(ctx context.Context, run *hubRun, after int64) {
+	err := poll(ctx, h.log, after, func(e *Event) error {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if h.run != run {
+			return ctx.Err()
+		}
+
+		for sub := range h.subs {
+			select {
+			case sub.events <- e:
+			default:
+				h.drop(sub, errOverflow)
+			}
+		}
+		h.after = e.Seq
+
+		return nil
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.run != run {
+		return
+	}
+
+	for sub := range h.subs {
+		h.drop(sub, err)
+	}
+	h.run = nil
+}
+
+func (h *Hub) drop(sub *subscriber, err error) {
+	sub.err = err
+	close(sub.events)
+	delete(h.subs, sub)
+}
+
+// replay calls fn for the changes following a sequence number up to another, returning the last one's.
+func replay(ctx context.Context, log ChangeLog, after, until int64, fn func(*Event) error) (int64, error) {
+	for after < until {
+		changes, err := log.Changes(ctx, after, followBatch)
+		if err != nil || len(changes) == 0 {
+			return after, err
+		}
+
+		for i := range changes {
+			e, err := NewEvent(&changes[i])
+			if err != nil {
+				return after, err
+			}
+			if e.Seq > until {
+				return after, nil
+			}
+
+			if err := fn(e); err != nil {
+				return after, err
+			}
+			after = e.Seq
+		}
+	}
+
+	return after, nil
+}
+
+// receive calls fn for the events fanned out to a subscriber following a sequence number, returning the last one's,
+// until the context is done, fn fails or the subscriber is dropped.
+func receive(ctx context.Context, sub *subscriber, after int64, fn func(*Event) error) (int64, error) {
+	for {
+		select {
+		case e, ok := <-sub.events:
+			if !ok {
+				return after, sub.err
+			}
+			if e.Seq <= after {
+				continue
+			}
+
+			if err := fn(e); err != nil {
+				return after, err
+			}
+			after = e.Seq
+		case <-ctx.Done():
+			return after, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/stretchr/testify/require"
+)
+
+// subscribed reports whether a Hub has n subscribers, and a follower as long as it has any.
+func subscribed(h *Hub, n int) func() bool {
+	return func() bool {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return len(h.subs) == n && (h.run != nil) == (n > 0)
+	}
+}
+
+// hubFollow returns the IDs of the first n events followed from a token through a Hub.
+func hubFollow(h *Hub, token string, n int, fn func()) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var ids []int64
+	err := h.Follow(ctx, token, func(e *Event) error {
+		if fn != nil {
+			fn()
+		}
+		ids = append(ids, e.ID)
+		if len(ids) == n {
+			return errStop
+		}
+		return nil
+	})
+	if errors.Is(err, errStop) {
+		err = nil
+	}
+
+	return ids, err
+}
+
+func TestHub(t *testing.T) {
+	old := time.Now().Add(-time.Minute)
+
+	log := &changeLog{}
+	for id := int64(1); id <= 3; id++ {
+		log.add(id, old)
+	}
+
+	h := NewHub(log)
+
+	var wg sync.WaitGroup
+	results := make([][]int64, 3)
+	errs := make([]error, 3)
+	for i, token := range []string{"", "", "1"} {
+		i, token := i, token
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = hubFollow(h, token, 3, nil)
+		}()
+	}
+
+	require.Eventually(t, subscribed(h, 3), time.Second, 10*time.Millisecond, "the subscribers share a follower")
+
+	for id := int64(4); id <= 6; id++ {
+		log.add(id, time.Now())
+	}
+	wg.Wait()
+
+	require.Equal(t, []error{nil, nil, nil}, errs)
+	require.Equal(t, []int64{4, 5, 6}, results[0])
+	require.Equal(t, []int64{4, 5, 6}, results[1])
+	require.Equal(t, []int64{2, 3, 4}, results[2], "the changes preceding the subscription are read from the change log")
+
+	require.Eventually(t, subscribed(h, 0), time.Second, 10*time.Millisecond, "the follower stops without subscribers")
+
+	ids, err := hubFollow(h, "4", 2, nil)
+	require.NoError(t, err)
+	require.Equal(t, []int64{5, 6}, ids)
+
+	_, err = hubFollow(h, "x", 1, nil)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestHub_slow(t *testing.T) {
+	log := &changeLog{}
+	log.add(1, time.Now())
+
+	h := NewHub(log)
+	n := 2*hubBuffer + 10
+
+	unblock := make(chan struct{})
+	var once sync.Once
+	var ids []int64
+	var err error
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ids, err = hubFollow(h, "", n, func() { once.Do(func() { <-unblock }) })
+	}()
+
+	require.Eventually(t, subscribed(h, 1), time.Second, 10*time.Millisecond)
+
+	for id := int64(2); id <= int64(n)+1; id++ {
+		log.add(id, time.Now())
+	}
+
+	// The blocked subscriber is dropped once its queue is full, then catches up from the change log.
+	require.Eventually(t, func() bool {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		return len(h.subs) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	close(unblock)
+
+	<-done
+	require.NoError(t, err)
+	require.Len(t, ids, n)
+	for i, id := range ids {
+		require.Equal(t, int64(i+2), id)
+	}
+}
+
+// failingLog fails reading the changes once fail is set.
+type failingLog struct {
+	*changeLog
+	mu   sync.Mutex
+	fail bool
+}
+
+var errLog = errors.New("change log failure")
+
+func (f *failingLog) Changes(ctx context.Context, after int64, limit int) ([]database.Change, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fail {
+		return nil, errLog
+	}
+	return f.changeLog.Changes(ctx, after, limit)
+}
+
+func TestHub_failure(t *testing.T) {
+	log := &failingLog{changeLog: &changeLog{}}
+	h := NewHub(log)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := hubFollow(h, "", 1, nil)
+			errs <- err
+		}()
+	}
+
+	require.Eventually(t, subscribed(h, 2), time.Second, 10*time.Millisecond)
+
+	log.mu.Lock()
+	log.fail = true
+	log.mu.Unlock()
+
+	require.ErrorIs(t, <-errs, errLog)
+	require.ErrorIs(t, <-errs, errLog)
+	require.Eventually(t, subscribed(h, 0), time.Second, 10*time.Millisecond)
+}
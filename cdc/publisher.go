@@ -0,0 +1,204 @@
+package cdc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/nats-io/nats.go"
+)
+
+const publishTimeout = 10 * time.Second
+
+// Open returns the Publisher selected by the configuration, nil when disabled.
+func Open(cfg config.CDC) (Publisher, error) {
+	switch cfg.Publisher {
+	case config.PublisherWebhook:
+		return NewWebhook(cfg.WebhookURL), nil
+	case config.PublisherNATS:
+		return NewNATS(cfg.NATSURL, cfg.NATSSubject)
+	case config.PublisherFile:
+		return NewFile(cfg.File), nil
+	case config.PublisherNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown change events publisher %q", cfg.Publisher)
+	}
+}
+
+// Multi represents a Publisher publishing to several ones in turn, a failure of any making them all publish the batch again.
+type Multi []Publisher
+
+// Publish publishes the Events to every Publisher, stopping at the first failure.
+func (m Multi) Publish(ctx context.Context, events []*Event) error {
+	for _, p := range m {
+		if err := p.Publish(ctx, events); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes every Publisher, returning the first failure.
+func (m Multi) Close() error {
+	var out error
+	for _, p := range m {
+		if err := p.Close(); err != nil && out == nil {
+			out = err
+		}
+	}
+
+	return out
+}
+
+// Webhook represents a Publisher posting every Event as JSON to a URL.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook instantiates a new Webhook publisher.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: publishTimeout},
+	}
+}
+
+// Publish posts the Events one by one, failing on the first response other than 2xx.
+func (w *Webhook) Publish(ctx context.Context, events []*Event) error {
+	for _, e := range events {
+		if err := w.post(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (w *Webhook) post(ctx context.Context, e *Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Id", strconv.FormatInt(e.ID, 10))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook event %d: unexpected status %s", e.ID, res.Status)
+	}
+
+	return nil
+}
+
+// Close does nothing.
+func (w *Webhook) Close() error {
+	return nil
+}
+
+// NATS represents a Publisher sending every Event as JSON to a NATS subject,
+// its ID in the Nats-Msg-Id header so JetStream streams drop the redeliveries.
+type NATS struct {
+	conn    *nats.Conn
+	subject string
+}
+
+// NewNATS connects a new NATS publisher, reconnecting in the background while the server is unreachable.
+func NewNATS(url, subject string) (*NATS, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("ports"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NATS{conn: conn, subject: subject}, nil
+}
+
+// Publish sends the Events, then waits for the server to acknowledge them.
+func (n *NATS) Publish(_ context.Context, events []*Event) error {
+	for _, e := range events {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		msg := nats.NewMsg(n.subject)
+		msg.Header.Set(nats.MsgIdHdr, strconv.FormatInt(e.ID, 10))
+		msg.Data = data
+
+		if err := n.conn.PublishMsg(msg); err != nil {
+			return err
+		}
+	}
+
+	return n.conn.FlushTimeout(publishTimeout)
+}
+
+// Close drains the connection.
+func (n *NATS) Close() error {
+	return n.conn.Drain()
+}
+
+// File represents a Publisher appending the Events to an NDJSON file.
+type File struct {
+	path string
+}
+
+// NewFile instantiates a new File publisher.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Publish appends the Events to the file, creating it if needed, and syncs it.
+func (f *File) Publish(_ context.Context, events []*Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Close does nothing.
+func (f *File) Close() error {
+	return nil
+}
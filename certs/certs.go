@@ -0,0 +1,185 @@
+// Package certs includes the TLS configuration shared by the servers and clients.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/rs/zerolog/log"
+)
+
+// reloadInterval is the minimum time between two checks of the certificate files.
+const reloadInterval = 5 * time.Second
+
+// Reloader serves a certificate key pair, reloading it when its files change.
+// It is safe for concurrent use.
+type Reloader struct {
+	certFile string
+	keyFile  string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modified  time.Time
+	checkedAt time.Time
+}
+
+// NewReloader instantiates a Reloader, loading the key pair right away.
+func NewReloader(certFile, keyFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile}
+
+	modTime, err := r.modTime()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.load(modTime); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// GetCertificate returns the current certificate, meant for tls.Config.GetCertificate and GetClientCertificate.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if now := time.Now(); now.Sub(r.checkedAt) >= reloadInterval {
+		r.checkedAt = now
+		r.reload()
+	}
+
+	return r.cert, nil
+}
+
+// reload loads the key pair again if any of its files changed, keeping the current one on failure.
+func (r *Reloader) reload() {
+	modTime, err := r.modTime()
+	if err != nil {
+		log.Error().Err(err).Msg("TLS certificate check failed")
+		return
+	}
+
+	if modTime.Equal(r.modified) {
+		return
+	}
+
+	if err := r.load(modTime); err != nil {
+		log.Error().Err(err).Msg("TLS certificate reload failed")
+		return
+	}
+
+	log.Info().Str("cert", r.certFile).Msg("TLS certificate reloaded")
+}
+
+func (r *Reloader) load(modTime time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.cert = &cert
+	r.modified = modTime
+	return nil
+}
+
+// modTime returns the latest modification time of the certificate and key files.
+func (r *Reloader) modTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// Server returns the tls.Config of a server, requiring client certificates when a client CA bundle is set.
+func Server(cfg config.TLS) (*tls.Config, error) {
+	r, err := NewReloader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.GetCertificate,
+	}
+
+	if cfg.ClientCAFile != "" {
+		pool, err := loadPool(cfg.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		out.ClientCAs = pool
+		out.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return out, nil
+}
+
+// Client represents a client TLS configuration.
+type Client struct {
+	// CAFile is the CA bundle verifying the server, the system roots are used when empty.
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the name verified against the server certificate.
+	ServerName string
+}
+
+// Config returns the tls.Config of a client, presenting a certificate when one is set.
+func (c Client) Config() (*tls.Config, error) {
+	out := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: c.ServerName,
+	}
+
+	if c.CAFile != "" {
+		pool, err := loadPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		out.RootCAs = pool
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		out.Certificates = []tls.Certificate{cert}
+	}
+
+	return out, nil
+}
+
+func loadPool(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA bundle %s: no PEM certificates", path)
+	}
+
+	return pool, nil
+}
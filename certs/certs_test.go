@@ -0,0 +1,159 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/stretchr/testify/require"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	dir  string
+}
+
+func newAuthority(t *testing.T) *authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	a := &authority{cert: cert, key: key, dir: t.TempDir()}
+	writePEM(t, a.path("ca.pem"), "CERTIFICATE", der)
+
+	return a
+}
+
+func (a *authority) path(name string) string {
+	return filepath.Join(a.dir, name)
+}
+
+// issue writes a certificate and key pair named after the common name, returning their paths.
+func (a *authority) issue(t *testing.T, name string, serial int64) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, a.cert, &key.PublicKey, a.key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := a.path(name+".pem"), a.path(name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "PRIVATE KEY", keyDER)
+
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600))
+}
+
+func TestReloader(t *testing.T) {
+	ca := newAuthority(t)
+	certFile, keyFile := ca.issue(t, "server", 2)
+
+	r, err := NewReloader(certFile, keyFile)
+	require.NoError(t, err)
+
+	first, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+
+	ca.issue(t, "server", 3)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+
+	r.checkedAt = time.Time{}
+	second, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	require.NotEqual(t, first.Certificate[0], second.Certificate[0])
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	later := future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+
+	r.checkedAt = time.Time{}
+	kept, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+	require.Same(t, second, kept)
+}
+
+func TestServer_mutual(t *testing.T) {
+	ca := newAuthority(t)
+	certFile, keyFile := ca.issue(t, "server", 2)
+	clientCert, clientKey := ca.issue(t, "client", 3)
+
+	server, err := Server(config.TLS{CertFile: certFile, KeyFile: keyFile, ClientCAFile: ca.path("ca.pem")})
+	require.NoError(t, err)
+
+	handshake := func(c Client) error {
+		cfg, err := c.Config()
+		require.NoError(t, err)
+
+		sc, cc := net.Pipe()
+
+		errs := make(chan error, 1)
+		go func() {
+			defer sc.Close()
+			errs <- tls.Server(sc, server).Handshake()
+		}()
+
+		conn := tls.Client(cc, cfg)
+		err = conn.Handshake()
+		if err == nil {
+			// TLS 1.3 servers verify the client certificate after the client handshake completes.
+			if _, err = conn.Read(make([]byte, 1)); errors.Is(err, io.EOF) {
+				err = nil
+			}
+		}
+		cc.Close()
+
+		if serr := <-errs; err == nil {
+			err = serr
+		}
+		return err
+	}
+
+	require.NoError(t, handshake(Client{CAFile: ca.path("ca.pem"), CertFile: clientCert, KeyFile: clientKey, ServerName: "server"}))
+	require.Error(t, handshake(Client{CAFile: ca.path("ca.pem"), ServerName: "server"}))
+	require.Error(t, handshake(Client{CAFile: ca.path("ca.pem"), CertFile: clientCert, KeyFile: clientKey, ServerName: "other"}))
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/agukrapo/ports/auth"
+	"github.com/spf13/cobra"
+)
+
+func authCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage the server credentials",
+		Args:  usageArgs(cobra.ArbitraryArgs),
+		RunE:  parentRun,
+	}
+
+	var scopes []string
+
+	keygen := &cobra.Command{
+		Use:   "keygen NAME",
+		Short: "Generate an API key and its API keys file entry",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+		RunE: func(_ *cobra.Command, args []string) error {
+			return runKeygen(args[0], scopes)
+		},
+	}
+	keygen.Flags().StringSliceVar(&scopes, "scope", []string{string(auth.ScopeRead)}, "granted scopes (ports:read, ports:write, ports:admin)")
+
+	cmd.AddCommand(keygen)
+
+	return cmd
+}
+
+func runKeygen(name string, scopes []string) error {
+	for _, s := range scopes {
+		if _, err := auth.ParseScope(s); err != nil {
+			return usageError{err}
+		}
+	}
+
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(os.Stderr, "API key, shown only once: %s\n", key)
+	_, err = fmt.Fprintf(os.Stdout, "  - name: %q\n    sha256: %s\n    scopes: [%s]\n", name, auth.HashAPIKey(key), strings.Join(scopes, ", "))
+	return err
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/agukrapo/ports/certs"
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/grpc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func clientCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "client",
+		Short: "Talk to a running server",
+		Args:  usageArgs(cobra.ArbitraryArgs),
+		RunE:  parentRun,
+	}
+
+	flags := &clientFlags{}
+	flags.bind(cmd.PersistentFlags())
+
+	cmd.AddCommand(clientUploadCmd(flags), clientExportCmd(flags), clientWatchCmd(flags), clientChangesCmd(flags))
+
+	return cmd
+}
+
+// clientFlags holds the connection flags shared by the client commands.
+type clientFlags struct {
+	token   string
+	retries int
+	// idempotencyKey makes the server return the original result of an upload submitted again.
+	idempotencyKey string
+	// source names the source the uploaded ports come from.
+	source string
+	// conflict is the policy the uploaded ports are written with.
+	conflict string
+	tls      bool
+	certs    certs.Client
+}
+
+func (f *clientFlags) bind(fs *pflag.FlagSet) {
+	fs.StringVar(&f.token, "token", "", "API key or JWT authenticating the calls, also PORTS_TOKEN")
+	fs.BoolVar(&f.tls, "tls", false, "connect over TLS, implied by the other TLS flags")
+	fs.StringVar(&f.certs.CAFile, "ca", "", "CA bundle verifying the server certificate, the system roots by default")
+	fs.StringVar(&f.certs.CertFile, "cert", "", "client certificate file, for mutual TLS")
+	fs.StringVar(&f.certs.KeyFile, "cert-key", "", "client certificate private key file, for mutual TLS")
+	fs.StringVar(&f.certs.ServerName, "server-name", "", "name verified against the server certificate, the address host by default")
+}
+
+// bindUpload registers the upload specific flags.
+func (f *clientFlags) bindUpload(fs *pflag.FlagSet) {
+	fs.IntVar(&f.retries, "retries", 5, "times an interrupted upload is resumed")
+	fs.StringVar(&f.idempotencyKey, "idempotency-key", "", "key making the server return the original result of an upload submitted again")
+	fs.StringVar(&f.source, "source", "", "name of the source the ports come from, the server default one if empty")
+	fs.StringVar(&f.conflict, "conflict", string(database.ConflictOverwrite), "how a port whose key is stored, or not, is written, see import --conflict")
+}
+
+func (f *clientFlags) dial(cfg *config.Config, address string) (*grpc.Client, error) {
+	token := f.token
+	if token == "" {
+		token = os.Getenv("PORTS_TOKEN")
+	}
+
+	opts := []grpc.ClientOption{grpc.WithToken(token), grpc.WithRetries(f.retries), grpc.WithSource(f.source), grpc.WithConflict(database.Conflict(f.conflict))}
+
+	if f.tls || f.certs != (certs.Client{}) {
+		tlsCfg, err := f.certs.Config()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.WithTLS(tlsCfg))
+	}
+
+	return grpc.NewClient(address, cfg.GRPC.ChunkSize, opts...)
+}
+
+func clientUploadCmd(flags *clientFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upload ADDRESS FILE",
+		Short: "Upload a ports JSON file to a gRPC server, resuming it after interruptions",
+		Args:  usageArgs(cobra.ExactArgs(2)),
+	}
+
+	flags.bindUpload(cmd.Flags())
+
+	return withConfig(cmd, func(ctx context.Context, cfg *config.Config, args []string) error {
+		return runGRPCClient(ctx, cfg, flags, args)
+	}, "grpc")
+}
+
+func runGRPCClient(ctx context.Context, cfg *config.Config, cf *clientFlags, args []string) error {
+	if _, err := database.ParseConflict(cf.conflict); err != nil {
+		return usageError{err}
+	}
+
+	client, err := cf.dial(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	defer safeClose(file)
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := cancelOnInterrupt(ctx)
+	defer cancel()
+
+	return client.UploadFile(grpc.WithIdempotencyKey(ctx, cf.idempotencyKey), file, info.Size())
+}
+
+func clientExportCmd(cf *clientFlags) *cobra.Command {
+	var flags exportFlags
+
+	cmd := &cobra.Command{
+		Use:   "export ADDRESS",
+		Short: "Export the ports stored by a gRPC server",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+	}
+
+	flags.bind(cmd.Flags())
+
+	return withConfig(cmd, func(ctx context.Context, cfg *config.Config, args []string) error {
+		return runGRPCExport(ctx, cfg, cf, flags, args)
+	}, "grpc")
+}
+
+func runGRPCExport(ctx context.Context, cfg *config.Config, cf *clientFlags, flags exportFlags, args []string) error {
+	format, filter, err := flags.parse()
+	if err != nil {
+		return err
+	}
+
+	client, err := cf.dial(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	req := &grpc.ExportRequest{
+		Format:  string(format),
+		Keys:    filter.Keys,
+		Country: filter.Country,
+		City:    filter.City,
+	}
+	if b := filter.BBox; b != nil {
+		req.BBox = &grpc.BBox{MinX: b.MinX, MinY: b.MinY, MaxX: b.MaxX, MaxY: b.MaxY}
+	}
+	if !filter.AsOf.IsZero() {
+		req.AsOf = filter.AsOf.Format(time.RFC3339Nano)
+	}
+
+	out, err := flags.create()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := cancelOnInterrupt(ctx)
+	defer cancel()
+
+	if err := client.Export(ctx, req, out); err != nil {
+		safeClose(out)
+		return err
+	}
+
+	return out.Close()
+}
+
+func clientWatchCmd(flags *clientFlags) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch ADDRESS ID",
+		Short: "Follow the progress of an import running in a gRPC server",
+		Args:  usageArgs(cobra.ExactArgs(2)),
+	}
+
+	return withConfig(cmd, func(ctx context.Context, cfg *config.Config, args []string) error {
+		return runGRPCWatch(ctx, cfg, flags, args)
+	}, "grpc")
+}
+
+func runGRPCWatch(ctx context.Context, cfg *config.Config, cf *clientFlags, args []string) error {
+	client, err := cf.dial(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := cancelOnInterrupt(ctx)
+	defer cancel()
+
+	enc := json.NewEncoder(os.Stdout)
+
+	return client.WatchImport(ctx, args[1], func(e *grpc.ImportEvent) error {
+		return enc.Encode(e.GetEvent())
+	})
+}
+
+func clientChangesCmd(cf *clientFlags) *cobra.Command {
+	var (
+		keys    []string
+		country string
+		bbox    string
+		token   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "changes ADDRESS",
+		Short: "Follow the port changes of a gRPC server, as NDJSON",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+	}
+
+	fs := cmd.Flags()
+	fs.StringSliceVar(&keys, "key", nil, "only follow these keys, repeated or comma separated")
+	fs.StringVar(&country, "country", "", "only follow ports of this country")
+	fs.StringVar(&bbox, "bbox", "", "only follow ports inside this minX,minY,maxX,maxY bounding box")
+	fs.StringVar(&token, "resume-token", "", "replay the changes following the one with this resume token")
+	fs.IntVar(&cf.retries, "retries", 5, "times an interrupted stream is resumed")
+
+	return withConfig(cmd, func(ctx context.Context, cfg *config.Config, args []string) error {
+		req := &grpc.WatchPortsRequest{Keys: keys, Country: country, ResumeToken: token}
+		if bbox != "" {
+			b, err := database.ParseBBox(bbox)
+			if err != nil {
+				return usageError{err}
+			}
+			req.BBox = &grpc.BBox{MinX: b.MinX, MinY: b.MinY, MaxX: b.MaxX, MaxY: b.MaxY}
+		}
+
+		return runGRPCChanges(ctx, cfg, cf, req, args)
+	}, "grpc")
+}
+
+func runGRPCChanges(ctx context.Context, cfg *config.Config, cf *clientFlags, req *grpc.WatchPortsRequest, args []string) error {
+	client, err := cf.dial(cfg, args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := cancelOnInterrupt(ctx)
+	defer cancel()
+
+	enc := json.NewEncoder(os.Stdout)
+
+	err = client.WatchPorts(ctx, req, func(c *grpc.PortChange) error {
+		return enc.Encode(c)
+	})
+	if ctx.Err() != nil {
+		// Interrupted by the user.
+		return nil
+	}
+
+	return err
+}
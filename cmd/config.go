@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/spf13/cobra"
+)
+
+func configCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+		Args:  usageArgs(cobra.ArbitraryArgs),
+		RunE:  parentRun,
+	}
+
+	print := &cobra.Command{
+		Use:   "print",
+		Short: "Print the effective configuration, with secrets redacted",
+		Args:  usageArgs(cobra.NoArgs),
+	}
+
+	cmd.AddCommand(withConfig(print, runConfigPrint))
+
+	return cmd
+}
+
+func runConfigPrint(_ context.Context, cfg *config.Config, _ []string) error {
+	out, err := config.Print(cfg.Redacted())
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(out)
+	return err
+}
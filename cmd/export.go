@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/export"
+	"github.com/agukrapo/ports/service"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// exportFlags represents the output format and filter flags shared by the export commands.
+type exportFlags struct {
+	format  string
+	output  string
+	keys    []string
+	country string
+	city    string
+	bbox    string
+	asOf    string
+}
+
+func (f *exportFlags) bind(fs *pflag.FlagSet) {
+	fs.StringVar(&f.format, "format", string(export.JSON), "output format: json, ndjson, csv or geojson")
+	fs.StringVarP(&f.output, "output", "o", "-", "output file path, - for the standard output")
+	fs.StringSliceVar(&f.keys, "key", nil, "only export these keys, repeated or comma separated")
+	fs.StringVar(&f.country, "country", "", "only export ports of this country")
+	fs.StringVar(&f.city, "city", "", "only export ports of this city")
+	fs.StringVar(&f.bbox, "bbox", "", "only export ports inside this minX,minY,maxX,maxY bounding box")
+	fs.StringVar(&f.asOf, "as-of", "", "export the ports as they were stored at this RFC 3339 time")
+}
+
+func (f *exportFlags) parse() (export.Format, database.Filter, error) {
+	format, err := export.ParseFormat(f.format)
+	if err != nil {
+		return "", database.Filter{}, usageError{err}
+	}
+
+	filter := database.Filter{
+		Keys:    f.keys,
+		Country: f.country,
+		City:    f.city,
+	}
+
+	if f.bbox != "" {
+		if filter.BBox, err = database.ParseBBox(f.bbox); err != nil {
+			return "", database.Filter{}, usageError{err}
+		}
+	}
+
+	if f.asOf != "" {
+		if filter.AsOf, err = database.ParseAsOf(f.asOf); err != nil {
+			return "", database.Filter{}, usageError{err}
+		}
+	}
+
+	return format, filter, nil
+}
+
+// create opens the output, which must be closed to flush it.
+func (f *exportFlags) create() (io.WriteCloser, error) {
+	var out io.WriteCloser = nopCloser{os.Stdout}
+	if f.output != stdio {
+		file, err := os.Create(f.output)
+		if err != nil {
+			return nil, err
+		}
+		out = file
+	}
+
+	return &output{Writer: bufio.NewWriter(out), c: out}, nil
+}
+
+type output struct {
+	*bufio.Writer
+	c io.Closer
+}
+
+func (o *output) Close() error {
+	if err := o.Flush(); err != nil {
+		safeClose(o.c)
+		return err
+	}
+	return o.c.Close()
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+func exportCmd() *cobra.Command {
+	var flags exportFlags
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the stored ports",
+		Example: `  ports export --format csv --country Argentina -o ports.csv
+  ports export --as-of 2022-06-01T00:00:00Z -o ports-june.json
+  ports export --format json > ports.json && ports import ports.json`,
+		Args: usageArgs(cobra.NoArgs),
+	}
+
+	flags.bind(cmd.Flags())
+
+	return withConfig(cmd, func(ctx context.Context, cfg *config.Config, _ []string) error {
+		return runExport(ctx, cfg, flags)
+	}, "database")
+}
+
+func runExport(ctx context.Context, cfg *config.Config, flags exportFlags) error {
+	format, filter, err := flags.parse()
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer safeClose(db)
+
+	out, err := flags.create()
+	if err != nil {
+		return err
+	}
+
+	enc, err := export.NewEncoder(format, out)
+	if err != nil {
+		safeClose(out)
+		return err
+	}
+
+	ctx, cancel := cancelOnInterrupt(ctx)
+	defer cancel()
+
+	if err := service.New(db).Export(ctx, filter, enc); err != nil {
+		safeClose(out)
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/imports"
+	"github.com/agukrapo/ports/parser"
+	"github.com/agukrapo/ports/service"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+// stdio is the file name standing for the standard input or output.
+const stdio = "-"
+
+type importFlags struct {
+	parallel   int
+	duplicates string
+	source     string
+	conflict   string
+}
+
+func importCmd() *cobra.Command {
+	var flags importFlags
+
+	cmd := &cobra.Command{
+		Use:     "import FILE|GLOB|- ...",
+		Aliases: []string{"cli"},
+		Short:   "Import ports JSON files into the database",
+		Long: `Import ports JSON files into the database.
+
+Every argument is a file path, a glob pattern or - to read from the standard input.
+Files are processed one after another unless --parallel is greater than 1.`,
+		Example: `  ports import ports.json
+  ports import 'data/*.json' extra.json --duplicates first
+  ports import supplier.json --source supplier
+  ports import corrections.json --conflict update-only
+  curl -s https://example.com/ports.json | ports import -`,
+		Args: usageArgs(cobra.MinimumNArgs(1)),
+	}
+
+	cmd.Flags().IntVar(&flags.parallel, "parallel", 1, "number of files processed at the same time")
+	cmd.Flags().StringVar(&flags.duplicates, "duplicates", string(service.DuplicatesLast),
+		"how a key present in several files is resolved: first (keep the first), last (keep the last) or error (reject every one but the first); with --parallel, first and last refer to processing order")
+	cmd.Flags().StringVar(&flags.conflict, "conflict", string(database.ConflictOverwrite),
+		"how a port whose key is stored, or not, is written: overwrite, insert-only (skip stored keys), update-only (skip new keys), fill-empty (only fill the empty stored fields) or reject (reject the ports changing a stored one)")
+	cmd.Flags().StringVar(&flags.source, "source", database.DefaultSource,
+		"name of the source the ports come from, their stored values being merged from every source by the merge precedence")
+
+	return withConfig(cmd, func(ctx context.Context, cfg *config.Config, args []string) error {
+		return runImport(ctx, cfg, flags, args)
+	}, "database", "dead_letter", "merge")
+}
+
+type fileReport struct {
+	name   string
+	report service.Report
+	err    error
+}
+
+func runImport(ctx context.Context, cfg *config.Config, flags importFlags, args []string) error {
+	if flags.parallel < 1 {
+		return usageError{errors.New("--parallel must be greater than 0")}
+	}
+
+	duplicates, err := service.ParseDuplicates(flags.duplicates)
+	if err != nil {
+		return usageError{err}
+	}
+
+	if err := service.ValidateSource(flags.source); err != nil {
+		return usageError{err}
+	}
+
+	conflict, err := database.ParseConflict(flags.conflict)
+	if err != nil {
+		return usageError{err}
+	}
+
+	names, err := expand(args)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer safeClose(db)
+
+	svc, err := newService(cfg, db)
+	if err != nil {
+		return err
+	}
+	keys := service.NewKeys(duplicates)
+
+	ctx, cancel := cancelOnInterrupt(ctx)
+	defer cancel()
+
+	log.Info().Int("files", len(names)).Msg("Ports import started")
+
+	start := time.Now()
+	reports := make([]fileReport, len(names))
+	sem := make(chan struct{}, flags.parallel)
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			report, err := importFile(ctx, svc, keys, name, service.WithSource(flags.source), service.WithConflict(conflict))
+			reports[i] = fileReport{name: name, report: report, err: err}
+
+			logReport(name, report, err)
+		}(i, name)
+	}
+	wg.Wait()
+
+	var total service.Report
+	var failed int
+	for _, r := range reports {
+		total.Add(r.report)
+		if r.err != nil {
+			failed++
+		}
+	}
+	total.Duration = time.Since(start)
+
+	log.Info().Msg("Ports import finished")
+
+	if err := printReports(os.Stdout, reports, total); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed", failed, len(names))
+	}
+	if duplicates == service.DuplicatesError && total.Rejected > 0 {
+		return validationError{fmt.Errorf("%d ports rejected", total.Rejected)}
+	}
+
+	return nil
+}
+
+// expand resolves glob patterns into file names, keeping their order and removing repetitions.
+func expand(args []string) ([]string, error) {
+	var out []string
+	seen := make(map[string]bool)
+
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+
+	for _, arg := range args {
+		if arg == stdio {
+			add(arg)
+			continue
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, usageError{fmt.Errorf("%s: %w", arg, err)}
+		}
+		if len(matches) == 0 {
+			return nil, usageError{fmt.Errorf("%s: no such file", arg)}
+		}
+
+		for _, m := range matches {
+			add(m)
+		}
+	}
+
+	return out, nil
+}
+
+func importFile(ctx context.Context, svc *service.Service, keys *service.Keys, name string, opts ...service.Option) (service.Report, error) {
+	var r io.Reader = os.Stdin
+	if name != stdio {
+		file, err := os.Open(name)
+		if err != nil {
+			return service.Report{}, err
+		}
+		defer safeClose(file)
+
+		r = file
+	}
+
+	src, err := parser.New(r)
+	if err != nil {
+		return service.Report{}, err
+	}
+
+	id := imports.NewID()
+	log.Info().Str("file", name).Str("import", id).Msg("File import started")
+
+	opts = append(opts, service.WithKeys(keys, name), service.WithImportID(id))
+	return svc.Process(ctx, src, opts...), nil
+}
+
+func logReport(name string, report service.Report, err error) {
+	if err != nil {
+		log.Error().Err(err).Str("file", name).Msg("File import failed")
+		return
+	}
+
+	log.Info().
+		Str("file", name).
+		Str("source", report.Source).
+		Str("conflict", string(report.Conflict)).
+		Int("processed", report.Processed).
+		Int("upserted", report.Upserted).
+		Int("rejected", report.Rejected).
+		Int("skipped", report.Skipped).
+		Int("retries", report.Retries).
+		Dur("duration", report.Duration).
+		Msg("File imported")
+}
+
+func printReports(w io.Writer, reports []fileReport, total service.Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	_, _ = fmt.Fprintln(tw, "FILE\tPROCESSED\tUPSERTED\tREJECTED\tSKIPPED\tRETRIES\tDURATION\tERROR")
+	for _, r := range reports {
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\n", r.name, r.report.Processed, r.report.Upserted,
+			r.report.Rejected, r.report.Skipped, r.report.Retries, r.report.Duration.Round(time.Millisecond), errString(r.err))
+	}
+	_, _ = fmt.Fprintf(tw, "TOTAL\t%d\t%d\t%d\t%d\t%d\t%s\t\n", total.Processed, total.Upserted,
+		total.Rejected, total.Skipped, total.Retries, total.Duration.Round(time.Millisecond))
+
+	return tw.Flush()
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/spf13/cobra"
+)
+
+// legacyCmds returns the commands preceding the current command tree, kept for backwards compatibility.
+func legacyCmds() []*cobra.Command {
+	rest := &cobra.Command{
+		Use:        "rest",
+		Hidden:     true,
+		Deprecated: "use 'ports serve rest' instead",
+		Args:       usageArgs(cobra.NoArgs),
+	}
+
+	grpcServer := &cobra.Command{
+		Use:        "grpc-server",
+		Hidden:     true,
+		Deprecated: "use 'ports serve grpc' instead",
+		Args:       usageArgs(cobra.NoArgs),
+	}
+
+	grpcClient := &cobra.Command{
+		Use:        "grpc-client ADDRESS FILE",
+		Hidden:     true,
+		Deprecated: "use 'ports client upload' instead",
+		Args:       usageArgs(cobra.ExactArgs(2)),
+	}
+
+	flags := &clientFlags{}
+	flags.bind(grpcClient.Flags())
+	flags.bindUpload(grpcClient.Flags())
+
+	return []*cobra.Command{
+		withConfig(rest, runREST, "database", "rest", "auth", "limits", "uploads", "idempotency", "dead_letter", "merge", "cdc", "webhooks"),
+		withConfig(grpcServer, runGRPCServer, "database", "grpc", "auth", "limits", "uploads", "idempotency", "dead_letter", "merge", "cdc", "webhooks"),
+		withConfig(grpcClient, func(ctx context.Context, cfg *config.Config, args []string) error {
+			return runGRPCClient(ctx, cfg, flags, args)
+		}, "grpc"),
+	}
+}
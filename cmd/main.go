@@ -9,181 +9,203 @@ import (
 	"os/signal"
 	"time"
 
+	"github.com/agukrapo/ports/config"
 	"github.com/agukrapo/ports/database"
-	"github.com/agukrapo/ports/grpc"
-	"github.com/agukrapo/ports/parser"
-	"github.com/agukrapo/ports/rest"
+	"github.com/agukrapo/ports/deadletter"
 	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/storage"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
 )
 
-type usageError string
-
-func (e usageError) Error() string {
-	return string(e) + `
+// Exit codes.
+const (
+	exitOK         = 0
+	exitRuntime    = 1
+	exitUsage      = 2
+	exitValidation = 3
+)
 
-usage:
-  ports COMMAND ARG
+// usageError represents an invalid command line invocation.
+type usageError struct{ error }
 
-available COMMANDS
-  cli: command line interface, requires an extra file path argument
-  rest: REST server
-`
-}
+// validationError represents invalid input data or configuration.
+type validationError struct{ error }
 
 func main() {
-	if err := exec(); err != nil {
-		_, _ = fmt.Fprintf(os.Stderr, "%v\n", err)
-		os.Exit(1)
-	}
+	os.Exit(exec(os.Args[1:]))
 }
 
-func exec() error {
+func exec(args []string) int {
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.Kitchen})
 
-	if len(os.Args) < 2 {
-		return usageError("missing COMMAND argument")
+	root := rootCmd()
+	root.SetArgs(args)
+
+	err := root.Execute()
+	if err == nil {
+		return exitOK
 	}
 
-	switch os.Args[1] {
-	case "cli":
-		return runCLI()
-	case "rest":
-		return runREST()
-	case "grpc-server":
-		return runGRPCServer()
-	case "grpc-client":
-		return runGRPCClient()
+	_, _ = fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+
+	var uErr usageError
+	var vErr validationError
+
+	switch {
+	case errors.As(err, &uErr):
+		_, _ = fmt.Fprintf(os.Stderr, "Run 'ports --help' for usage.\n")
+		return exitUsage
+	case errors.As(err, &vErr):
+		return exitValidation
 	default:
-		return usageError("invalid COMMAND argument: " + os.Args[1])
+		return exitRuntime
 	}
 }
 
-func runCLI() error {
-	if len(os.Args) < 3 {
-		return errors.New("file path argument missing")
-	}
+func rootCmd() *cobra.Command {
+	root := &cobra.Command{
+		Use:           "ports",
+		Short:         "Ports catalogue loader and servers",
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		Args:          usageArgs(cobra.ArbitraryArgs),
+		RunE:          parentRun,
+	}
+
+	root.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return usageError{err}
+	})
+
+	root.AddCommand(
+		importCmd(),
+		replayCmd(),
+		validateCmd(),
+		exportCmd(),
+		serveCmd(),
+		clientCmd(),
+		migrateCmd(),
+		snapshotCmd(),
+		configCmd(),
+		authCmd(),
+	)
+	root.AddCommand(legacyCmds()...)
+
+	return root
+}
 
-	file, err := os.Open(os.Args[2])
-	if err != nil {
-		return err
+// parentRun prints the help of a command that only groups subcommands, failing on unknown ones.
+func parentRun(cmd *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return usageError{fmt.Errorf("unknown command %q for %q", args[0], cmd.CommandPath())}
 	}
-	defer safeClose(file)
 
-	src, err := parser.New(file)
-	if err != nil {
-		return err
-	}
+	return cmd.Help()
+}
 
-	db, err := openDB()
-	if err != nil {
-		return err
+// usageArgs wraps a positional arguments validator so its failures are reported as usage errors.
+func usageArgs(fn cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := fn(cmd, args); err != nil {
+			return usageError{err}
+		}
+		return nil
 	}
-	defer safeClose(db)
+}
 
-	svc := service.New(db)
+// withConfig binds the configuration flags of the given sections to a command and sets its run function,
+// which receives the loaded configuration.
+func withConfig(cmd *cobra.Command, run func(context.Context, *config.Config, []string) error, sections ...string) *cobra.Command {
+	if len(sections) > 0 {
+		sections = append(sections, "log")
+	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	loader := config.Bind(cmd.Flags(), sections...)
 
-	go func() {
-		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, os.Interrupt)
-		<-quit
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		cfg, err := loader.Load()
+		if err != nil {
+			return validationError{err}
+		}
 
-		log.Info().Msg("Gracefully stopping...")
-		cancel()
-	}()
+		if err := setupLog(cfg.Log); err != nil {
+			return err
+		}
 
-	log.Info().Msg("Ports cli started")
-	svc.Process(ctx, src)
-	log.Info().Msg("Ports cli finished")
+		return run(cmd.Context(), cfg, args)
+	}
 
-	return nil
+	return cmd
 }
 
-func runREST() error {
-	db, err := openDB()
+func setupLog(cfg config.Log) error {
+	level, err := zerolog.ParseLevel(cfg.Level)
 	if err != nil {
 		return err
 	}
-	defer safeClose(db)
+	zerolog.SetGlobalLevel(level)
 
-	port, ok := os.LookupEnv("PORT")
-	if !ok {
-		port = "8080"
+	if cfg.Format == config.FormatJSON {
+		log.Logger = zerolog.New(os.Stderr).With().Timestamp().Logger()
 	}
 
-	server := rest.New(port, service.New(db))
-
-	server.Start()
-	server.Listen()
-
 	return nil
 }
 
-func runGRPCServer() error {
-	db, err := openDB()
-	if err != nil {
-		return err
-	}
-	defer safeClose(db)
+// cancelOnInterrupt returns a context canceled when an os.Interrupt occurs.
+func cancelOnInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
 
-	port, ok := os.LookupEnv("PORT")
-	if !ok {
-		port = "8080"
-	}
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt)
+		defer signal.Stop(quit)
+
+		select {
+		case <-quit:
+			log.Info().Msg("Gracefully stopping...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
-	server := grpc.NewServer(port, service.New(db))
+	return ctx, cancel
+}
 
-	server.Start()
-	server.Listen()
+func requireDSN(cfg config.Database) error {
+	if cfg.DSN == "" {
+		return validationError{errors.New("database DSN missing, set it through --database-dsn, PORTS_DATABASE_DSN or DATABASE_DSN")}
+	}
 	return nil
 }
 
-func runGRPCClient() error {
-	if len(os.Args) < 3 {
-		return errors.New("server address argument missing")
-	}
-	if len(os.Args) < 4 {
-		return errors.New("file path argument missing")
+func openDB(cfg config.Database) (storage.Storage, error) {
+	if err := requireDSN(cfg); err != nil {
+		return nil, err
 	}
 
-	client, err := grpc.NewClient(os.Args[2])
-	if err != nil {
-		return err
-	}
+	return storage.Open(cfg)
+}
 
-	file, err := os.Open(os.Args[3])
+// newService instantiates a Service storing the ports in db, with the configured write rate and dead-letter sink.
+func newService(cfg *config.Config, db storage.Storage) (*service.Service, error) {
+	sink, err := deadletter.Open(cfg.DeadLetter, db)
 	if err != nil {
-		return err
-	}
-	defer safeClose(file)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	go func() {
-		quit := make(chan os.Signal, 1)
-		signal.Notify(quit, os.Interrupt)
-		<-quit
-
-		log.Info().Msg("Gracefully stopping...")
-		cancel()
-	}()
-
-	if err := client.Upload(ctx, file); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
-}
-
-func openDB() (*database.Database, error) {
-	dsn, ok := os.LookupEnv("DATABASE_DSN")
-	if !ok {
-		return nil, errors.New("DATABASE_DSN environment missing")
+	precedence, err := database.NewPrecedence(cfg.Merge)
+	if err != nil {
+		return nil, validationError{err}
 	}
 
-	return database.New(dsn)
+	return service.New(db,
+		service.WithWriteRate(cfg.Database.WriteRate),
+		service.WithRetries(database.Backoff{Retries: cfg.Database.MaxRetries, Base: cfg.Database.RetryBackoff}),
+		service.WithDeadLetters(sink),
+		service.WithPrecedence(precedence),
+	), nil
 }
 
 func safeClose(c io.Closer) {
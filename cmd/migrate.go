@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/storage"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func migrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage the database schema migrations",
+		Args:  usageArgs(cobra.ArbitraryArgs),
+		RunE:  parentRun,
+	}
+
+	up := &cobra.Command{
+		Use:   "up",
+		Short: "Apply every pending migration",
+		Args:  usageArgs(cobra.NoArgs),
+	}
+
+	down := &cobra.Command{
+		Use:   "down [STEPS]",
+		Short: "Revert the last STEPS applied migrations, 1 by default",
+		Args:  usageArgs(cobra.MaximumNArgs(1)),
+	}
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "List the migrations and whether they are applied",
+		Args:  usageArgs(cobra.NoArgs),
+	}
+
+	cmd.AddCommand(
+		withConfig(up, runMigrateUp, "database"),
+		withConfig(down, runMigrateDown, "database"),
+		withConfig(status, runMigrateStatus, "database"),
+	)
+
+	return cmd
+}
+
+func openMigrator(cfg config.Database) (*database.Migrator, error) {
+	if err := requireDSN(cfg); err != nil {
+		return nil, err
+	}
+
+	if storage.IsMemory(cfg.DSN) {
+		return nil, validationError{errors.New("the in-memory backend has no schema to migrate")}
+	}
+
+	return database.NewMigrator(cfg)
+}
+
+func runMigrateUp(ctx context.Context, cfg *config.Config, _ []string) error {
+	m, err := openMigrator(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer safeClose(m)
+
+	applied, err := m.Up(ctx)
+	for _, mig := range applied {
+		log.Info().Int("version", mig.Version).Str("name", mig.Name).Msg("Migration applied")
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Info().Int("version", m.Latest()).Msg("Database schema up to date")
+	return nil
+}
+
+func runMigrateDown(ctx context.Context, cfg *config.Config, args []string) error {
+	steps := 1
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return usageError{fmt.Errorf("invalid STEPS %q, must be a positive integer", args[0])}
+		}
+		steps = n
+	}
+
+	m, err := openMigrator(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer safeClose(m)
+
+	reverted, err := m.Down(ctx, steps)
+	for _, mig := range reverted {
+		log.Info().Int("version", mig.Version).Str("name", mig.Name).Msg("Migration reverted")
+	}
+
+	return err
+}
+
+func runMigrateStatus(ctx context.Context, cfg *config.Config, _ []string) error {
+	m, err := openMigrator(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer safeClose(m)
+
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, s := range status {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = s.AppliedAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\ncurrent version %d, latest %d\n", current, m.Latest())
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/deadletter"
+	"github.com/agukrapo/ports/imports"
+	"github.com/agukrapo/ports/service"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+type replayFlags struct {
+	importID string
+	source   string
+	conflict string
+}
+
+func replayCmd() *cobra.Command {
+	var flags replayFlags
+
+	cmd := &cobra.Command{
+		Use:   "replay [FILE|-]",
+		Short: "Import again the records rejected by an import, once fixed",
+		Long: `Import again the records rejected by an import, once fixed.
+
+The rejected records are read from FILE, in the NDJSON format written by the file dead-letter sink
+and GET /imports/{id}/rejects, or from the configured dead-letter sink when no FILE is given.
+They are imported with the source and conflict policy of the import rejecting them, unless told otherwise.
+Records rejected again are written to the dead-letter sink under a new import ID.`,
+		Example: `  ports replay --import 3f2a9c0e8d7b4a1f6e5d4c3b2a190817
+  curl -s localhost:8080/imports/3f2a9c0e8d7b4a1f6e5d4c3b2a190817/rejects > rejects.ndjson
+  ports replay rejects.ndjson`,
+		Args: usageArgs(cobra.MaximumNArgs(1)),
+	}
+
+	cmd.Flags().StringVar(&flags.importID, "import", "", "ID of the import whose records are replayed, required without FILE")
+	cmd.Flags().StringVar(&flags.source, "source", "", "name of the source the replayed ports come from, the one of their import if empty")
+	cmd.Flags().StringVar(&flags.conflict, "conflict", "", "how a replayed port whose key is stored, or not, is written, see import --conflict, the policy of their import if empty")
+
+	return withConfig(cmd, func(ctx context.Context, cfg *config.Config, args []string) error {
+		return runReplay(ctx, cfg, flags, args)
+	}, "database", "dead_letter", "merge")
+}
+
+func runReplay(ctx context.Context, cfg *config.Config, flags replayFlags, args []string) error {
+	if len(args) == 0 && flags.importID == "" {
+		return usageError{errors.New("--import is required to replay from the dead-letter sink")}
+	}
+	if len(args) == 0 && cfg.DeadLetter.Sink == config.SinkNone {
+		return validationError{errors.New("dead_letter.sink: required to replay without FILE")}
+	}
+	if err := service.ValidateSource(flags.source); err != nil {
+		return usageError{err}
+	}
+	if _, err := database.ParseConflict(flags.conflict); err != nil {
+		return usageError{err}
+	}
+
+	db, err := openDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer safeClose(db)
+
+	svc, err := newService(cfg, db)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := cancelOnInterrupt(ctx)
+	defer cancel()
+
+	id := imports.NewID()
+
+	name := "import " + flags.importID
+	each := func(fn func(*service.DeadLetter) error) error {
+		return svc.DeadLetters(ctx, flags.importID, fn)
+	}
+
+	if len(args) == 1 {
+		name = args[0]
+
+		var r io.Reader = os.Stdin
+		if name != stdio {
+			file, err := os.Open(name)
+			if err != nil {
+				return err
+			}
+			defer safeClose(file)
+
+			r = file
+		}
+
+		each = func(fn func(*service.DeadLetter) error) error {
+			return deadletter.Decode(ctx, r, flags.importID, func(l *service.DeadLetter) error {
+				// FILE may be the file sink, which gets the records this replay rejects.
+				if l.ImportID == id {
+					return nil
+				}
+				return fn(l)
+			})
+		}
+	}
+
+	log.Info().Str("source", name).Str("import", id).Msg("Replay started")
+
+	letters := make(chan *service.DeadLetter)
+	var eachErr error
+	go func() {
+		defer close(letters)
+		eachErr = each(func(l *service.DeadLetter) error {
+			select {
+			case letters <- l:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	// The records are imported in a run per consecutive ones sharing a source and a conflict policy.
+	var (
+		reports []fileReport
+		total   service.Report
+		skipped int
+		start   = time.Now()
+	)
+
+	flagged := replayOptions{source: flags.source, conflict: database.Conflict(flags.conflict)}
+
+	next, ok := <-letters
+	for first := true; first || ok; first = false {
+		if ctx.Err() != nil {
+			break
+		}
+
+		opts := flagged
+		if ok {
+			if opts, err = flagged.of(next); err != nil {
+				return validationError{err}
+			}
+		}
+
+		src := deadletter.NewSource(func(fn func(*service.DeadLetter) error) error {
+			for ; ok; next, ok = <-letters {
+				if o, err := flagged.of(next); err != nil || o != opts {
+					return nil
+				}
+				if err := fn(next); err != nil {
+					return err
+				}
+			}
+			return eachErr
+		})
+		report := svc.Process(ctx, src, service.WithImportID(id), service.WithSource(opts.source), service.WithConflict(opts.conflict))
+
+		skipped += src.Skipped()
+		logReport(name, report, nil)
+
+		reports = append(reports, fileReport{name: name, report: report})
+		total.Add(report)
+	}
+
+	total.Duration = time.Since(start)
+
+	if skipped > 0 {
+		log.Warn().Int("skipped", skipped).Msg("Rejected records without input skipped")
+	}
+
+	return printReports(os.Stdout, reports, total)
+}
+
+// replayOptions represents the source and conflict policy replayed records are imported with.
+type replayOptions struct {
+	source   string
+	conflict database.Conflict
+}
+
+// of returns the options a dead letter is replayed with, the ones of its import unless set.
+func (o replayOptions) of(l *service.DeadLetter) (replayOptions, error) {
+	if o.source == "" {
+		o.source = l.Source
+	}
+	if o.conflict == "" {
+		o.conflict = l.Conflict
+	}
+
+	if err := service.ValidateSource(o.source); err != nil {
+		return o, fmt.Errorf("import %s: %w", l.ImportID, err)
+	}
+	conflict, err := database.ParseConflict(string(o.conflict))
+	if err != nil {
+		return o, fmt.Errorf("import %s: %w", l.ImportID, err)
+	}
+	o.conflict = conflict
+	if o.source == "" {
+		o.source = database.DefaultSource
+	}
+
+	return o, nil
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/agukrapo/ports/auth"
+	"github.com/agukrapo/ports/cdc"
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/grpc"
+	"github.com/agukrapo/ports/idempotency"
+	"github.com/agukrapo/ports/limits"
+	"github.com/agukrapo/ports/rest"
+	"github.com/agukrapo/ports/storage"
+	"github.com/agukrapo/ports/uploads"
+	"github.com/agukrapo/ports/webhooks"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func serveCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run an upload server",
+		Args:  usageArgs(cobra.ArbitraryArgs),
+		RunE:  parentRun,
+	}
+
+	cmd.AddCommand(serveRESTCmd(), serveGRPCCmd())
+
+	return cmd
+}
+
+func serveRESTCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rest",
+		Short: "Run the REST server",
+		Args:  usageArgs(cobra.NoArgs),
+	}
+
+	return withConfig(cmd, runREST, "database", "rest", "auth", "limits", "uploads", "idempotency", "dead_letter", "merge", "cdc", "webhooks")
+}
+
+func serveGRPCCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grpc",
+		Short: "Run the gRPC server",
+		Args:  usageArgs(cobra.NoArgs),
+	}
+
+	return withConfig(cmd, runGRPCServer, "database", "grpc", "auth", "limits", "uploads", "idempotency", "dead_letter", "merge", "cdc", "webhooks")
+}
+
+func runREST(_ context.Context, cfg *config.Config, _ []string) error {
+	authenticator, err := newAuthenticator(cfg.Auth)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer safeClose(db)
+
+	store, err := uploads.NewStore(cfg.Uploads)
+	if err != nil {
+		return err
+	}
+	defer safeClose(store)
+
+	svc, err := newService(cfg, db)
+	if err != nil {
+		return err
+	}
+
+	hooks := newWebhooks(db, cfg.Webhooks)
+
+	stop, err := startWorkers(cfg.CDC, db, hooks)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	server, err := rest.New(cfg.REST, svc, db, authenticator, limits.New(cfg.Limits), store, newGuard(db, cfg.Idempotency), hooks)
+	if err != nil {
+		return err
+	}
+
+	server.Start()
+	server.Listen()
+
+	return nil
+}
+
+func runGRPCServer(_ context.Context, cfg *config.Config, _ []string) error {
+	authenticator, err := newAuthenticator(cfg.Auth)
+	if err != nil {
+		return err
+	}
+
+	db, err := openDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer safeClose(db)
+
+	store, err := uploads.NewStore(cfg.Uploads)
+	if err != nil {
+		return err
+	}
+	defer safeClose(store)
+
+	svc, err := newService(cfg, db)
+	if err != nil {
+		return err
+	}
+
+	hooks := newWebhooks(db, cfg.Webhooks)
+
+	stop, err := startWorkers(cfg.CDC, db, hooks)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	server, err := grpc.NewServer(cfg.GRPC, svc, db, authenticator, limits.New(cfg.Limits), store, newGuard(db, cfg.Idempotency), hooks)
+	if err != nil {
+		return err
+	}
+
+	server.Start()
+	server.Listen()
+	return nil
+}
+
+// newGuard deduplicates the uploads through the storage, unless it does not keep idempotency keys.
+func newGuard(db storage.Storage, cfg config.Idempotency) *idempotency.Guard {
+	store, ok := db.(idempotency.Store)
+	if !ok {
+		log.Warn().Msg("Upload deduplication unsupported by the storage")
+	}
+
+	return idempotency.New(store, cfg.Window, cfg.Lease)
+}
+
+// newWebhooks manages the webhooks through the storage, unless it does not keep them.
+func newWebhooks(db storage.Storage, cfg config.Webhooks) *webhooks.Registry {
+	store, ok := db.(webhooks.Store)
+	if !ok {
+		log.Warn().Msg("Webhooks unsupported by the storage")
+	}
+
+	return webhooks.New(store, cfg)
+}
+
+// startWorkers runs the change events relay and the webhook deliveries in the background,
+// returning the function stopping them.
+func startWorkers(cfg config.CDC, db storage.Storage, hooks *webhooks.Registry) (func(), error) {
+	publisher, err := cdc.Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var publishers cdc.Multi
+	if publisher != nil {
+		publishers = append(publishers, publisher)
+	}
+	if hooks.Enabled() {
+		publishers = append(publishers, hooks)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+
+	if store, ok := db.(cdc.Store); ok {
+		var p cdc.Publisher
+		if len(publishers) > 0 {
+			p = publishers
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cdc.NewRelay(store, p, cfg.PollInterval, cfg.BatchSize, cfg.Retention).Run(ctx)
+		}()
+	} else if publisher != nil {
+		log.Warn().Msg("Change events unsupported by the storage")
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		hooks.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		wg.Wait()
+		safeClose(publishers)
+	}, nil
+}
+
+func newAuthenticator(cfg config.Auth) (*auth.Authenticator, error) {
+	a, err := auth.New(cfg)
+	if err != nil {
+		return nil, validationError{err}
+	}
+
+	if !a.Enabled() {
+		log.Warn().Msg("Authentication disabled, set auth.api_keys_file or auth.jwks_file to enable it")
+	}
+
+	return a, nil
+}
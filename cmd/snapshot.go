@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"text/tabwriter"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/service"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+func snapshotCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Manage the named snapshots of the whole catalogue",
+		Args:  usageArgs(cobra.ArbitraryArgs),
+		RunE:  parentRun,
+	}
+
+	var createdBy string
+
+	create := &cobra.Command{
+		Use:     "create NAME",
+		Short:   "Copy every stored port to a new snapshot",
+		Example: `  ports snapshot create before-sync && ports import supplier.json`,
+		Args:    usageArgs(cobra.ExactArgs(1)),
+	}
+	create.Flags().StringVar(&createdBy, "created-by", currentUser(), "creator recorded in the snapshot")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List the snapshots, oldest first",
+		Args:  usageArgs(cobra.NoArgs),
+	}
+
+	restore := &cobra.Command{
+		Use:   "restore NAME",
+		Short: "Replace every stored port with the ones of a snapshot, in a single transaction",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+	}
+
+	del := &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a snapshot",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+	}
+
+	cmd.AddCommand(
+		withConfig(create, func(ctx context.Context, cfg *config.Config, args []string) error {
+			return runSnapshotCreate(ctx, cfg, args[0], createdBy)
+		}, "database"),
+		withConfig(list, runSnapshotList, "database"),
+		withConfig(restore, runSnapshotRestore, "database"),
+		withConfig(del, runSnapshotDelete, "database"),
+	)
+
+	return cmd
+}
+
+// currentUser returns the name of the user running the command, if known.
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	return u.Username
+}
+
+// withSnapshots opens the database and calls fn with a Service over it.
+func withSnapshots(cfg *config.Config, fn func(*service.Service) error) error {
+	db, err := openDB(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer safeClose(db)
+
+	err = fn(service.New(db))
+	if errors.Is(err, service.ErrInvalidSnapshotName) {
+		return usageError{err}
+	}
+
+	return err
+}
+
+func runSnapshotCreate(ctx context.Context, cfg *config.Config, name, createdBy string) error {
+	return withSnapshots(cfg, func(svc *service.Service) error {
+		s, err := svc.CreateSnapshot(ctx, name, createdBy)
+		if err != nil {
+			return err
+		}
+
+		log.Info().Str("name", s.Name).Int64("rows", s.Rows).Str("checksum", s.Checksum).Msg("Snapshot created")
+		return nil
+	})
+}
+
+func runSnapshotList(ctx context.Context, cfg *config.Config, _ []string) error {
+	return withSnapshots(cfg, func(svc *service.Service) error {
+		snapshots, err := svc.Snapshots(ctx)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "NAME\tCREATED AT\tCREATED BY\tROWS\tCHECKSUM")
+		for _, s := range snapshots {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", s.Name, s.CreatedAt.Format(time.RFC3339), s.CreatedBy, s.Rows, s.Checksum)
+		}
+
+		return tw.Flush()
+	})
+}
+
+func runSnapshotRestore(ctx context.Context, cfg *config.Config, args []string) error {
+	return withSnapshots(cfg, func(svc *service.Service) error {
+		r, err := svc.RestoreSnapshot(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		log.Info().Str("name", args[0]).
+			Int("created", r.Created).
+			Int("updated", r.Updated).
+			Int("deleted", r.Deleted).
+			Int("unchanged", r.Unchanged).
+			Msg("Snapshot restored")
+		return nil
+	})
+}
+
+func runSnapshotDelete(ctx context.Context, cfg *config.Config, args []string) error {
+	return withSnapshots(cfg, func(svc *service.Service) error {
+		if err := svc.DeleteSnapshot(ctx, args[0]); err != nil {
+			return err
+		}
+
+		log.Info().Str("name", args[0]).Msg("Snapshot deleted")
+		return nil
+	})
+}
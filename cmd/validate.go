@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/parser"
+	"github.com/agukrapo/ports/service"
+	"github.com/spf13/cobra"
+)
+
+func validateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate FILE",
+		Short: "Check a ports JSON file without importing it",
+		Args:  usageArgs(cobra.ExactArgs(1)),
+	}
+
+	return withConfig(cmd, runValidate, "log")
+}
+
+func runValidate(ctx context.Context, _ *config.Config, args []string) error {
+	file, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer safeClose(file)
+
+	src, err := parser.New(file)
+	if err != nil {
+		return validationError{err}
+	}
+
+	count, errs := service.Validate(ctx, src)
+	for _, err := range errs {
+		fmt.Println(err)
+	}
+
+	fmt.Printf("%d ports checked, %d invalid\n", count, len(errs))
+
+	if len(errs) > 0 {
+		return validationError{fmt.Errorf("%s: %d invalid ports", args[0], len(errs))}
+	}
+
+	return nil
+}
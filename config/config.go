@@ -0,0 +1,560 @@
+// Package config includes application configuration utilities.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Config represents the whole application configuration.
+type Config struct {
+	Log         Log
+	Database    Database
+	REST        REST
+	GRPC        GRPC
+	Auth        Auth
+	Limits      Limits
+	Uploads     Uploads
+	Idempotency Idempotency
+	DeadLetter  DeadLetter
+	CDC         CDC
+	Webhooks    Webhooks
+	Merge       Merge
+}
+
+// Log represents the logging configuration.
+type Log struct {
+	Level  string
+	Format string
+}
+
+// Database represents the storage configuration.
+type Database struct {
+	DSN             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// WriteRate caps the ports written per second by all the running imports, zero means unlimited.
+	WriteRate int
+	// MaxRetries bounds the retries of a write failing with a transient error, RetryBackoff being the first delay.
+	MaxRetries   int
+	RetryBackoff time.Duration
+}
+
+// REST represents the REST server configuration.
+type REST struct {
+	Address           string
+	ReadHeaderTimeout time.Duration
+	// DrainDelay is how long the server keeps serving once reporting it is not ready, before shutting down,
+	// for the load balancers to stop routing requests to it.
+	DrainDelay      time.Duration
+	ShutdownTimeout time.Duration
+	MaxBodySize     int
+	// TrustedProxies lists the proxies whose X-Forwarded-For header gives the client IP, as comma separated IPs or CIDRs.
+	TrustedProxies string
+	TLS            TLS
+}
+
+// Proxies parses the trusted proxies.
+func (r REST) Proxies() ([]*net.IPNet, error) {
+	var out []*net.IPNet
+	for _, proxy := range strings.Split(r.TrustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy == "" {
+			continue
+		}
+
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("rest.trusted_proxies: invalid IP %q", proxy)
+			}
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("rest.trusted_proxies: invalid CIDR %q", proxy)
+		}
+		out = append(out, ipNet)
+	}
+
+	return out, nil
+}
+
+// GRPC represents the gRPC server and client configuration.
+type GRPC struct {
+	Address         string
+	ShutdownTimeout time.Duration
+	ChunkSize       int
+	TLS             TLS
+}
+
+// TLS represents a server TLS configuration, it is disabled when no certificate is set.
+type TLS struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mutual TLS, requiring client certificates signed by its CA bundle.
+	ClientCAFile string
+}
+
+// Enabled tells whether the server uses TLS.
+func (t TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+func (t TLS) validate(section string) []error {
+	var errs []error
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("%s.tls_cert_file: must be set along with %s.tls_key_file", section, section))
+	}
+	if t.ClientCAFile != "" && t.CertFile == "" {
+		errs = append(errs, fmt.Errorf("%s.tls_client_ca_file: requires %s.tls_cert_file", section, section))
+	}
+	return errs
+}
+
+// Auth represents the servers authentication configuration, it is disabled when no credential source is set.
+type Auth struct {
+	APIKeysFile string
+	JWKSFile    string
+	Issuer      string
+	Audience    string
+}
+
+// Enabled tells whether any credential source is configured.
+func (a Auth) Enabled() bool {
+	return a.APIKeysFile != "" || a.JWKSFile != ""
+}
+
+// Limits represents the servers request rate and import concurrency limits, zero values disable them.
+type Limits struct {
+	RequestRate        int
+	RequestBurst       int
+	MaxImports         int
+	ImportQueueTimeout time.Duration
+}
+
+// Uploads represents the resumable upload sessions configuration.
+type Uploads struct {
+	// Dir holds the sessions data, the system temporary directory when empty.
+	Dir        string
+	SessionTTL time.Duration
+	MaxSize    int
+}
+
+// Idempotency represents the upload deduplication configuration.
+type Idempotency struct {
+	// Window is how long a submission is remembered, zero disables deduplication.
+	Window time.Duration
+	// Lease is how long a submission being imported holds its key without a heartbeat from its importer.
+	Lease time.Duration
+}
+
+// DeadLetter represents the rejected records sink configuration.
+type DeadLetter struct {
+	Sink string
+	// File is the NDJSON file appended by the file sink.
+	File string
+}
+
+// Dead-letter sinks.
+const (
+	SinkNone     = "none"
+	SinkFile     = "file"
+	SinkDatabase = "database"
+)
+
+// CDC represents the port change events relay configuration.
+type CDC struct {
+	Publisher   string
+	WebhookURL  string
+	NATSURL     string
+	NATSSubject string
+	// File is the NDJSON file appended by the file publisher.
+	File         string
+	PollInterval time.Duration
+	BatchSize    int
+	// Retention is how long the change log is kept, zero keeps it forever.
+	Retention time.Duration
+}
+
+// Webhooks represents the webhook deliveries configuration.
+type Webhooks struct {
+	// MaxAttempts bounds the attempts of a delivery, RetryBackoff being the delay before the first retry.
+	MaxAttempts  int
+	RetryBackoff time.Duration
+	PollInterval time.Duration
+	Timeout      time.Duration
+	// Retention is how long the finished deliveries are logged, zero keeps them forever.
+	Retention time.Duration
+}
+
+// MergeFields lists the port fields merged by precedence, coordinates standing for the latitude and longitude together.
+// The alias and unlocs lists are merged as the union of every source, in precedence order.
+var MergeFields = []string{"code", "name", "city", "province", "country", "timezone", "coordinates", "alias", "unlocs"}
+
+// Merge represents how the port values imported from several sources are merged.
+type Merge struct {
+	// Precedence ranks the sources, comma separated, the first one supplying a field winning.
+	Precedence string
+	// FieldPrecedence overrides Precedence per field, as FIELD=SOURCE,SOURCE rankings separated by semicolons.
+	FieldPrecedence string
+}
+
+// Parse returns the source ranking and the per field rankings.
+func (m Merge) Parse() ([]string, map[string][]string, error) {
+	order, err := ranking(m.Precedence)
+	if err != nil {
+		return nil, nil, fmt.Errorf("merge.precedence: %w", err)
+	}
+
+	fields := make(map[string][]string)
+	for _, pair := range strings.Split(m.FieldPrecedence, ";") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+
+		field, sources, found := strings.Cut(pair, "=")
+		field = strings.TrimSpace(field)
+		if !found {
+			return nil, nil, fmt.Errorf("merge.field_precedence: %q must be FIELD=SOURCE,SOURCE", pair)
+		}
+		if !contains(MergeFields, field) {
+			return nil, nil, fmt.Errorf("merge.field_precedence: unknown field %q, must be one of %s", field, strings.Join(MergeFields, ", "))
+		}
+		if _, ok := fields[field]; ok {
+			return nil, nil, fmt.Errorf("merge.field_precedence: field %q ranked twice", field)
+		}
+
+		if fields[field], err = ranking(sources); err != nil {
+			return nil, nil, fmt.Errorf("merge.field_precedence: %s: %w", field, err)
+		}
+	}
+
+	return order, fields, nil
+}
+
+func ranking(s string) ([]string, error) {
+	var out []string
+	for _, source := range strings.Split(s, ",") {
+		if source = strings.TrimSpace(source); source == "" {
+			continue
+		}
+		if contains(out, source) {
+			return nil, fmt.Errorf("source %q ranked twice", source)
+		}
+		out = append(out, source)
+	}
+
+	return out, nil
+}
+
+// CDC publishers.
+const (
+	PublisherNone    = "none"
+	PublisherWebhook = "webhook"
+	PublisherNATS    = "nats"
+	PublisherFile    = "file"
+)
+
+// Log formats.
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+)
+
+// Default returns a Config populated with default values.
+func Default() *Config {
+	return &Config{
+		Log: Log{
+			Level:  zerolog.InfoLevel.String(),
+			Format: FormatConsole,
+		},
+		Database: Database{
+			MaxOpenConns:    10,
+			MaxIdleConns:    2,
+			ConnMaxLifetime: time.Hour,
+			MaxRetries:      3,
+			RetryBackoff:    100 * time.Millisecond,
+		},
+		REST: REST{
+			Address:           ":8080",
+			ReadHeaderTimeout: 10 * time.Second,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   3 * time.Second,
+			MaxBodySize:       100 << 20,
+		},
+		Uploads: Uploads{
+			SessionTTL: 24 * time.Hour,
+			MaxSize:    1 << 30,
+		},
+		Idempotency: Idempotency{
+			Window: 24 * time.Hour,
+			Lease:  time.Minute,
+		},
+		DeadLetter: DeadLetter{
+			Sink: SinkNone,
+			File: "rejects.ndjson",
+		},
+		CDC: CDC{
+			Publisher:    PublisherNone,
+			NATSSubject:  "ports.changes",
+			File:         "changes.ndjson",
+			PollInterval: time.Second,
+			BatchSize:    100,
+			Retention:    7 * 24 * time.Hour,
+		},
+		Webhooks: Webhooks{
+			MaxAttempts:  8,
+			RetryBackoff: 10 * time.Second,
+			PollInterval: time.Second,
+			Timeout:      10 * time.Second,
+			Retention:    7 * 24 * time.Hour,
+		},
+		GRPC: GRPC{
+			Address:         ":8080",
+			ShutdownTimeout: 3 * time.Second,
+			ChunkSize:       1024,
+		},
+	}
+}
+
+// Validate checks the Config values.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if _, err := zerolog.ParseLevel(c.Log.Level); err != nil || c.Log.Level == "" {
+		errs = append(errs, fmt.Errorf("log.level: invalid level %q", c.Log.Level))
+	}
+	if c.Log.Format != FormatConsole && c.Log.Format != FormatJSON {
+		errs = append(errs, fmt.Errorf("log.format: must be %s or %s", FormatConsole, FormatJSON))
+	}
+
+	if c.Database.MaxOpenConns < 0 {
+		errs = append(errs, errors.New("database.max_open_conns: must not be negative"))
+	}
+	if c.Database.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database.max_idle_conns: must not be negative"))
+	}
+	if c.Database.ConnMaxLifetime < 0 {
+		errs = append(errs, errors.New("database.conn_max_lifetime: must not be negative"))
+	}
+	if c.Database.WriteRate < 0 {
+		errs = append(errs, errors.New("database.write_rate: must not be negative"))
+	}
+	if c.Database.MaxRetries < 0 {
+		errs = append(errs, errors.New("database.max_retries: must not be negative"))
+	}
+	if c.Database.RetryBackoff < 0 {
+		errs = append(errs, errors.New("database.retry_backoff: must not be negative"))
+	}
+
+	if c.REST.Address == "" {
+		errs = append(errs, errors.New("rest.address: must not be empty"))
+	}
+	if c.REST.ReadHeaderTimeout <= 0 {
+		errs = append(errs, errors.New("rest.read_header_timeout: must be positive"))
+	}
+	if c.REST.DrainDelay < 0 {
+		errs = append(errs, errors.New("rest.drain_delay: must not be negative"))
+	}
+	if c.REST.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("rest.shutdown_timeout: must be positive"))
+	}
+
+	if c.REST.MaxBodySize <= 0 {
+		errs = append(errs, errors.New("rest.max_body_size: must be positive"))
+	}
+	if _, err := c.REST.Proxies(); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, c.REST.TLS.validate("rest")...)
+
+	if c.GRPC.Address == "" {
+		errs = append(errs, errors.New("grpc.address: must not be empty"))
+	}
+	if c.GRPC.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("grpc.shutdown_timeout: must be positive"))
+	}
+	if c.GRPC.ChunkSize <= 0 {
+		errs = append(errs, errors.New("grpc.chunk_size: must be positive"))
+	}
+	errs = append(errs, c.GRPC.TLS.validate("grpc")...)
+
+	if c.Limits.RequestRate < 0 {
+		errs = append(errs, errors.New("limits.request_rate: must not be negative"))
+	}
+	if c.Limits.RequestBurst < 0 {
+		errs = append(errs, errors.New("limits.request_burst: must not be negative"))
+	}
+	if c.Limits.MaxImports < 0 {
+		errs = append(errs, errors.New("limits.max_imports: must not be negative"))
+	}
+	if c.Limits.ImportQueueTimeout < 0 {
+		errs = append(errs, errors.New("limits.import_queue_timeout: must not be negative"))
+	}
+
+	if c.Uploads.SessionTTL <= 0 {
+		errs = append(errs, errors.New("uploads.session_ttl: must be positive"))
+	}
+	if c.Uploads.MaxSize <= 0 {
+		errs = append(errs, errors.New("uploads.max_size: must be positive"))
+	}
+
+	if c.Idempotency.Window < 0 {
+		errs = append(errs, errors.New("idempotency.window: must not be negative"))
+	}
+	if c.Idempotency.Lease <= 0 {
+		errs = append(errs, errors.New("idempotency.lease: must be positive"))
+	}
+
+	switch c.DeadLetter.Sink {
+	case SinkNone, SinkDatabase:
+	case SinkFile:
+		if c.DeadLetter.File == "" {
+			errs = append(errs, errors.New("dead_letter.file: required by the file sink"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("dead_letter.sink: must be %s, %s or %s", SinkNone, SinkFile, SinkDatabase))
+	}
+
+	switch c.CDC.Publisher {
+	case PublisherNone:
+	case PublisherWebhook:
+		if c.CDC.WebhookURL == "" {
+			errs = append(errs, errors.New("cdc.webhook_url: required by the webhook publisher"))
+		}
+	case PublisherNATS:
+		if c.CDC.NATSURL == "" {
+			errs = append(errs, errors.New("cdc.nats_url: required by the nats publisher"))
+		}
+		if c.CDC.NATSSubject == "" {
+			errs = append(errs, errors.New("cdc.nats_subject: required by the nats publisher"))
+		}
+	case PublisherFile:
+		if c.CDC.File == "" {
+			errs = append(errs, errors.New("cdc.file: required by the file publisher"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("cdc.publisher: must be %s, %s, %s or %s", PublisherNone, PublisherWebhook, PublisherNATS, PublisherFile))
+	}
+	if c.CDC.PollInterval <= 0 {
+		errs = append(errs, errors.New("cdc.poll_interval: must be positive"))
+	}
+	if c.CDC.BatchSize <= 0 {
+		errs = append(errs, errors.New("cdc.batch_size: must be positive"))
+	}
+	if c.CDC.Retention < 0 {
+		errs = append(errs, errors.New("cdc.retention: must not be negative"))
+	}
+
+	if c.Webhooks.MaxAttempts <= 0 {
+		errs = append(errs, errors.New("webhooks.max_attempts: must be positive"))
+	}
+	if c.Webhooks.RetryBackoff < 0 {
+		errs = append(errs, errors.New("webhooks.retry_backoff: must not be negative"))
+	}
+	if c.Webhooks.PollInterval <= 0 {
+		errs = append(errs, errors.New("webhooks.poll_interval: must be positive"))
+	}
+	if c.Webhooks.Timeout <= 0 {
+		errs = append(errs, errors.New("webhooks.timeout: must be positive"))
+	}
+	if c.Webhooks.Retention < 0 {
+		errs = append(errs, errors.New("webhooks.retention: must not be negative"))
+	}
+
+	if _, _, err := c.Merge.Parse(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.Auth.JWKSFile == "" && (c.Auth.Issuer != "" || c.Auth.Audience != "") {
+		errs = append(errs, errors.New("auth.jwks_file: required by auth.issuer and auth.audience"))
+	}
+
+	return join(errs)
+}
+
+const redacted = "xxxxx"
+
+var passwordRE = regexp.MustCompile(`(password=)\S+`)
+
+// Redacted returns a copy of the Config without secrets, suitable for printing.
+func (c *Config) Redacted() *Config {
+	out := *c
+
+	if u, err := url.Parse(c.Database.DSN); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			out.Database.DSN = u.String()
+		}
+	} else {
+		out.Database.DSN = passwordRE.ReplaceAllString(c.Database.DSN, "${1}"+redacted)
+	}
+
+	out.CDC.WebhookURL = redactURL(c.CDC.WebhookURL)
+
+	servers := strings.Split(c.CDC.NATSURL, ",")
+	for i, s := range servers {
+		servers[i] = redactURL(s)
+	}
+	out.CDC.NATSURL = strings.Join(servers, ",")
+
+	return &out
+}
+
+// redactURL masks the userinfo and the query values of a URL, where credentials and tokens are given,
+// or the whole URL when invalid.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return redacted
+	}
+
+	if u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		} else {
+			u.User = url.User(redacted)
+		}
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			q[k] = []string{redacted}
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String()
+}
+
+type multiError []error
+
+func (e multiError) Error() string {
+	out := "invalid configuration:"
+	for _, err := range e {
+		out += "\n  " + err.Error()
+	}
+	return out
+}
+
+func join(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return multiError(errs)
+}
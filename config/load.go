@@ -0,0 +1,353 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "PORTS_"
+
+// setting binds a Config field to its file key, environment variable and command line flag.
+type setting struct {
+	key   string
+	usage string
+	value func(*Config) flag.Value
+}
+
+var settings = []setting{
+	{"log.level", "log level (trace, debug, info, warn, error)", func(c *Config) flag.Value { return (*stringValue)(&c.Log.Level) }},
+	{"log.format", "log format (console, json)", func(c *Config) flag.Value { return (*stringValue)(&c.Log.Format) }},
+	{"database.dsn", "database connection string", func(c *Config) flag.Value { return (*stringValue)(&c.Database.DSN) }},
+	{"database.max_open_conns", "maximum open database connections, 0 means unlimited", func(c *Config) flag.Value { return (*intValue)(&c.Database.MaxOpenConns) }},
+	{"database.max_idle_conns", "maximum idle database connections", func(c *Config) flag.Value { return (*intValue)(&c.Database.MaxIdleConns) }},
+	{"database.conn_max_lifetime", "maximum database connection lifetime, 0 means unlimited", func(c *Config) flag.Value { return (*durationValue)(&c.Database.ConnMaxLifetime) }},
+	{"database.write_rate", "maximum ports written per second by all the running imports, 0 means unlimited", func(c *Config) flag.Value { return (*intValue)(&c.Database.WriteRate) }},
+	{"database.max_retries", "maximum retries of a write failing with a transient error, 0 disables them", func(c *Config) flag.Value { return (*intValue)(&c.Database.MaxRetries) }},
+	{"database.retry_backoff", "maximum delay before the first retry, doubled for each following one", func(c *Config) flag.Value { return (*durationValue)(&c.Database.RetryBackoff) }},
+	{"rest.address", "REST server listen address", func(c *Config) flag.Value { return (*stringValue)(&c.REST.Address) }},
+	{"rest.read_header_timeout", "REST server request header read timeout", func(c *Config) flag.Value { return (*durationValue)(&c.REST.ReadHeaderTimeout) }},
+	{"rest.drain_delay", "REST server delay between reporting it is not ready and shutting down", func(c *Config) flag.Value { return (*durationValue)(&c.REST.DrainDelay) }},
+	{"rest.shutdown_timeout", "REST server graceful shutdown timeout", func(c *Config) flag.Value { return (*durationValue)(&c.REST.ShutdownTimeout) }},
+	{"rest.max_body_size", "REST server maximum upload body size in bytes", func(c *Config) flag.Value { return (*intValue)(&c.REST.MaxBodySize) }},
+	{"rest.trusted_proxies", "REST server proxies trusted to forward the client IP, comma separated IPs or CIDRs", func(c *Config) flag.Value { return (*stringValue)(&c.REST.TrustedProxies) }},
+	{"rest.tls_cert_file", "REST server TLS certificate file, enables TLS", func(c *Config) flag.Value { return (*stringValue)(&c.REST.TLS.CertFile) }},
+	{"rest.tls_key_file", "REST server TLS private key file", func(c *Config) flag.Value { return (*stringValue)(&c.REST.TLS.KeyFile) }},
+	{"rest.tls_client_ca_file", "REST server client CA bundle, enables mutual TLS", func(c *Config) flag.Value { return (*stringValue)(&c.REST.TLS.ClientCAFile) }},
+	{"grpc.address", "gRPC server listen address", func(c *Config) flag.Value { return (*stringValue)(&c.GRPC.Address) }},
+	{"grpc.shutdown_timeout", "gRPC server graceful shutdown timeout", func(c *Config) flag.Value { return (*durationValue)(&c.GRPC.ShutdownTimeout) }},
+	{"grpc.chunk_size", "gRPC client upload chunk size in bytes", func(c *Config) flag.Value { return (*intValue)(&c.GRPC.ChunkSize) }},
+	{"grpc.tls_cert_file", "gRPC server TLS certificate file, enables TLS", func(c *Config) flag.Value { return (*stringValue)(&c.GRPC.TLS.CertFile) }},
+	{"grpc.tls_key_file", "gRPC server TLS private key file", func(c *Config) flag.Value { return (*stringValue)(&c.GRPC.TLS.KeyFile) }},
+	{"grpc.tls_client_ca_file", "gRPC server client CA bundle, enables mutual TLS", func(c *Config) flag.Value { return (*stringValue)(&c.GRPC.TLS.ClientCAFile) }},
+	{"auth.api_keys_file", "API keys file, enables authentication", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.APIKeysFile) }},
+	{"auth.jwks_file", "JWKS file verifying JWT bearer tokens, enables authentication", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.JWKSFile) }},
+	{"auth.issuer", "required JWT issuer (iss claim)", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.Issuer) }},
+	{"auth.audience", "required JWT audience (aud claim)", func(c *Config) flag.Value { return (*stringValue)(&c.Auth.Audience) }},
+	{"uploads.dir", "resumable upload sessions directory, the system temporary directory by default", func(c *Config) flag.Value { return (*stringValue)(&c.Uploads.Dir) }},
+	{"uploads.session_ttl", "time after which an upload session without new data is discarded", func(c *Config) flag.Value { return (*durationValue)(&c.Uploads.SessionTTL) }},
+	{"uploads.max_size", "maximum resumable upload size in bytes", func(c *Config) flag.Value { return (*intValue)(&c.Uploads.MaxSize) }},
+	{"idempotency.window", "time a duplicate upload returns the original import result instead of running again, 0 disables it", func(c *Config) flag.Value { return (*durationValue)(&c.Idempotency.Window) }},
+	{"idempotency.lease", "time an upload being imported keeps its idempotency key once its server stops renewing it", func(c *Config) flag.Value { return (*durationValue)(&c.Idempotency.Lease) }},
+	{"dead_letter.sink", "where rejected records are written: none, file or database", func(c *Config) flag.Value { return (*stringValue)(&c.DeadLetter.Sink) }},
+	{"dead_letter.file", "NDJSON file the file dead-letter sink appends to", func(c *Config) flag.Value { return (*stringValue)(&c.DeadLetter.File) }},
+	{"cdc.publisher", "where port change events are relayed: none, webhook, nats or file", func(c *Config) flag.Value { return (*stringValue)(&c.CDC.Publisher) }},
+	{"cdc.webhook_url", "URL the webhook publisher posts the change events to", func(c *Config) flag.Value { return (*stringValue)(&c.CDC.WebhookURL) }},
+	{"cdc.nats_url", "NATS server URL of the nats publisher", func(c *Config) flag.Value { return (*stringValue)(&c.CDC.NATSURL) }},
+	{"cdc.nats_subject", "NATS subject the change events are published to", func(c *Config) flag.Value { return (*stringValue)(&c.CDC.NATSSubject) }},
+	{"cdc.file", "NDJSON file the file publisher appends to", func(c *Config) flag.Value { return (*stringValue)(&c.CDC.File) }},
+	{"cdc.poll_interval", "time between the change log polls", func(c *Config) flag.Value { return (*durationValue)(&c.CDC.PollInterval) }},
+	{"cdc.batch_size", "maximum change events relayed per poll", func(c *Config) flag.Value { return (*intValue)(&c.CDC.BatchSize) }},
+	{"cdc.retention", "time the change log is kept, 0 keeps it forever", func(c *Config) flag.Value { return (*durationValue)(&c.CDC.Retention) }},
+	{"webhooks.max_attempts", "maximum attempts of a webhook delivery", func(c *Config) flag.Value { return (*intValue)(&c.Webhooks.MaxAttempts) }},
+	{"webhooks.retry_backoff", "delay before the first webhook delivery retry, doubled for each following one", func(c *Config) flag.Value { return (*durationValue)(&c.Webhooks.RetryBackoff) }},
+	{"webhooks.poll_interval", "time between the pending webhook deliveries polls", func(c *Config) flag.Value { return (*durationValue)(&c.Webhooks.PollInterval) }},
+	{"webhooks.timeout", "webhook delivery request timeout", func(c *Config) flag.Value { return (*durationValue)(&c.Webhooks.Timeout) }},
+	{"webhooks.retention", "time the finished webhook deliveries are logged, 0 keeps them forever", func(c *Config) flag.Value { return (*durationValue)(&c.Webhooks.Retention) }},
+	{"merge.precedence", "comma separated sources ranked by reliability, the first one supplying a port field wins", func(c *Config) flag.Value { return (*stringValue)(&c.Merge.Precedence) }},
+	{"merge.field_precedence", "per field source rankings overriding merge.precedence, e.g. coordinates=geo,supplier-a;alias=supplier-b", func(c *Config) flag.Value { return (*stringValue)(&c.Merge.FieldPrecedence) }},
+	{"limits.request_rate", "maximum requests per second per client, 0 means unlimited", func(c *Config) flag.Value { return (*intValue)(&c.Limits.RequestRate) }},
+	{"limits.request_burst", "requests a client may burst above the rate, defaults to the rate", func(c *Config) flag.Value { return (*intValue)(&c.Limits.RequestBurst) }},
+	{"limits.max_imports", "maximum concurrent imports, 0 means unlimited", func(c *Config) flag.Value { return (*intValue)(&c.Limits.MaxImports) }},
+	{"limits.import_queue_timeout", "time an import waits for a free slot, 0 rejects it right away", func(c *Config) flag.Value { return (*durationValue)(&c.Limits.ImportQueueTimeout) }},
+}
+
+func (s setting) flag() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.key)
+}
+
+func (s setting) env() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(s.key, ".", "_"))
+}
+
+// Loader loads a Config from, in increasing order of precedence:
+// defaults, a YAML or TOML file, environment variables and command line flags.
+type Loader struct {
+	file  string
+	flags map[string]string
+}
+
+// Bind registers the configuration file flag and the flags of the given sections (e.g. "rest") in a flag set,
+// all sections are registered if none is given.
+func Bind(fs *pflag.FlagSet, sections ...string) *Loader {
+	l := &Loader{
+		flags: make(map[string]string),
+	}
+
+	fs.StringVar(&l.file, "config", "", "configuration file path (YAML or TOML), also "+envPrefix+"CONFIG")
+
+	for _, s := range settings {
+		if section, _ := split(s.key); len(sections) > 0 && !contains(sections, section) {
+			continue
+		}
+
+		r := &recorder{key: s.key, typ: typeOf(s.value(Default())), flags: l.flags}
+		fs.Var(r, s.flag(), fmt.Sprintf("%s, also %s", s.usage, s.env()))
+	}
+
+	return l
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(v flag.Value) string {
+	switch v.(type) {
+	case *intValue:
+		return "int"
+	case *durationValue:
+		return "duration"
+	default:
+		return "string"
+	}
+}
+
+// Load builds and validates a Config, must be called after parsing the bound flag set.
+func (l *Loader) Load() (*Config, error) {
+	cfg := Default()
+
+	file := l.file
+	if file == "" {
+		file = os.Getenv(envPrefix + "CONFIG")
+	}
+
+	if file != "" {
+		if err := loadFile(cfg, file); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	for _, s := range settings {
+		if v, ok := l.flags[s.key]; ok {
+			if err := s.value(cfg).Set(v); err != nil {
+				return nil, fmt.Errorf("flag --%s: %w", s.flag(), err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	raw := make(map[string]interface{})
+
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return fmt.Errorf("config file %s: unsupported extension %q", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten("", raw, values)
+
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+
+	var errs []error
+	for k, v := range values {
+		s, ok := byKey[k]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown key", k))
+			continue
+		}
+
+		if err := s.value(cfg).Set(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", k, err))
+		}
+	}
+
+	if err := join(errs); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func flatten(prefix string, in map[string]interface{}, out map[string]string) {
+	for k, v := range in {
+		key := prefix + k
+
+		if m, ok := v.(map[string]interface{}); ok {
+			flatten(key+".", m, out)
+			continue
+		}
+
+		out[key] = fmt.Sprint(v)
+	}
+}
+
+func loadEnv(cfg *Config) error {
+	// Legacy variables, kept for backwards compatibility.
+	if v, ok := os.LookupEnv("DATABASE_DSN"); ok {
+		cfg.Database.DSN = v
+	}
+	if v, ok := os.LookupEnv("PORT"); ok {
+		cfg.REST.Address = ":" + v
+		cfg.GRPC.Address = ":" + v
+	}
+
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env()); ok {
+			if err := s.value(cfg).Set(v); err != nil {
+				return fmt.Errorf("environment %s: %w", s.env(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Print writes the Config as YAML, the output is a valid configuration file.
+func Print(cfg *Config) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	sections := make(map[string]*yaml.Node)
+
+	for _, s := range settings {
+		section, name := split(s.key)
+
+		node, ok := sections[section]
+		if !ok {
+			node = &yaml.Node{Kind: yaml.MappingNode}
+			sections[section] = node
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: section}, node)
+		}
+
+		v := s.value(cfg)
+		value := &yaml.Node{Kind: yaml.ScalarNode, Value: v.String()}
+		if _, ok := v.(*stringValue); ok {
+			value.Tag = "!!str"
+		}
+
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, value)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func split(key string) (string, string) {
+	i := strings.LastIndex(key, ".")
+	return key[:i], key[i+1:]
+}
+
+// recorder stores a flag value as is, to be applied after the file and the environment.
+type recorder struct {
+	key   string
+	typ   string
+	value string
+	flags map[string]string
+}
+
+func (r *recorder) Type() string {
+	return r.typ
+}
+
+func (r *recorder) String() string {
+	if r == nil {
+		return ""
+	}
+	return r.value
+}
+
+func (r *recorder) Set(v string) error {
+	r.value = v
+	r.flags[r.key] = v
+	return nil
+}
+
+type stringValue string
+
+func (v *stringValue) String() string { return string(*v) }
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+type intValue int
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+
+type durationValue time.Duration
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
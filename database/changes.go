@@ -0,0 +1,263 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// relayLock is the Postgres advisory lock key serializing the outbox relays.
+const relayLock = 7_263_001
+
+// Change types.
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// Change represents an outbox database table, holding an event per port insert, update or delete.
+type Change struct {
+	ID int64 `gorm:"primarykey"`
+	// Seq orders the Changes by commit, nil until stamped by SequenceChanges.
+	Seq  *int64
+	Key  string
+	Type string
+	// Fields holds the JSON encoded names of the changed fields, Before and After the JSON encoded Port, if any.
+	Fields      string
+	Before      string
+	After       string
+	CreatedAt   time.Time
+	PublishedAt *time.Time
+}
+
+// TableName overrides the gorm default table name.
+func (Change) TableName() string {
+	return "outbox"
+}
+
+// Diff returns the names of the fields whose values differ between two Ports, nil standing for a missing one.
+func Diff(before, after *Port) []string {
+	var a, b Port
+	if before != nil {
+		a = *before
+	}
+	if after != nil {
+		b = *after
+	}
+
+	var out []string
+	add := func(name string, changed bool) {
+		if changed {
+			out = append(out, name)
+		}
+	}
+
+	add("code", a.Code != b.Code)
+	add("name", a.Name != b.Name)
+	add("city", a.City != b.City)
+	add("province", a.Province != b.Province)
+	add("country", a.Country != b.Country)
+	add("timezone", a.Timezone != b.Timezone)
+	add("latitude", a.Latitude != b.Latitude)
+	add("longitude", a.Longitude != b.Longitude)
+	add("unlocs", !equal(a.Unlocs, b.Unlocs))
+	add("alias", !equal(a.Alias, b.Alias))
+
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// newChange returns the Change turning before into after, nil for an update changing nothing.
+func newChange(key string, before, after *Port) (*Change, error) {
+	fields := Diff(before, after)
+
+	out := &Change{Key: key, CreatedAt: time.Now().UTC()}
+	switch {
+	case before == nil:
+		out.Type = ChangeCreate
+	case after == nil:
+		out.Type = ChangeDelete
+	case len(fields) == 0:
+		return nil, nil
+	default:
+		out.Type = ChangeUpdate
+	}
+
+	if fields == nil {
+		fields = []string{}
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	out.Fields = string(data)
+
+	if out.Before, err = encode(before); err != nil {
+		return nil, err
+	}
+	if out.After, err = encode(after); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+func encode(p *Port) (string, error) {
+	if p == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(p)
+	return string(data), err
+}
+
+// current returns the stored Port with the given key, locked until the end of the transaction on Postgres, or nil.
+func (db *Database) current(tx *gorm.DB, key string) (*Port, error) {
+	if db.schema.dialect == Postgres {
+		tx = tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	}
+
+	var out Port
+	err := tx.Where("key = ?", key).Take(&out).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// SequenceChanges stamps the committed Changes not sequenced yet with the following sequence numbers, in ID order,
+// up to limit, returning the number stamped. Once stamped, Changes committed late, after others taking a greater ID,
+// are still read after them.
+// Concurrent calls on Postgres are serialized with PublishChanges, a call returning right away while another one runs.
+func (db *Database) SequenceChanges(ctx context.Context, limit int) (int, error) {
+	var stamped int
+
+	err := db.relay(ctx, func(tx *gorm.DB) error {
+		res := tx.Exec(`UPDATE outbox SET seq = sequenced.seq + pending.n
+FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY id) AS n FROM outbox WHERE seq IS NULL ORDER BY id LIMIT ?) AS pending,
+	(SELECT COALESCE(MAX(seq), 0) AS seq FROM outbox) AS sequenced
+WHERE outbox.id = pending.id`, limit)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		stamped = int(res.RowsAffected)
+		return nil
+	})
+
+	return stamped, err
+}
+
+// PublishChanges calls fn with the oldest unpublished sequenced Changes, up to limit, marking them published once it succeeds.
+// It returns the number of Changes published.
+// Concurrent calls on Postgres are serialized, a call returning right away while another one runs.
+func (db *Database) PublishChanges(ctx context.Context, limit int, fn func([]Change) error) (int, error) {
+	var published int
+
+	err := db.relay(ctx, func(tx *gorm.DB) error {
+		var changes []Change
+		if err := tx.Where("published_at IS NULL AND seq IS NOT NULL").Order("seq").Limit(limit).Find(&changes).Error; err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+
+		if err := fn(changes); err != nil {
+			return err
+		}
+
+		ids := make([]int64, len(changes))
+		for i, c := range changes {
+			ids[i] = c.ID
+		}
+
+		if err := tx.Model(&Change{}).Where("id IN ?", ids).Update("published_at", time.Now().UTC()).Error; err != nil {
+			return err
+		}
+
+		published = len(changes)
+		return nil
+	})
+
+	return published, err
+}
+
+// relay runs fn holding the relay lock in a transaction on Postgres, skipping it while another relay holds it.
+func (db *Database) relay(ctx context.Context, fn func(*gorm.DB) error) error {
+	if db.schema.dialect != Postgres {
+		// A transaction would hold an SQLite snapshot while publishing, failing its final write if the imports wrote meanwhile.
+		return fn(db.db.WithContext(ctx))
+	}
+
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", relayLock).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			return nil
+		}
+
+		return fn(tx)
+	})
+}
+
+// Changes returns the Changes following a sequence number, in order, up to limit.
+func (db *Database) Changes(ctx context.Context, after int64, limit int) ([]Change, error) {
+	var out []Change
+	if err := db.db.WithContext(ctx).Where("seq > ?", after).Order("seq").Limit(limit).Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// ChangeLogBounds returns the sequence numbers of the first and last Changes kept, zero when there are none.
+func (db *Database) ChangeLogBounds(ctx context.Context) (int64, int64, error) {
+	var bounds struct {
+		First, Last *int64
+	}
+	if err := db.db.WithContext(ctx).Model(&Change{}).Select("MIN(seq) AS first, MAX(seq) AS last").Scan(&bounds).Error; err != nil {
+		return 0, 0, err
+	}
+
+	if bounds.First == nil || bounds.Last == nil {
+		return 0, 0, nil
+	}
+
+	return *bounds.First, *bounds.Last, nil
+}
+
+// PruneChanges deletes the sequenced Changes created before a time, only the published ones if told so,
+// returning the number deleted. The last one is kept, so the sequence carries on from it.
+func (db *Database) PruneChanges(ctx context.Context, before time.Time, published bool) (int64, error) {
+	tx := db.db.WithContext(ctx).Where("created_at < ? AND seq < (SELECT MAX(seq) FROM outbox)", before.UTC())
+	if published {
+		tx = tx.Where("published_at IS NOT NULL")
+	}
+
+	tx = tx.Delete(&Change{})
+	return tx.RowsAffected, tx.Error
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	port := &Port{Key: "AEAJM", Name: "Ajman", Country: "United Arab Emirates", Latitude: 55.5, Unlocs: pq.StringArray{"AEAJM"}}
+
+	changed := *port
+	changed.Name = "Ajman Port"
+	changed.Unlocs = pq.StringArray{"AEAJM", "AEAJX"}
+
+	empty := *port
+	empty.Unlocs = pq.StringArray{}
+	empty.Alias = pq.StringArray{}
+
+	require.Empty(t, Diff(port, port))
+	require.Equal(t, []string{"name", "unlocs"}, Diff(port, &changed))
+	require.Equal(t, []string{"unlocs"}, Diff(port, &empty))
+	require.Equal(t, []string{"name", "country", "latitude", "unlocs"}, Diff(nil, port))
+	require.Equal(t, []string{"name", "country", "latitude", "unlocs"}, Diff(port, nil))
+	require.Empty(t, Diff(&Port{Unlocs: pq.StringArray{}}, &Port{}))
+}
+
+// migratedDatabase opens a migrated database.
+func migratedDatabase(t *testing.T, dsn string) *Database {
+	cfg := config.Default().Database
+	cfg.DSN = dsn
+
+	m, err := NewMigrator(cfg)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+
+	db, err := New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	return db
+}
+
+func TestDatabase_outbox(t *testing.T) {
+	ctx := context.Background()
+	db := migratedDatabase(t, "sqlite://"+filepath.Join(t.TempDir(), "ports.db"))
+
+	first, last, err := db.ChangeLogBounds(ctx)
+	require.NoError(t, err)
+	require.Zero(t, first)
+	require.Zero(t, last)
+
+	port := &Port{Key: "AEAJM", Name: "Ajman", Unlocs: pq.StringArray{"AEAJM"}, Alias: pq.StringArray{}}
+	require.NoError(t, db.Merge(ctx, port, MergeOptions{}))
+	require.NoError(t, db.Merge(ctx, port, MergeOptions{}))
+
+	changed := *port
+	changed.Name = "Ajman Port"
+	require.NoError(t, db.Merge(ctx, &changed, MergeOptions{}))
+	require.NoError(t, db.Delete(ctx, "AEAJM"))
+	require.ErrorIs(t, db.Delete(ctx, "AEAJM"), ErrNotFound)
+
+	var got []Change
+
+	n, err := db.PublishChanges(ctx, 10, func(changes []Change) error {
+		t.Fatal("changes relayed before being sequenced")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	n, err = db.SequenceChanges(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	n, err = db.SequenceChanges(ctx, 2)
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	n, err = db.PublishChanges(ctx, 10, func(changes []Change) error {
+		got = changes
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+
+	require.Equal(t, ChangeCreate, got[0].Type)
+	require.Equal(t, `["name","unlocs"]`, got[0].Fields)
+	require.Empty(t, got[0].Before)
+	require.JSONEq(t, `{"key":"AEAJM","code":"","name":"Ajman","city":"","province":"","country":"","timezone":"","latitude":0,"longitude":0,"unlocs":["AEAJM"],"alias":[]}`, got[0].After)
+
+	require.Equal(t, ChangeUpdate, got[1].Type)
+	require.Equal(t, `["name"]`, got[1].Fields)
+	require.Equal(t, got[0].After, got[1].Before)
+
+	require.Equal(t, ChangeDelete, got[2].Type)
+	require.Equal(t, got[1].After, got[2].Before)
+	require.Empty(t, got[2].After)
+
+	for i, c := range got {
+		require.Equal(t, int64(i+1), *c.Seq)
+	}
+
+	first, last, err = db.ChangeLogBounds(ctx)
+	require.NoError(t, err)
+	require.Equal(t, *got[0].Seq, first)
+	require.Equal(t, *got[2].Seq, last)
+
+	after, err := db.Changes(ctx, first, 10)
+	require.NoError(t, err)
+	require.Len(t, after, 2)
+	require.Equal(t, got[1].ID, after[0].ID)
+	require.Equal(t, got[2].ID, after[1].ID)
+	require.NotNil(t, after[0].PublishedAt)
+
+	n, err = db.PublishChanges(ctx, 10, func([]Change) error {
+		t.Fatal("published changes relayed again")
+		return nil
+	})
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestDatabase_SequenceChanges_commitOrder(t *testing.T) {
+	ctx := context.Background()
+	db := migratedDatabase(t, "sqlite://"+filepath.Join(t.TempDir(), "ports.db"))
+
+	// Transactions take their change IDs on insert, but may commit in any order:
+	// here the one taking ID 1 commits after the one taking ID 2, long after its change was created.
+	require.NoError(t, db.db.Create(&Change{ID: 2, Key: "B", Type: ChangeCreate, Fields: "[]", CreatedAt: time.Now().UTC()}).Error)
+
+	_, err := db.SequenceChanges(ctx, 10)
+	require.NoError(t, err)
+
+	changes, err := db.Changes(ctx, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	last := *changes[0].Seq
+
+	require.NoError(t, db.db.Create(&Change{ID: 1, Key: "A", Type: ChangeCreate, Fields: "[]", CreatedAt: time.Now().Add(-time.Minute).UTC()}).Error)
+
+	_, err = db.SequenceChanges(ctx, 10)
+	require.NoError(t, err)
+
+	changes, err = db.Changes(ctx, last, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1, "a change committed late follows the ones committed before")
+	require.Equal(t, int64(1), changes[0].ID)
+}
+
+func TestDatabase_SequenceChanges_interleaved(t *testing.T) {
+	dsn, ok := os.LookupEnv("PORTS_TEST_POSTGRES_DSN")
+	if !ok {
+		t.Skip("PORTS_TEST_POSTGRES_DSN not set")
+	}
+
+	ctx := context.Background()
+	db := migratedDatabase(t, dsn)
+
+	_, err := db.SequenceChanges(ctx, 1_000_000)
+	require.NoError(t, err)
+	_, last, err := db.ChangeLogBounds(ctx)
+	require.NoError(t, err)
+
+	// A slow transaction takes its change ID first, then the one of another transaction commits.
+	slow := db.db.Begin()
+	require.NoError(t, slow.Error)
+	require.NoError(t, slow.Create(&Change{Key: "SLOW", Type: ChangeCreate, Fields: "[]", CreatedAt: time.Now().Add(-time.Minute).UTC()}).Error)
+
+	require.NoError(t, db.db.Create(&Change{Key: "FAST", Type: ChangeCreate, Fields: "[]", CreatedAt: time.Now().UTC()}).Error)
+
+	_, err = db.SequenceChanges(ctx, 10)
+	require.NoError(t, err)
+
+	changes, err := db.Changes(ctx, last, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, "FAST", changes[0].Key)
+	last = *changes[0].Seq
+
+	require.NoError(t, slow.Commit().Error)
+
+	_, err = db.SequenceChanges(ctx, 10)
+	require.NoError(t, err)
+
+	changes, err = db.Changes(ctx, last, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1, "the slow transaction change follows the one committed before")
+	require.Equal(t, "SLOW", changes[0].Key)
+}
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/lib/pq"
+)
+
+// Conflict tells how a merged Port is written depending on its key being already stored.
+type Conflict string
+
+// Available Conflict policies.
+const (
+	// ConflictOverwrite stores every merged Port, inserting the new keys and updating the stored ones.
+	ConflictOverwrite Conflict = "overwrite"
+	// ConflictInsertOnly stores the new keys, skipping the stored ones.
+	ConflictInsertOnly Conflict = "insert-only"
+	// ConflictUpdateOnly updates the stored keys, skipping the new ones.
+	ConflictUpdateOnly Conflict = "update-only"
+	// ConflictFillEmpty inserts the new keys and only fills the empty fields of the stored ones.
+	ConflictFillEmpty Conflict = "fill-empty"
+	// ConflictReject inserts the new keys and rejects the Ports changing a stored one.
+	ConflictReject Conflict = "reject"
+)
+
+var (
+	// ErrSkipped is returned when the Conflict policy skips a Port, nothing being written.
+	ErrSkipped = errors.New("port skipped by the conflict policy")
+	// ErrConflict is returned when a Port changes a stored one under ConflictReject, nothing being written.
+	ErrConflict = errors.New("port conflicts with the stored one")
+)
+
+// ParseConflict validates a Conflict policy name, empty standing for ConflictOverwrite.
+func ParseConflict(s string) (Conflict, error) {
+	switch c := Conflict(s); c {
+	case "":
+		return ConflictOverwrite, nil
+	case ConflictOverwrite, ConflictInsertOnly, ConflictUpdateOnly, ConflictFillEmpty, ConflictReject:
+		return c, nil
+	default:
+		return "", fmt.Errorf("invalid conflict policy %q, must be %s, %s, %s, %s or %s",
+			s, ConflictOverwrite, ConflictInsertOnly, ConflictUpdateOnly, ConflictFillEmpty, ConflictReject)
+	}
+}
+
+// Apply enforces the policy on a Port merged from its sources over the stored one, nil for a new key,
+// returning ErrSkipped or ErrConflict when nothing must be written. With ConflictFillEmpty the non empty stored
+// fields are kept in merged, their sources keeping the Supplies they had as stored.
+func (c Conflict) Apply(before, merged *Port, sources []SourcePort, stored map[string][]string) error {
+	switch {
+	case before == nil && c == ConflictUpdateOnly:
+		return ErrSkipped
+	case before == nil:
+		return nil
+	case c == ConflictInsertOnly:
+		return ErrSkipped
+	case c == ConflictReject && len(Diff(before, merged)) > 0:
+		return ErrConflict
+	case c != ConflictFillEmpty:
+		return nil
+	}
+
+	kept := keptFields(before)
+	for _, field := range kept {
+		setField(field, merged, before)
+	}
+
+	for i := range sources {
+		s := &sources[i]
+
+		supplies := pq.StringArray{}
+		for _, field := range config.MergeFields {
+			if contains(kept, field) && contains(stored[s.Source], field) || !contains(kept, field) && contains(s.Supplies, field) {
+				supplies = append(supplies, field)
+			}
+		}
+		s.Supplies = supplies
+	}
+
+	return nil
+}
+
+// Keep reverts, with ConflictFillEmpty, the fields of the values imported from a source whose stored value is kept
+// to the previous values of the source, nil for a new one, so that the source only records the fields it filled.
+func (c Conflict) Keep(before, port, previous *Port) {
+	if c != ConflictFillEmpty || before == nil {
+		return
+	}
+
+	if previous == nil {
+		previous = &Port{Unlocs: pq.StringArray{}, Alias: pq.StringArray{}}
+	}
+
+	for _, field := range keptFields(before) {
+		setField(field, port, previous)
+	}
+}
+
+// keptFields returns the merge fields holding a value in a stored Port, kept by ConflictFillEmpty.
+func keptFields(before *Port) []string {
+	var out []string
+	for _, field := range config.MergeFields {
+		if list := listField(field); list != nil && len(*list(before)) > 0 || list == nil && supplied(field, before) {
+			out = append(out, field)
+		}
+	}
+	return out
+}
+
+func setField(field string, dst, src *Port) {
+	if list := listField(field); list != nil {
+		*list(dst) = *list(src)
+		return
+	}
+	copyField(field, dst, src)
+}
@@ -0,0 +1,32 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConflict(t *testing.T) {
+	c, err := ParseConflict("")
+	require.NoError(t, err)
+	require.Equal(t, ConflictOverwrite, c)
+
+	c, err = ParseConflict("fill-empty")
+	require.NoError(t, err)
+	require.Equal(t, ConflictFillEmpty, c)
+
+	_, err = ParseConflict("upsert")
+	require.EqualError(t, err, `invalid conflict policy "upsert", must be overwrite, insert-only, update-only, fill-empty or reject`)
+}
+
+func TestConflict_Apply_fillEmpty(t *testing.T) {
+	before := &Port{Key: "AEAJM", Name: "Ajman", Unlocs: pq.StringArray{"AEAJM"}}
+	merged := &Port{Key: "AEAJM", Name: "Ajman Port", City: "Ajman", Unlocs: pq.StringArray{"AEAJX"}}
+	sources := []SourcePort{{Source: "supplier", Supplies: pq.StringArray{"name", "city", "unlocs"}}}
+	stored := map[string][]string{"default": {"name", "unlocs"}}
+
+	require.NoError(t, ConflictFillEmpty.Apply(before, merged, sources, stored))
+	require.Equal(t, &Port{Key: "AEAJM", Name: "Ajman", City: "Ajman", Unlocs: pq.StringArray{"AEAJM"}}, merged)
+	require.Equal(t, pq.StringArray{"city"}, sources[0].Supplies)
+}
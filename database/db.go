@@ -2,67 +2,225 @@
 package database
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/glebarez/sqlite"
 	"github.com/lib/pq"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
-// Database represents a Postgres storage.
+// Supported SQL dialects.
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite"
+)
+
+const sqliteMemory = ":memory:"
+
+// ErrNotFound is returned when a Port does not exist.
+var ErrNotFound = errors.New("port not found")
+
+// Database represents a SQL storage, either Postgres or SQLite.
 type Database struct {
-	db *gorm.DB
+	db     *gorm.DB
+	schema *Migrator
 }
 
-// New instantiates a new Database.
-func New(dsn string) (*Database, error) {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
+// New instantiates a new Database, failing if its schema is not at the latest migration version.
+// The DSN scheme selects the dialect: postgres:// (or a key=value connection string) or sqlite://.
+func New(cfg config.Database) (*Database, error) {
+	db, dialect, err := open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := newMigrator(db, dialect)
 	if err != nil {
+		_ = closeDB(db)
 		return nil, err
 	}
 
-	if err := db.AutoMigrate(&Port{}); err != nil {
+	out := &Database{
+		db:     db,
+		schema: schema,
+	}
+
+	if err := out.checkSchema(context.Background()); err != nil {
+		_ = out.Close()
 		return nil, err
 	}
 
-	return &Database{
-		db: db,
-	}, nil
+	return out, nil
+}
+
+// Dialect returns the SQL dialect of a DSN, failing if it is not supported.
+func Dialect(dsn string) (string, error) {
+	scheme, _, found := strings.Cut(dsn, "://")
+	if !found {
+		// Postgres key=value connection string.
+		return Postgres, nil
+	}
+
+	switch scheme {
+	case "postgres", "postgresql":
+		return Postgres, nil
+	case SQLite:
+		return SQLite, nil
+	default:
+		return "", fmt.Errorf("unsupported database scheme %q", scheme)
+	}
+}
+
+func open(cfg config.Database) (*gorm.DB, string, error) {
+	dialect, err := Dialect(cfg.DSN)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var dialector gorm.Dialector
+	switch dialect {
+	case SQLite:
+		dialector = sqlite.Open(sqliteDSN(cfg.DSN))
+	default:
+		dialector = postgres.Open(cfg.DSN)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, "", err
+	}
+
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if dialect == SQLite && strings.HasPrefix(sqliteDSN(cfg.DSN), sqliteMemory) {
+		// Every connection to an in-memory SQLite database opens a different database.
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+	}
+
+	return db, dialect, nil
+}
+
+// sqliteDSN turns sqlite://PATH into a driver DSN, waiting for locks instead of failing right away.
+func sqliteDSN(dsn string) string {
+	path := strings.TrimPrefix(dsn, SQLite+"://")
+
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+
+	return path + sep + "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+}
+
+func closeDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	return sqlDB.Close()
 }
 
 // Close releases Database resources.
 func (db *Database) Close() error {
+	return closeDB(db.db)
+}
+
+func (db *Database) checkSchema(ctx context.Context) error {
+	_, err := db.schema.check(db.db.WithContext(ctx), true)
+	return err
+}
+
+// Check tells if the Database is reachable and its schema is at the latest migration version.
+func (db *Database) Check(ctx context.Context) error {
 	sqlDB, err := db.db.DB()
 	if err != nil {
 		return err
 	}
 
-	return sqlDB.Close()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		return err
+	}
+
+	return db.checkSchema(ctx)
 }
 
 // Port represents a ports database table.
 type Port struct {
-	Key       string `gorm:"primarykey"`
-	Code      string
-	Name      string
-	City      string
-	Province  string
-	Country   string
-	Timezone  string
-	Latitude  float64
-	Longitude float64
-	Unlocs    pq.StringArray `gorm:"type:text[]"`
-	Alias     pq.StringArray `gorm:"type:text[]"`
+	Key       string         `gorm:"primarykey" json:"key"`
+	Code      string         `json:"code"`
+	Name      string         `json:"name"`
+	City      string         `json:"city"`
+	Province  string         `json:"province"`
+	Country   string         `json:"country"`
+	Timezone  string         `json:"timezone"`
+	Latitude  float64        `json:"latitude"`
+	Longitude float64        `json:"longitude"`
+	Unlocs    pq.StringArray `gorm:"type:text[]" json:"unlocs"`
+	Alias     pq.StringArray `gorm:"type:text[]" json:"alias"`
 }
 
-// Upsert inserts a new Port, or updates it if already present.
-func (db *Database) Upsert(port *Port) error {
-	tx := db.db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "key"}},
-		UpdateAll: true,
-	}).Create(&port)
+// maxMergeAttempts bounds the attempts of a Merge whose Port is inserted concurrently by another one.
+const maxMergeAttempts = 3
+
+// errConcurrentInsert is returned when a Port keeps being inserted concurrently, it is transient.
+var errConcurrentInsert = transientError{errors.New("port inserted concurrently")}
 
-	return tx.Error
+// Get returns the Port with the given key, or ErrNotFound.
+func (db *Database) Get(ctx context.Context, key string) (*Port, error) {
+	var out Port
+
+	err := db.db.WithContext(ctx).Where("key = ?", key).Take(&out).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// Delete removes the Port with the given key and its source values, writing its Change to the outbox and closing its version in the history
+// in the same transaction, or returns ErrNotFound.
+func (db *Database) Delete(ctx context.Context, key string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		before, err := db.current(tx, key)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return ErrNotFound
+		}
+
+		if err := tx.Where("key = ?", key).Delete(&Port{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("key = ?", key).Delete(&SourcePort{}).Error; err != nil {
+			return err
+		}
+
+		change, err := newChange(key, before, nil)
+		if err != nil {
+			return err
+		}
+
+		return save(tx, change, nil)
+	})
 }
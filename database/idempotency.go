@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyKey statuses.
+const (
+	IdempotencyPending   = "pending"
+	IdempotencyCompleted = "completed"
+)
+
+// IdempotencyKey represents an idempotency_keys database table, remembering the upload submissions.
+type IdempotencyKey struct {
+	Key      string `gorm:"primarykey"`
+	Checksum string
+	ImportID string
+	Status   string
+	// Report holds the JSON encoded import report once completed.
+	Report    string
+	CreatedAt time.Time
+	// PendingUntil is the end of the lease of a pending row, which can be claimed again once it ends.
+	PendingUntil time.Time
+}
+
+// ClaimIdempotencyKey inserts a row, unless another one created after since holds its key, which is returned instead.
+// Rows created before since are deleted, along with the pending ones whose lease ended before row was created.
+func (db *Database) ClaimIdempotencyKey(ctx context.Context, row *IdempotencyKey, since time.Time) (*IdempotencyKey, error) {
+	tx := db.db.WithContext(ctx)
+
+	err := tx.Where("created_at < ? OR (status = ? AND pending_until < ?)", since.UTC(), IdempotencyPending, row.CreatedAt.UTC()).
+		Delete(&IdempotencyKey{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(row)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var out IdempotencyKey
+	err = tx.Where("key = ?", row.Key).Take(&out).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Released meanwhile, so claim it again.
+		return db.ClaimIdempotencyKey(ctx, row, since)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// SaveIdempotencyKey updates a claimed row.
+func (db *Database) SaveIdempotencyKey(ctx context.Context, row *IdempotencyKey) error {
+	return db.db.WithContext(ctx).Save(row).Error
+}
+
+// RenewIdempotencyKey extends the lease of a pending row.
+func (db *Database) RenewIdempotencyKey(ctx context.Context, key string, until time.Time) error {
+	return db.db.WithContext(ctx).Model(&IdempotencyKey{}).
+		Where("key = ? AND status = ?", key, IdempotencyPending).
+		Update("pending_until", until.UTC()).Error
+}
+
+// DeleteIdempotencyKey releases a claimed row, so its key can be claimed again.
+func (db *Database) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	return db.db.WithContext(ctx).Where("key = ?", key).Delete(&IdempotencyKey{}).Error
+}
@@ -0,0 +1,342 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationLock is the Postgres advisory lock key serializing concurrent migrations.
+const migrationLock = 7_470_717_274
+
+// Migration represents a versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus represents a Migration and when it was applied, if it was.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type schemaMigration struct {
+	Version   int `gorm:"primarykey"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+var createSchemaMigrations = map[string]string{
+	Postgres: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    bigint PRIMARY KEY,
+    name       text NOT NULL,
+    applied_at timestamptz NOT NULL
+)`,
+	SQLite: `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    integer PRIMARY KEY,
+    name       text NOT NULL,
+    applied_at datetime NOT NULL
+)`,
+}
+
+// ErrSchemaVersion is returned when the database schema version is not the one the application expects.
+var ErrSchemaVersion = errors.New("unexpected schema version")
+
+// Migrator applies and reverts the embedded migrations.
+type Migrator struct {
+	db         *gorm.DB
+	dialect    string
+	migrations []Migration
+}
+
+// NewMigrator instantiates a new Migrator, it does not check the schema version.
+func NewMigrator(cfg config.Database) (*Migrator, error) {
+	db, dialect, err := open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMigrator(db, dialect)
+}
+
+func newMigrator(db *gorm.DB, dialect string) (*Migrator, error) {
+	migrations, err := loadMigrations(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+// Close releases Migrator resources.
+func (m *Migrator) Close() error {
+	return closeDB(m.db)
+}
+
+func loadMigrations(dialect string) ([]Migration, error) {
+	dir := path.Join("migrations", dialect)
+
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		name := e.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migration %s: invalid file name", name)
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		i := strings.Index(base, "_")
+		if i < 0 {
+			return nil, fmt.Errorf("migration %s: invalid file name", name)
+		}
+
+		version, err := strconv.Atoi(base[:i])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", name, err)
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: base[i+1:]}
+			byVersion[version] = m
+		}
+
+		if direction == "up" {
+			m.up = string(content)
+		} else {
+			m.down = string(content)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, fmt.Errorf("migration %d: missing up or down file", m.Version)
+		}
+		out = append(out, *m)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+
+	return out, nil
+}
+
+// Latest returns the version of the newest embedded Migration.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status lists every embedded Migration along with when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(m.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := MigrationStatus{Migration: mig}
+		if a, ok := applied[mig.Version]; ok {
+			t := a.AppliedAt
+			s.AppliedAt = &t
+		}
+		out = append(out, s)
+	}
+
+	return out, nil
+}
+
+// Version returns the current schema version, 0 meaning no migration was applied.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	return version(m.db.WithContext(ctx))
+}
+
+// Up applies every pending Migration, returning the applied ones.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var out []Migration
+
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		if err := db.Exec(createSchemaMigrations[m.dialect]).Error; err != nil {
+			return err
+		}
+
+		current, err := m.check(db, false)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if mig.Version <= current {
+				continue
+			}
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(mig.up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&schemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d %s: %w", mig.Version, mig.Name, err)
+			}
+
+			out = append(out, mig)
+		}
+
+		return nil
+	})
+
+	return out, err
+}
+
+// Down reverts the given number of applied migrations, newest first, returning the reverted ones.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var out []Migration
+
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		current, err := m.check(db, false)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(out) < steps; i-- {
+			mig := m.migrations[i]
+			if mig.Version > current {
+				continue
+			}
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(mig.down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&schemaMigration{Version: mig.Version}).Error
+			}); err != nil {
+				return fmt.Errorf("migration %d %s: %w", mig.Version, mig.Name, err)
+			}
+
+			out = append(out, mig)
+		}
+
+		return nil
+	})
+
+	return out, err
+}
+
+// check verifies the schema version is known, and the latest one if strict, returning it.
+func (m *Migrator) check(db *gorm.DB, strict bool) (int, error) {
+	current, err := version(db)
+	if err != nil {
+		return 0, err
+	}
+
+	latest := m.Latest()
+
+	switch {
+	case current > latest:
+		return 0, fmt.Errorf("%w: schema version %d is newer than the latest known %d, upgrade the application", ErrSchemaVersion, current, latest)
+	case current != 0 && !m.known(current):
+		return 0, fmt.Errorf("%w: schema version %d is unknown", ErrSchemaVersion, current)
+	case strict && current < latest:
+		return 0, fmt.Errorf("%w: schema version %d is older than %d, run ports migrate up", ErrSchemaVersion, current, latest)
+	}
+
+	return current, nil
+}
+
+func (m *Migrator) known(v int) bool {
+	for _, mig := range m.migrations {
+		if mig.Version == v {
+			return true
+		}
+	}
+	return false
+}
+
+// locked runs fn on a single connection holding the migration lock, SQLite serializes writers by itself.
+func (m *Migrator) locked(ctx context.Context, fn func(*gorm.DB) error) error {
+	if m.dialect != Postgres {
+		return fn(m.db.WithContext(ctx))
+	}
+
+	return m.db.WithContext(ctx).Connection(func(db *gorm.DB) error {
+		if err := db.Exec("SELECT pg_advisory_lock(?)", migrationLock).Error; err != nil {
+			return err
+		}
+		defer db.Exec("SELECT pg_advisory_unlock(?)", migrationLock)
+
+		return fn(db)
+	})
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[int]schemaMigration, error) {
+	out := make(map[int]schemaMigration)
+
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return out, nil
+	}
+
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, r := range rows {
+		out[r.Version] = r
+	}
+
+	return out, nil
+}
+
+func version(db *gorm.DB) (int, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return 0, nil
+	}
+
+	var out int
+	err := db.Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&out).Error
+
+	return out, err
+}
@@ -0,0 +1,29 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	postgres, err := loadMigrations(Postgres)
+	require.NoError(t, err)
+	require.NotEmpty(t, postgres)
+
+	for i, m := range postgres {
+		require.Equal(t, i+1, m.Version, "versions must be consecutive")
+		require.NotEmpty(t, m.Name)
+		require.NotEmpty(t, m.up)
+		require.NotEmpty(t, m.down)
+	}
+
+	sqlite, err := loadMigrations(SQLite)
+	require.NoError(t, err)
+	require.Len(t, sqlite, len(postgres), "every dialect must have the same migrations")
+
+	for i, m := range sqlite {
+		require.Equal(t, postgres[i].Version, m.Version)
+		require.Equal(t, postgres[i].Name, m.Name)
+	}
+}
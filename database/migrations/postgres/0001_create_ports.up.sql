@@ -0,0 +1,14 @@
+-- Matches the table previously created by gorm AutoMigrate, so existing databases adopt this version as is.
+CREATE TABLE IF NOT EXISTS ports (
+    key       text PRIMARY KEY,
+    code      text,
+    name      text,
+    city      text,
+    province  text,
+    country   text,
+    timezone  text,
+    latitude  decimal,
+    longitude decimal,
+    unlocs    text[],
+    alias     text[]
+);
@@ -0,0 +1 @@
+DROP INDEX ports_country_idx;
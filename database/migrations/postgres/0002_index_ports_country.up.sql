@@ -0,0 +1 @@
+CREATE INDEX ports_country_idx ON ports (country);
@@ -0,0 +1,10 @@
+-- Upload submissions remembered to return the original import result to duplicates.
+CREATE TABLE idempotency_keys (
+    key        text PRIMARY KEY,
+    checksum   text NOT NULL DEFAULT '',
+    import_id  text NOT NULL DEFAULT '',
+    status     text NOT NULL,
+    report     text NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL
+);
+CREATE INDEX idempotency_keys_created_at_idx ON idempotency_keys (created_at);
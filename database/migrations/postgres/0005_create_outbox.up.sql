@@ -0,0 +1,13 @@
+-- Transactional outbox: a change event per port insert, update or delete, written along with it.
+-- Events are kept once published, as a change log.
+CREATE TABLE outbox (
+    id           bigserial PRIMARY KEY,
+    key          text NOT NULL,
+    type         text NOT NULL,
+    fields       text NOT NULL,
+    before       text NOT NULL DEFAULT '',
+    after        text NOT NULL DEFAULT '',
+    created_at   timestamptz NOT NULL,
+    published_at timestamptz
+);
+CREATE INDEX outbox_pending_idx ON outbox (id) WHERE published_at IS NULL;
@@ -0,0 +1,25 @@
+-- Webhook subscriptions, and their delivery log doubling as the retry queue.
+CREATE TABLE webhooks (
+    id         text PRIMARY KEY,
+    url        text NOT NULL,
+    events     text[] NOT NULL,
+    secret     text NOT NULL,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+    id              bigserial PRIMARY KEY,
+    webhook_id      text NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event           text NOT NULL,
+    payload         text NOT NULL,
+    status          text NOT NULL,
+    attempts        integer NOT NULL DEFAULT 0,
+    response_code   integer NOT NULL DEFAULT 0,
+    error           text NOT NULL DEFAULT '',
+    next_attempt_at timestamptz NOT NULL,
+    created_at      timestamptz NOT NULL,
+    delivered_at    timestamptz
+);
+CREATE INDEX webhook_deliveries_webhook_id_idx ON webhook_deliveries (webhook_id, id);
+CREATE INDEX webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,23 @@
+-- Port history: a row per stored version of a port, valid from valid_from until valid_to, null while current.
+-- Existing ports are known since this migration.
+CREATE TABLE port_versions (
+    id         bigserial PRIMARY KEY,
+    key        text NOT NULL,
+    code       text,
+    name       text,
+    city       text,
+    province   text,
+    country    text,
+    timezone   text,
+    latitude   decimal,
+    longitude  decimal,
+    unlocs     text[],
+    alias      text[],
+    valid_from timestamptz NOT NULL,
+    valid_to   timestamptz
+);
+CREATE INDEX port_versions_key_idx ON port_versions (key, valid_from);
+CREATE UNIQUE INDEX port_versions_current_idx ON port_versions (key) WHERE valid_to IS NULL;
+
+INSERT INTO port_versions (key, code, name, city, province, country, timezone, latitude, longitude, unlocs, alias, valid_from)
+SELECT key, code, name, city, province, country, timezone, latitude, longitude, unlocs, alias, now() FROM ports;
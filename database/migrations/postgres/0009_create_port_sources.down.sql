@@ -0,0 +1,2 @@
+DROP TABLE snapshot_port_sources;
+DROP TABLE port_sources;
@@ -0,0 +1,5 @@
+DROP INDEX outbox_pending_idx;
+CREATE INDEX outbox_pending_idx ON outbox (id) WHERE published_at IS NULL;
+DROP INDEX outbox_unsequenced_idx;
+DROP INDEX outbox_seq_idx;
+ALTER TABLE outbox DROP COLUMN seq;
@@ -0,0 +1,9 @@
+-- seq orders the change log by commit, stamped by the relay once the changes are committed,
+-- whereas their IDs are taken on insert by transactions committing in any order.
+ALTER TABLE outbox ADD COLUMN seq bigint;
+-- The existing changes are all committed.
+UPDATE outbox SET seq = id;
+CREATE UNIQUE INDEX outbox_seq_idx ON outbox (seq);
+CREATE INDEX outbox_unsequenced_idx ON outbox (id) WHERE seq IS NULL;
+DROP INDEX outbox_pending_idx;
+CREATE INDEX outbox_pending_idx ON outbox (seq) WHERE published_at IS NULL;
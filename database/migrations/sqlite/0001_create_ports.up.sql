@@ -0,0 +1,14 @@
+-- List columns hold Postgres array literals, e.g. {a,b}, as read and written by pq.StringArray.
+CREATE TABLE ports (
+    key       text PRIMARY KEY,
+    code      text,
+    name      text,
+    city      text,
+    province  text,
+    country   text,
+    timezone  text,
+    latitude  real,
+    longitude real,
+    unlocs    text,
+    alias     text
+);
@@ -0,0 +1,10 @@
+-- Dead letters: the records rejected by the imports, with their original JSON fragment.
+CREATE TABLE rejects (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    import_id   text NOT NULL,
+    key         text NOT NULL DEFAULT '',
+    error       text NOT NULL,
+    record      text NOT NULL DEFAULT '',
+    rejected_at datetime NOT NULL
+);
+CREATE INDEX rejects_import_id_idx ON rejects (import_id);
@@ -0,0 +1,24 @@
+-- Port history: a row per stored version of a port, valid from valid_from until valid_to, null while current.
+-- Existing ports are known since this migration.
+CREATE TABLE port_versions (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    key        text NOT NULL,
+    code       text,
+    name       text,
+    city       text,
+    province   text,
+    country    text,
+    timezone   text,
+    latitude   real,
+    longitude  real,
+    unlocs     text,
+    alias      text,
+    valid_from datetime NOT NULL,
+    valid_to   datetime
+);
+CREATE INDEX port_versions_key_idx ON port_versions (key, valid_from);
+CREATE UNIQUE INDEX port_versions_current_idx ON port_versions (key) WHERE valid_to IS NULL;
+
+-- Times are compared as text, formatted like the driver writes them in UTC.
+INSERT INTO port_versions (key, code, name, city, province, country, timezone, latitude, longitude, unlocs, alias, valid_from)
+SELECT key, code, name, city, province, country, timezone, latitude, longitude, unlocs, alias, strftime('%Y-%m-%d %H:%M:%f+00:00', 'now') FROM ports;
@@ -0,0 +1,2 @@
+DROP TABLE snapshot_ports;
+DROP TABLE snapshots;
@@ -0,0 +1,24 @@
+-- Named copies of the whole ports table, restored on demand.
+CREATE TABLE snapshots (
+    name       text PRIMARY KEY,
+    created_at datetime NOT NULL,
+    created_by text NOT NULL DEFAULT '',
+    row_count  bigint NOT NULL,
+    checksum   text NOT NULL
+);
+
+CREATE TABLE snapshot_ports (
+    snapshot  text NOT NULL REFERENCES snapshots (name) ON DELETE CASCADE,
+    key       text NOT NULL,
+    code      text,
+    name      text,
+    city      text,
+    province  text,
+    country   text,
+    timezone  text,
+    latitude  real,
+    longitude real,
+    unlocs    text,
+    alias     text,
+    PRIMARY KEY (snapshot, key)
+);
@@ -0,0 +1,46 @@
+-- The port values as last imported from each source, merged by precedence into the ports table.
+-- supplies lists the fields whose stored value comes from the source.
+CREATE TABLE port_sources (
+    key        text NOT NULL,
+    source     text NOT NULL,
+    code       text,
+    name       text,
+    city       text,
+    province   text,
+    country    text,
+    timezone   text,
+    latitude   real,
+    longitude  real,
+    unlocs     text,
+    alias      text,
+    supplies   text,
+    updated_at datetime NOT NULL,
+    PRIMARY KEY (key, source)
+);
+
+-- Existing ports are known from the default source, the one of the untagged imports.
+INSERT INTO port_sources (key, source, code, name, city, province, country, timezone, latitude, longitude, unlocs, alias, supplies, updated_at)
+SELECT key, 'default', code, name, city, province, country, timezone, latitude, longitude, unlocs, alias, '{code,name,city,province,country,timezone,coordinates,alias,unlocs}', strftime('%Y-%m-%d %H:%M:%f+00:00', 'now') FROM ports;
+
+-- Snapshots keep the per-source values along with the ports, so restores roll them back too.
+CREATE TABLE snapshot_port_sources (
+    snapshot   text NOT NULL REFERENCES snapshots (name) ON DELETE CASCADE,
+    key        text NOT NULL,
+    source     text NOT NULL,
+    code       text,
+    name       text,
+    city       text,
+    province   text,
+    country    text,
+    timezone   text,
+    latitude   real,
+    longitude  real,
+    unlocs     text,
+    alias      text,
+    supplies   text,
+    updated_at datetime NOT NULL,
+    PRIMARY KEY (snapshot, key, source)
+);
+
+INSERT INTO snapshot_port_sources (snapshot, key, source, code, name, city, province, country, timezone, latitude, longitude, unlocs, alias, supplies, updated_at)
+SELECT snapshot, key, 'default', code, name, city, province, country, timezone, latitude, longitude, unlocs, alias, '{code,name,city,province,country,timezone,coordinates,alias,unlocs}', strftime('%Y-%m-%d %H:%M:%f+00:00', 'now') FROM snapshot_ports;
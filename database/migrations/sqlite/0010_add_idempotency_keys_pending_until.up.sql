@@ -0,0 +1,3 @@
+-- Pending submissions hold their key until pending_until, renewed while imported, so those of a crashed server expire.
+-- The rows pending before are taken as expired.
+ALTER TABLE idempotency_keys ADD COLUMN pending_until datetime NOT NULL DEFAULT '1970-01-01 00:00:00+00:00';
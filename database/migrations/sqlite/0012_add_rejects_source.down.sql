@@ -0,0 +1,2 @@
+ALTER TABLE rejects DROP COLUMN conflict;
+ALTER TABLE rejects DROP COLUMN source;
@@ -0,0 +1,4 @@
+-- The source and conflict policy of the import rejecting a record, for a replay to default to them.
+-- They are unknown for the existing records, which are replayed with the defaults.
+ALTER TABLE rejects ADD COLUMN source text NOT NULL DEFAULT '';
+ALTER TABLE rejects ADD COLUMN conflict text NOT NULL DEFAULT '';
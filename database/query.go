@@ -0,0 +1,148 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Filter represents the criteria a Port must match to be read, zero values match every Port.
+type Filter struct {
+	Keys    []string
+	Country string
+	City    string
+	BBox    *BBox
+	// AsOf reads the Ports as they were stored at that time instead of the current ones, when not zero.
+	AsOf time.Time
+}
+
+// BBox represents a bounding box over the port coordinates, in the order they are given in the source files.
+type BBox struct {
+	MinX, MinY, MaxX, MaxY float64
+}
+
+// Validate checks the BBox minimums do not exceed its maximums.
+func (b *BBox) Validate() error {
+	if b.MinX > b.MaxX || b.MinY > b.MaxY {
+		return errors.New("minimums exceed maximums")
+	}
+	return nil
+}
+
+// Match tells if a Port meets the Filter criteria, AsOf aside.
+func (f Filter) Match(p *Port) bool {
+	if len(f.Keys) > 0 && !contains(f.Keys, p.Key) {
+		return false
+	}
+	if f.Country != "" && p.Country != f.Country {
+		return false
+	}
+	if f.City != "" && p.City != f.City {
+		return false
+	}
+	if b := f.BBox; b != nil {
+		if p.Latitude < b.MinX || p.Latitude > b.MaxX || p.Longitude < b.MinY || p.Longitude > b.MaxY {
+			return false
+		}
+	}
+
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Filter) apply(tx *gorm.DB) *gorm.DB {
+	if len(f.Keys) > 0 {
+		tx = tx.Where("key IN ?", f.Keys)
+	}
+	if f.Country != "" {
+		tx = tx.Where("country = ?", f.Country)
+	}
+	if f.City != "" {
+		tx = tx.Where("city = ?", f.City)
+	}
+	if f.BBox != nil {
+		tx = tx.Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?", f.BBox.MinX, f.BBox.MaxX, f.BBox.MinY, f.BBox.MaxY)
+	}
+
+	return tx
+}
+
+// query selects the Ports matching the Filter, from the history when reading as of a past time.
+func (db *Database) query(ctx context.Context, filter Filter) *gorm.DB {
+	tx := db.db.WithContext(ctx).Model(&Port{})
+	if !filter.AsOf.IsZero() {
+		// Times are stored in UTC, which SQLite compares as text.
+		t := filter.AsOf.UTC()
+		tx = tx.Table(portVersion{}.TableName()).Where("valid_from <= ? AND (valid_to IS NULL OR valid_to > ?)", t, t)
+	}
+
+	return filter.apply(tx)
+}
+
+// List returns a page of the Ports matching the Filter, ordered by key.
+func (db *Database) List(ctx context.Context, filter Filter, limit, offset int) ([]Port, error) {
+	var out []Port
+
+	tx := db.query(ctx, filter).Order("key").Limit(limit).Offset(offset).Find(&out)
+
+	return out, tx.Error
+}
+
+// Each calls fn for every Port matching the Filter, ordered by key, streaming them from the Database.
+func (db *Database) Each(ctx context.Context, filter Filter, fn func(*Port) error) error {
+	rows, err := db.query(ctx, filter).Order("key").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var port Port
+		if err := db.db.ScanRows(rows, &port); err != nil {
+			return err
+		}
+
+		if err := fn(&port); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ParseBBox parses a "minX,minY,maxX,maxY" bounding box.
+func ParseBBox(s string) (*BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid bounding box %q, must be minX,minY,maxX,maxY", s)
+	}
+
+	var values [4]float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bounding box %q: %w", s, err)
+		}
+		values[i] = v
+	}
+
+	b := &BBox{MinX: values[0], MinY: values[1], MaxX: values[2], MaxY: values[3]}
+	if err := b.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid bounding box %q: %w", s, err)
+	}
+
+	return b, nil
+}
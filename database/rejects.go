@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// Reject represents a rejects database table, holding the dead letters of the imports.
+type Reject struct {
+	ID         int64 `gorm:"primarykey"`
+	ImportID   string
+	Source     string
+	Conflict   string
+	Key        string
+	Error      string
+	Record     string
+	RejectedAt time.Time
+}
+
+// InsertReject stores a new Reject.
+func (db *Database) InsertReject(ctx context.Context, reject *Reject) error {
+	return db.db.WithContext(ctx).Create(reject).Error
+}
+
+// EachReject calls fn for every Reject of an import, in insertion order.
+func (db *Database) EachReject(ctx context.Context, importID string, fn func(*Reject) error) error {
+	rows, err := db.db.WithContext(ctx).Model(&Reject{}).Where("import_id = ?", importID).Order("id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reject Reject
+		if err := db.db.ScanRows(rows, &reject); err != nil {
+			return err
+		}
+
+		if err := fn(&reject); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// maxBackoff caps the delay between two retries.
+const maxBackoff = 5 * time.Second
+
+// SQLite primary result codes of a database locked by another connection.
+const (
+	sqliteBusy   = 5
+	sqliteLocked = 6
+)
+
+// Transient tells whether an error may not happen again when retried: a lost connection, a deadlock,
+// a serialization failure, a server shutting down or a locked SQLite database.
+// Any other error, like a constraint violation or invalid data, is permanent.
+func Transient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pg interface{ SQLState() string }
+	if errors.As(err, &pg) {
+		return transientState(pg.SQLState())
+	}
+
+	var sqlite interface{ Code() int }
+	if errors.As(err, &sqlite) {
+		code := sqlite.Code() & 0xff
+		return code == sqliteBusy || code == sqliteLocked
+	}
+
+	var retry interface{ SafeToRetry() bool }
+	if errors.As(err, &retry) && retry.SafeToRetry() {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.As(err, &netErr)
+}
+
+// transientState tells whether a Postgres SQLSTATE code is transient.
+func transientState(code string) bool {
+	switch code {
+	case "40001", // serialization_failure
+		"40P01", // deadlock_detected
+		"55P03", // lock_not_available
+		"57P01", // admin_shutdown
+		"57P02", // crash_shutdown
+		"57P03": // cannot_connect_now
+		return true
+	}
+
+	// Connection exceptions and insufficient resources, e.g. too many connections.
+	return strings.HasPrefix(code, "08") || strings.HasPrefix(code, "53")
+}
+
+// transientError marks an error as transient.
+type transientError struct {
+	error
+}
+
+func (transientError) SafeToRetry() bool {
+	return true
+}
+
+func (e transientError) Unwrap() error {
+	return e.error
+}
+
+// Backoff represents a retry policy with jittered exponential backoff, the zero value does not retry.
+type Backoff struct {
+	// Retries is the maximum number of retries after the first attempt.
+	Retries int
+	// Base is the maximum delay before the first retry, doubled for each following one.
+	Base time.Duration
+}
+
+// Retry calls fn until it succeeds, fails with a permanent error or runs out of retries,
+// returning the number of retries made along with the last error.
+func (b Backoff) Retry(ctx context.Context, fn func() error) (int, error) {
+	for retries := 0; ; retries++ {
+		err := fn()
+		if err == nil || retries >= b.Retries || !Transient(err) {
+			return retries, err
+		}
+
+		timer := time.NewTimer(b.delay(retries))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return retries, err
+		}
+	}
+}
+
+// delay returns a random delay up to the exponential backoff of a retry, known as full jitter.
+func (b Backoff) delay(retry int) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+
+	ceiling := maxBackoff
+	if retry < 32 {
+		if d := b.Base << retry; d > 0 && d < maxBackoff {
+			ceiling = d
+		}
+	}
+
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+type sqliteError int
+
+func (e sqliteError) Error() string { return fmt.Sprintf("sqlite error %d", int(e)) }
+func (e sqliteError) Code() int     { return int(e) }
+
+func TestTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: "40001"}, true},
+		{&pgconn.PgError{Code: "40P01"}, true},
+		{&pgconn.PgError{Code: "57P01"}, true},
+		{&pgconn.PgError{Code: "08006"}, true},
+		{fmt.Errorf("upsert: %w", &pgconn.PgError{Code: "53300"}), true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{&pgconn.PgError{Code: "22P02"}, false},
+		{sqliteError(5), true},
+		{sqliteError(5 | 2<<8), true},
+		{sqliteError(19), false},
+		{driver.ErrBadConn, true},
+		{fmt.Errorf("read: %w", errors.New("boom")), false},
+		{context.Canceled, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, Transient(tt.err), "%v", tt.err)
+	}
+}
+
+func TestBackoff_Retry(t *testing.T) {
+	transient := &pgconn.PgError{Code: "40001"}
+	b := Backoff{Retries: 3, Base: time.Millisecond}
+
+	calls := 0
+	retries, err := b.Retry(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return transient
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, retries)
+
+	calls = 0
+	retries, err = b.Retry(context.Background(), func() error {
+		calls++
+		return transient
+	})
+	require.ErrorIs(t, err, transient)
+	require.Equal(t, 3, retries)
+	require.Equal(t, 4, calls)
+
+	permanent := &pgconn.PgError{Code: "23505"}
+	retries, err = b.Retry(context.Background(), func() error { return permanent })
+	require.ErrorIs(t, err, permanent)
+	require.Zero(t, retries)
+
+	retries, err = Backoff{}.Retry(context.Background(), func() error { return transient })
+	require.ErrorIs(t, err, transient)
+	require.Zero(t, retries, "the zero value does not retry")
+}
+
+func TestBackoff_delay(t *testing.T) {
+	b := Backoff{Base: 100 * time.Millisecond}
+
+	for i := 0; i < 100; i++ {
+		require.LessOrEqual(t, b.delay(0), 100*time.Millisecond)
+		require.LessOrEqual(t, b.delay(2), 400*time.Millisecond)
+		require.LessOrEqual(t, b.delay(40), maxBackoff)
+	}
+	require.Zero(t, Backoff{}.delay(3))
+}
@@ -0,0 +1,294 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Columns shared by the ports and snapshot_ports tables, and by the port_sources and snapshot_port_sources ones.
+const (
+	portColumns   = "key, code, name, city, province, country, timezone, latitude, longitude, unlocs, alias"
+	sourceColumns = portColumns + ", source, supplies, updated_at"
+)
+
+var (
+	// ErrSnapshotNotFound is returned when a Snapshot does not exist.
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+	// ErrSnapshotExists is returned when creating a Snapshot whose name is taken.
+	ErrSnapshotExists = errors.New("snapshot already exists")
+	// ErrSnapshotCorrupted is returned when restoring a Snapshot whose ports do not match its row count and checksum.
+	ErrSnapshotCorrupted = errors.New("snapshot corrupted, its ports do not match its checksum")
+)
+
+// Snapshot represents a snapshots database table, holding the metadata of a named copy of every Port,
+// kept in the snapshot_ports table, and of their source values, kept in the snapshot_port_sources table.
+type Snapshot struct {
+	Name      string    `gorm:"primarykey" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	Rows      int64     `gorm:"column:row_count" json:"rows"`
+	// Checksum is the hex encoded SHA-256 of the Ports, see Checksum.
+	Checksum string `json:"checksum"`
+}
+
+// Restore represents the outcome of a Snapshot restore.
+type Restore struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Deleted   int `json:"deleted"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Checksum accumulates the SHA-256 of Ports given in key order, as JSON lines, nil and empty lists hashing the same.
+type Checksum struct {
+	h hash.Hash
+}
+
+// NewChecksum instantiates an empty Checksum.
+func NewChecksum() *Checksum {
+	return &Checksum{h: sha256.New()}
+}
+
+// Add hashes the next Port.
+func (c *Checksum) Add(p *Port) error {
+	port := *p
+	if port.Unlocs == nil {
+		port.Unlocs = []string{}
+	}
+	if port.Alias == nil {
+		port.Alias = []string{}
+	}
+
+	data, err := json.Marshal(port)
+	if err != nil {
+		return err
+	}
+
+	_, _ = c.h.Write(append(data, '\n'))
+	return nil
+}
+
+// Sum returns the hex encoded checksum of the Ports added so far.
+func (c *Checksum) Sum() string {
+	return hex.EncodeToString(c.h.Sum(nil))
+}
+
+// CreateSnapshot copies every Port to a new Snapshot, or returns ErrSnapshotExists.
+func (db *Database) CreateSnapshot(ctx context.Context, name, createdBy string) (*Snapshot, error) {
+	out := &Snapshot{Name: name, CreatedAt: time.Now().UTC(), CreatedBy: createdBy}
+
+	err := db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(out)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrSnapshotExists
+		}
+
+		// A single statement copies a consistent view of the table.
+		err := tx.Exec("INSERT INTO snapshot_ports (snapshot, "+portColumns+") SELECT ?, "+portColumns+" FROM ports", name).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Exec("INSERT INTO snapshot_port_sources (snapshot, "+sourceColumns+") SELECT ?, "+sourceColumns+" FROM port_sources", name).Error
+		if err != nil {
+			return err
+		}
+
+		ports, err := snapshotPorts(tx, name)
+		if err != nil {
+			return err
+		}
+
+		if out.Rows, out.Checksum, err = checksum(ports); err != nil {
+			return err
+		}
+
+		return tx.Model(out).Select("row_count", "checksum").Updates(out).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Snapshots returns every Snapshot, oldest first.
+func (db *Database) Snapshots(ctx context.Context) ([]Snapshot, error) {
+	var out []Snapshot
+	if err := db.db.WithContext(ctx).Order("created_at, name").Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// RestoreSnapshot replaces every Port and source value with the ones of a Snapshot in a single transaction,
+// writing the Port Changes to the history and the outbox, or returns ErrSnapshotNotFound.
+// The Snapshot is checked against its row count and checksum first.
+func (db *Database) RestoreSnapshot(ctx context.Context, name string) (*Restore, error) {
+	var out Restore
+
+	err := db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var snap Snapshot
+		res := tx.Where("name = ?", name).Limit(1).Find(&snap)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrSnapshotNotFound
+		}
+
+		ports, err := snapshotPorts(tx, name)
+		if err != nil {
+			return err
+		}
+
+		rows, sum, err := checksum(ports)
+		if err != nil {
+			return err
+		}
+		if rows != snap.Rows || sum != snap.Checksum {
+			return ErrSnapshotCorrupted
+		}
+
+		if db.schema.dialect == Postgres {
+			// Holds off concurrent writes, and their locking reads, until the restore commits.
+			if err := tx.Exec("LOCK TABLE ports, port_sources IN EXCLUSIVE MODE").Error; err != nil {
+				return err
+			}
+		}
+
+		var current []Port
+		if err := tx.Find(&current).Error; err != nil {
+			return err
+		}
+
+		stored := make(map[string]*Port, len(current))
+		for i := range current {
+			stored[current[i].Key] = &current[i]
+		}
+
+		for i := range ports {
+			port := &ports[i]
+
+			before := stored[port.Key]
+			delete(stored, port.Key)
+
+			if err := restore(tx, before, port, &out); err != nil {
+				return err
+			}
+		}
+
+		keys := make([]string, 0, len(stored))
+		for key := range stored {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if err := restore(tx, stored[key], nil, &out); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Exec("DELETE FROM port_sources").Error; err != nil {
+			return err
+		}
+
+		return tx.Exec("INSERT INTO port_sources ("+sourceColumns+") SELECT "+sourceColumns+" FROM snapshot_port_sources WHERE snapshot = ?", name).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
+
+// restore turns a stored Port, nil if missing, into its Snapshot version, nil deleting it, counting the outcome.
+func restore(tx *gorm.DB, before, after *Port, out *Restore) error {
+	port := after
+	if port == nil {
+		port = before
+	}
+
+	change, err := newChange(port.Key, before, after)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case change == nil:
+		out.Unchanged++
+		return nil
+	case before == nil:
+		err = tx.Create(after).Error
+		out.Created++
+	case after == nil:
+		err = tx.Where("key = ?", before.Key).Delete(&Port{}).Error
+		out.Deleted++
+	default:
+		err = tx.Model(&Port{}).Where("key = ?", after.Key).Select("*").Updates(after).Error
+		out.Updated++
+	}
+	if err != nil {
+		return err
+	}
+
+	return save(tx, change, after)
+}
+
+// DeleteSnapshot removes a Snapshot along with its Ports and source values, or returns ErrSnapshotNotFound.
+func (db *Database) DeleteSnapshot(ctx context.Context, name string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM snapshot_ports WHERE snapshot = ?", name).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM snapshot_port_sources WHERE snapshot = ?", name).Error; err != nil {
+			return err
+		}
+
+		res := tx.Where("name = ?", name).Delete(&Snapshot{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrSnapshotNotFound
+		}
+
+		return nil
+	})
+}
+
+// snapshotPorts returns the Ports of a Snapshot, ordered by key.
+func snapshotPorts(tx *gorm.DB, name string) ([]Port, error) {
+	var out []Port
+	if err := tx.Table("snapshot_ports").Where("snapshot = ?", name).Order("key").Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// checksum returns the count and Checksum of Ports ordered by key.
+func checksum(ports []Port) (int64, string, error) {
+	sum := NewChecksum()
+	for i := range ports {
+		if err := sum.Add(&ports[i]); err != nil {
+			return 0, "", err
+		}
+	}
+
+	return int64(len(ports)), sum.Sum(), nil
+}
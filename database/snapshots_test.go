@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabase_RestoreSnapshot(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := config.Default().Database
+	cfg.DSN = "sqlite://" + filepath.Join(t.TempDir(), "ports.db")
+
+	m, err := NewMigrator(cfg)
+	require.NoError(t, err)
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+
+	db, err := New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	port := &Port{Key: "AEAJM", Name: "Ajman", Unlocs: pq.StringArray{"AEAJM"}, Alias: pq.StringArray{}}
+	require.NoError(t, db.Merge(ctx, port, MergeOptions{}))
+
+	_, err = db.CreateSnapshot(ctx, "before", "tester")
+	require.NoError(t, err)
+
+	changed := *port
+	changed.Name = "Ajman Port"
+	require.NoError(t, db.Merge(ctx, &changed, MergeOptions{}))
+
+	_, err = db.SequenceChanges(ctx, 10)
+	require.NoError(t, err)
+	_, last, err := db.ChangeLogBounds(ctx)
+	require.NoError(t, err)
+
+	_, err = db.RestoreSnapshot(ctx, "before")
+	require.NoError(t, err)
+
+	_, err = db.SequenceChanges(ctx, 10)
+	require.NoError(t, err)
+	changes, err := db.Changes(ctx, last, 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1, "a restore must write its changes to the outbox")
+	require.Equal(t, ChangeUpdate, changes[0].Type)
+	require.Equal(t, `["name"]`, changes[0].Fields)
+
+	var versions int64
+	require.NoError(t, db.db.Model(&portVersion{}).Where("key = ?", "AEAJM").Count(&versions).Error)
+	require.EqualValues(t, 3, versions, "a restore must write its versions to the history")
+
+	require.NoError(t, db.db.Exec("UPDATE snapshot_ports SET name = 'tampered'").Error)
+
+	_, err = db.RestoreSnapshot(ctx, "before")
+	require.ErrorIs(t, err, ErrSnapshotCorrupted)
+
+	got, err := db.Get(ctx, "AEAJM")
+	require.NoError(t, err)
+	require.Equal(t, "Ajman", got.Name)
+}
@@ -0,0 +1,339 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultSource is the source of the ports imported without one.
+const DefaultSource = "default"
+
+// errMergeConflict is returned when the rows of a merged Port are inserted concurrently, the merge being retried.
+var errMergeConflict = errors.New("port merged concurrently")
+
+// SourcePort represents a port_sources database table, the values of a Port as last imported from a source.
+type SourcePort struct {
+	Port   `gorm:"embedded"`
+	Source string `gorm:"primarykey" json:"source"`
+	// Supplies lists the config.MergeFields whose stored value comes from this source.
+	Supplies  pq.StringArray `gorm:"type:text[]" json:"supplies"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// TableName overrides the gorm default table name.
+func (SourcePort) TableName() string {
+	return "port_sources"
+}
+
+// Precedence represents how the values of several sources are merged into a Port, the zero value
+// ranking the most recently imported source first.
+type Precedence struct {
+	// Order ranks the sources, the first one supplying a field winning.
+	// Unlisted sources rank after the listed ones, the most recently imported first.
+	Order []string
+	// Fields overrides Order per config.MergeFields name.
+	Fields map[string][]string
+}
+
+// NewPrecedence instantiates the configured Precedence.
+func NewPrecedence(cfg config.Merge) (Precedence, error) {
+	order, fields, err := cfg.Parse()
+	if err != nil {
+		return Precedence{}, err
+	}
+
+	return Precedence{Order: order, Fields: fields}, nil
+}
+
+// rank returns the sources ordered by precedence for a field.
+func (p Precedence) rank(field string, sources []SourcePort) []*SourcePort {
+	order, ok := p.Fields[field]
+	if !ok {
+		order = p.Order
+	}
+
+	index := func(s *SourcePort) int {
+		for i, name := range order {
+			if name == s.Source {
+				return i
+			}
+		}
+		return len(order)
+	}
+
+	out := make([]*SourcePort, len(sources))
+	for i := range sources {
+		out[i] = &sources[i]
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if ia, ib := index(a), index(b); ia != ib {
+			return ia < ib
+		}
+		if !a.UpdatedAt.Equal(b.UpdatedAt) {
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+		return a.Source < b.Source
+	})
+
+	return out
+}
+
+// Merge returns the Port made of the values of several sources of a key: each field comes from the first source
+// by Precedence supplying it, staying empty if none does, and the alias and unlocs lists are the union of
+// every source, in precedence order. The Supplies of every source are set accordingly.
+func Merge(sources []SourcePort, p Precedence) Port {
+	var out Port
+	if len(sources) == 0 {
+		return out
+	}
+	out.Key = sources[0].Key
+
+	supplies := make(map[string][]string, len(sources))
+
+	for _, field := range config.MergeFields {
+		ranked := p.rank(field, sources)
+
+		if list := listField(field); list != nil {
+			var merged []string
+			seen := make(map[string]bool)
+			for _, s := range ranked {
+				values := *list(&s.Port)
+				if values != nil && merged == nil {
+					merged = []string{}
+				}
+
+				contributed := false
+				for _, v := range values {
+					if !seen[v] {
+						seen[v] = true
+						merged = append(merged, v)
+						contributed = true
+					}
+				}
+				if contributed {
+					supplies[s.Source] = append(supplies[s.Source], field)
+				}
+			}
+
+			*list(&out) = merged
+			continue
+		}
+
+		for _, s := range ranked {
+			if supplied(field, &s.Port) {
+				copyField(field, &out, &s.Port)
+				supplies[s.Source] = append(supplies[s.Source], field)
+				break
+			}
+		}
+	}
+
+	for i := range sources {
+		sources[i].Supplies = supplies[sources[i].Source]
+		if sources[i].Supplies == nil {
+			sources[i].Supplies = pq.StringArray{}
+		}
+	}
+
+	return out
+}
+
+func listField(field string) func(*Port) *pq.StringArray {
+	switch field {
+	case "alias":
+		return func(p *Port) *pq.StringArray { return &p.Alias }
+	case "unlocs":
+		return func(p *Port) *pq.StringArray { return &p.Unlocs }
+	default:
+		return nil
+	}
+}
+
+// supplied tells whether a Port holds a value for a scalar merge field.
+func supplied(field string, p *Port) bool {
+	switch field {
+	case "code":
+		return p.Code != ""
+	case "name":
+		return p.Name != ""
+	case "city":
+		return p.City != ""
+	case "province":
+		return p.Province != ""
+	case "country":
+		return p.Country != ""
+	case "timezone":
+		return p.Timezone != ""
+	case "coordinates":
+		return p.Latitude != 0 || p.Longitude != 0
+	default:
+		return false
+	}
+}
+
+func copyField(field string, dst, src *Port) {
+	switch field {
+	case "code":
+		dst.Code = src.Code
+	case "name":
+		dst.Name = src.Name
+	case "city":
+		dst.City = src.City
+	case "province":
+		dst.Province = src.Province
+	case "country":
+		dst.Country = src.Country
+	case "timezone":
+		dst.Timezone = src.Timezone
+	case "coordinates":
+		dst.Latitude, dst.Longitude = src.Latitude, src.Longitude
+	}
+}
+
+// MergeOptions represents how a Port imported from a source is merged with the ones of the other sources.
+type MergeOptions struct {
+	// Source names the source, DefaultSource if empty.
+	Source     string
+	Precedence Precedence
+	// Conflict is the policy applied to the merged Port, ConflictOverwrite if empty.
+	Conflict Conflict
+}
+
+func (o MergeOptions) source() string {
+	if o.Source == "" {
+		return DefaultSource
+	}
+	return o.Source
+}
+
+// Merge stores the values of a Port imported from a source, then stores the Port merged from every source of its key,
+// writing its Change to the outbox and its version to the history in the same transaction.
+// Merges changing nothing in the stored Port only update the source values.
+// It returns ErrSkipped or ErrConflict, writing nothing, when the Conflict policy says so.
+func (db *Database) Merge(ctx context.Context, port *Port, opts MergeOptions) error {
+	for attempt := 0; attempt < maxMergeAttempts; attempt++ {
+		err := db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			before, err := db.current(tx, port.Key)
+			if err != nil {
+				return err
+			}
+
+			var sources []SourcePort
+			if err := tx.Where("key = ?", port.Key).Order("source").Find(&sources).Error; err != nil {
+				return err
+			}
+
+			supplies := make(map[string][]string, len(sources))
+			for _, s := range sources {
+				supplies[s.Source] = s.Supplies
+			}
+
+			sources, row, previous := setSource(sources, port, opts.source())
+			merged := Merge(sources, opts.Precedence)
+			if err := opts.Conflict.Apply(before, &merged, sources, supplies); err != nil {
+				return err
+			}
+			opts.Conflict.Keep(before, &row.Port, previous)
+
+			change, err := newChange(port.Key, before, &merged)
+			if err != nil {
+				return err
+			}
+
+			if change != nil {
+				if before == nil {
+					res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&merged)
+					if res.Error != nil {
+						return res.Error
+					}
+					if res.RowsAffected == 0 {
+						return errMergeConflict
+					}
+				} else if err := tx.Model(&Port{}).Where("key = ?", port.Key).Select("*").Updates(&merged).Error; err != nil {
+					return err
+				}
+
+				if err := save(tx, change, &merged); err != nil {
+					return err
+				}
+			}
+
+			return saveSources(tx, sources, supplies, opts.source(), previous == nil)
+		})
+		if !errors.Is(err, errMergeConflict) {
+			return err
+		}
+	}
+
+	return errConcurrentInsert
+}
+
+// setSource replaces, or adds, the values of a source, returning its row along with its previous values, nil if new.
+func setSource(sources []SourcePort, port *Port, source string) ([]SourcePort, *SourcePort, *Port) {
+	row := SourcePort{Port: *port, Source: source, UpdatedAt: time.Now().UTC()}
+
+	for i := range sources {
+		if sources[i].Source == source {
+			previous := sources[i].Port
+			sources[i] = row
+			return sources, &sources[i], &previous
+		}
+	}
+
+	sources = append(sources, row)
+	return sources, &sources[len(sources)-1], nil
+}
+
+// saveSources writes the values of the imported source, and the Supplies of the others when they differ from the stored ones.
+func saveSources(tx *gorm.DB, sources []SourcePort, stored map[string][]string, source string, created bool) error {
+	for i := range sources {
+		s := &sources[i]
+
+		switch {
+		case s.Source != source && equal(s.Supplies, stored[s.Source]):
+		case s.Source != source:
+			err := tx.Model(&SourcePort{}).Where("key = ? AND source = ?", s.Key, s.Source).Update("supplies", s.Supplies).Error
+			if err != nil {
+				return err
+			}
+		case created:
+			res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(s)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errMergeConflict
+			}
+		default:
+			err := tx.Model(&SourcePort{}).Where("key = ? AND source = ?", s.Key, s.Source).Select("*").Updates(s).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Sources returns the values of every source of a key, ordered by source, or ErrNotFound.
+func (db *Database) Sources(ctx context.Context, key string) ([]SourcePort, error) {
+	var out []SourcePort
+	if err := db.db.WithContext(ctx).Where("key = ?", key).Order("source").Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return out, nil
+}
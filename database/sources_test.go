@@ -0,0 +1,72 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	now := time.Now()
+
+	sources := func() []SourcePort {
+		return []SourcePort{
+			{
+				Port:      Port{Key: "AEAJM", Name: "Ajman", City: "Ajman", Latitude: 55.5, Longitude: 25.4, Unlocs: pq.StringArray{"AEAJM"}},
+				Source:    "geo",
+				UpdatedAt: now.Add(-time.Hour),
+			},
+			{
+				Port:      Port{Key: "AEAJM", Name: "Ajman Port", Country: "United Arab Emirates", Unlocs: pq.StringArray{"AEAJM", "AEAJX"}, Alias: pq.StringArray{"Ajman"}},
+				Source:    "supplier",
+				UpdatedAt: now,
+			},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		precedence Precedence
+		want       Port
+		supplies   map[string][]string
+	}{
+		{
+			name: "most recent first",
+			want: Port{
+				Key: "AEAJM", Name: "Ajman Port", City: "Ajman", Country: "United Arab Emirates", Latitude: 55.5, Longitude: 25.4,
+				Unlocs: pq.StringArray{"AEAJM", "AEAJX"}, Alias: pq.StringArray{"Ajman"},
+			},
+			supplies: map[string][]string{
+				"geo":      {"city", "coordinates"},
+				"supplier": {"name", "country", "alias", "unlocs"},
+			},
+		},
+		{
+			name:       "ranked",
+			precedence: Precedence{Order: []string{"geo"}, Fields: map[string][]string{"unlocs": {"supplier"}}},
+			want: Port{
+				Key: "AEAJM", Name: "Ajman", City: "Ajman", Country: "United Arab Emirates", Latitude: 55.5, Longitude: 25.4,
+				Unlocs: pq.StringArray{"AEAJM", "AEAJX"}, Alias: pq.StringArray{"Ajman"},
+			},
+			supplies: map[string][]string{
+				"geo":      {"name", "city", "coordinates"},
+				"supplier": {"country", "alias", "unlocs"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := sources()
+
+			require.Equal(t, tt.want, Merge(in, tt.precedence))
+
+			supplies := make(map[string][]string)
+			for _, s := range in {
+				supplies[s.Source] = s.Supplies
+			}
+			require.Equal(t, tt.supplies, supplies)
+		})
+	}
+}
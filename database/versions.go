@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// portVersion represents a port_versions database table, a Port as stored from ValidFrom until ValidTo, nil while current.
+type portVersion struct {
+	ID        int64 `gorm:"primarykey"`
+	Key       string
+	Code      string
+	Name      string
+	City      string
+	Province  string
+	Country   string
+	Timezone  string
+	Latitude  float64
+	Longitude float64
+	Unlocs    pq.StringArray `gorm:"type:text[]"`
+	Alias     pq.StringArray `gorm:"type:text[]"`
+	ValidFrom time.Time
+	ValidTo   *time.Time
+}
+
+// TableName overrides the gorm default table name.
+func (portVersion) TableName() string {
+	return "port_versions"
+}
+
+// save writes a Change to the port history and the outbox, port being the one stored, nil when deleted.
+func save(tx *gorm.DB, change *Change, port *Port) error {
+	if err := record(tx, change, port); err != nil {
+		return err
+	}
+
+	return tx.Create(change).Error
+}
+
+// record closes the current version of a changed Port and opens the next one, if not deleted,
+// both at the Change time so the history has no gaps.
+func record(tx *gorm.DB, change *Change, port *Port) error {
+	err := tx.Model(&portVersion{}).
+		Where("key = ? AND valid_to IS NULL", change.Key).
+		Update("valid_to", change.CreatedAt).Error
+	if err != nil || port == nil {
+		return err
+	}
+
+	return tx.Create(&portVersion{
+		Key:       port.Key,
+		Code:      port.Code,
+		Name:      port.Name,
+		City:      port.City,
+		Province:  port.Province,
+		Country:   port.Country,
+		Timezone:  port.Timezone,
+		Latitude:  port.Latitude,
+		Longitude: port.Longitude,
+		Unlocs:    port.Unlocs,
+		Alias:     port.Alias,
+		ValidFrom: change.CreatedAt,
+	}).Error
+}
+
+// ParseAsOf parses an RFC 3339 point in time.
+func ParseAsOf(s string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid as of time %q, must be RFC 3339, e.g. 2006-01-02T15:04:05Z", s)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook represents a webhooks database table, holding the subscriptions to the notified events.
+type Webhook struct {
+	ID        string         `gorm:"primarykey" json:"id"`
+	URL       string         `json:"url"`
+	Events    pq.StringArray `gorm:"type:text[]" json:"events"`
+	Secret    string         `json:"secret,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Delivery represents a webhook_deliveries database table, holding the notifications sent, or to be sent, to the webhooks.
+type Delivery struct {
+	ID            int64      `gorm:"primarykey" json:"id"`
+	WebhookID     string     `json:"webhook_id"`
+	Event         string     `json:"event"`
+	Payload       string     `json:"payload"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	ResponseCode  int        `json:"response_code,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// TableName overrides the gorm default table name.
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// CreateWebhook stores a new Webhook.
+func (db *Database) CreateWebhook(ctx context.Context, hook *Webhook) error {
+	return db.db.WithContext(ctx).Create(hook).Error
+}
+
+// Webhooks returns every Webhook, oldest first.
+func (db *Database) Webhooks(ctx context.Context) ([]Webhook, error) {
+	var out []Webhook
+	if err := db.db.WithContext(ctx).Order("created_at, id").Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetWebhook returns the Webhook with the given ID, or ErrNotFound.
+func (db *Database) GetWebhook(ctx context.Context, id string) (*Webhook, error) {
+	var out Webhook
+	tx := db.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&out)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &out, nil
+}
+
+// UpdateWebhook replaces the URL, events and secret of a Webhook, or returns ErrNotFound.
+func (db *Database) UpdateWebhook(ctx context.Context, hook *Webhook) error {
+	tx := db.db.WithContext(ctx).Model(&Webhook{}).Where("id = ?", hook.ID).Updates(map[string]interface{}{
+		"url":        hook.URL,
+		"events":     hook.Events,
+		"secret":     hook.Secret,
+		"updated_at": hook.UpdatedAt,
+	})
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteWebhook removes the Webhook with the given ID and its Deliveries, or returns ErrNotFound.
+func (db *Database) DeleteWebhook(ctx context.Context, id string) error {
+	return db.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&Delivery{}).Error; err != nil {
+			return err
+		}
+
+		res := tx.Where("id = ?", id).Delete(&Webhook{})
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+}
+
+// InsertDeliveries stores new Deliveries.
+func (db *Database) InsertDeliveries(ctx context.Context, deliveries []Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	return db.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+// ClaimDeliveries returns the oldest pending Deliveries due at a time, up to limit,
+// postponing their next attempt by lease so concurrent calls do not return them again meanwhile.
+func (db *Database) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]Delivery, error) {
+	var lock string
+	if db.schema.dialect == Postgres {
+		lock = " FOR UPDATE SKIP LOCKED"
+	}
+
+	var out []Delivery
+	err := db.db.WithContext(ctx).Raw(
+		"UPDATE webhook_deliveries SET next_attempt_at = ? WHERE id IN "+
+			"(SELECT id FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ? ORDER BY id LIMIT ?"+lock+") RETURNING *",
+		now.Add(lease).UTC(), DeliveryPending, now.UTC(), limit,
+	).Scan(&out).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+
+	return out, nil
+}
+
+// SaveDelivery stores the outcome of a Delivery attempt, unless deleted meanwhile.
+func (db *Database) SaveDelivery(ctx context.Context, delivery *Delivery) error {
+	return db.db.WithContext(ctx).Model(&Delivery{}).Where("id = ?", delivery.ID).
+		Select("status", "attempts", "response_code", "error", "next_attempt_at", "delivered_at").
+		Updates(delivery).Error
+}
+
+// Deliveries returns the last Deliveries of a Webhook, newest first, up to limit.
+func (db *Database) Deliveries(ctx context.Context, webhookID string, limit int) ([]Delivery, error) {
+	var out []Delivery
+	if err := db.db.WithContext(ctx).Where("webhook_id = ?", webhookID).Order("id DESC").Limit(limit).Find(&out).Error; err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// PruneDeliveries deletes the finished Deliveries created before a time, returning the number deleted.
+func (db *Database) PruneDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	tx := db.db.WithContext(ctx).Where("status <> ? AND created_at < ?", DeliveryPending, before.UTC()).Delete(&Delivery{})
+	return tx.RowsAffected, tx.Error
+}
@@ -0,0 +1,228 @@
+// Package deadletter includes the sinks storing the records rejected by the imports.
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/parser"
+	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/storage"
+)
+
+// Store represents the storage of the rejects table, implemented by the database and memory backends.
+type Store interface {
+	InsertReject(context.Context, *database.Reject) error
+	EachReject(context.Context, string, func(*database.Reject) error) error
+}
+
+// Open returns the sink selected by the configuration, nil when disabled.
+func Open(cfg config.DeadLetter, db storage.Storage) (service.DeadLetters, error) {
+	switch cfg.Sink {
+	case config.SinkFile:
+		return NewFile(cfg.File), nil
+	case config.SinkDatabase:
+		store, ok := db.(Store)
+		if !ok {
+			return nil, errors.New("dead-letter database sink unsupported by the storage")
+		}
+		return NewDatabase(store), nil
+	case config.SinkNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown dead-letter sink %q", cfg.Sink)
+	}
+}
+
+// File represents a sink appending the dead letters to an NDJSON file, it is safe for concurrent use.
+type File struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFile instantiates a new File sink.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// Write appends a DeadLetter to the file, creating it if needed.
+func (f *File) Write(_ context.Context, l *service.DeadLetter) error {
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Each calls fn for every DeadLetter of an import found in the file.
+func (f *File) Each(ctx context.Context, importID string, fn func(*service.DeadLetter) error) error {
+	file, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return Decode(ctx, file, importID, fn)
+}
+
+// Decode calls fn for every DeadLetter of an import read from NDJSON, every one when importID is empty.
+func Decode(ctx context.Context, r io.Reader, importID string, fn func(*service.DeadLetter) error) error {
+	dec := json.NewDecoder(r)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var l service.DeadLetter
+		err := dec.Decode(&l)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if importID != "" && l.ImportID != importID {
+			continue
+		}
+
+		if err := fn(&l); err != nil {
+			return err
+		}
+	}
+}
+
+// Database represents a sink storing the dead letters in the rejects table.
+type Database struct {
+	store Store
+}
+
+// NewDatabase instantiates a new Database sink.
+func NewDatabase(store Store) *Database {
+	return &Database{store: store}
+}
+
+// Write stores a DeadLetter.
+func (d *Database) Write(ctx context.Context, l *service.DeadLetter) error {
+	return d.store.InsertReject(ctx, &database.Reject{
+		ImportID:   l.ImportID,
+		Source:     l.Source,
+		Conflict:   string(l.Conflict),
+		Key:        l.Key,
+		Error:      l.Error,
+		Record:     string(l.Record),
+		RejectedAt: l.RejectedAt,
+	})
+}
+
+// Each calls fn for every stored DeadLetter of an import.
+func (d *Database) Each(ctx context.Context, importID string, fn func(*service.DeadLetter) error) error {
+	return d.store.EachReject(ctx, importID, func(r *database.Reject) error {
+		l := &service.DeadLetter{
+			ImportID:   r.ImportID,
+			Source:     r.Source,
+			Conflict:   database.Conflict(r.Conflict),
+			Key:        r.Key,
+			Error:      r.Error,
+			RejectedAt: r.RejectedAt,
+		}
+		if r.Record != "" {
+			l.Record = json.RawMessage(r.Record)
+		}
+
+		return fn(l)
+	})
+}
+
+// Source streams the records of dead letters to import them again, implementing parser.Source.
+// Dead letters without record, whose input could not be read, are skipped.
+type Source struct {
+	each    func(func(*service.DeadLetter) error) error
+	skipped int
+}
+
+// NewSource instantiates a Source reading the dead letters from an iteration function, e.g. a sink Each method.
+func NewSource(each func(func(*service.DeadLetter) error) error) *Source {
+	return &Source{each: each}
+}
+
+// Skipped returns the number of dead letters without record, once the stream is exhausted.
+func (s *Source) Skipped() int {
+	return s.skipped
+}
+
+// Stream returns a Packet unbuffered channel.
+func (s *Source) Stream(ctx context.Context) chan parser.Packet {
+	out := make(chan parser.Packet)
+
+	go func() {
+		defer close(out)
+
+		err := s.each(func(l *service.DeadLetter) error {
+			if len(l.Record) == 0 {
+				s.skipped++
+				return nil
+			}
+
+			select {
+			case out <- packet(l):
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			select {
+			case out <- parser.Packet{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return out
+}
+
+// packet parses the record of a DeadLetter, whose own key field, if any, takes precedence over the DeadLetter key,
+// so a fixed NDJSON record may change it.
+func packet(l *service.DeadLetter) parser.Packet {
+	var keyed struct {
+		Key string `json:"key"`
+	}
+
+	key := l.Key
+	if err := json.Unmarshal(l.Record, &keyed); err == nil && keyed.Key != "" {
+		key = ""
+	}
+
+	p := parser.Packet{Key: l.Key, Raw: l.Record}
+	if p.Port, p.Err = parser.Decode(key, l.Record); p.Port != nil {
+		p.Key = p.Port.Key
+	}
+
+	return p
+}
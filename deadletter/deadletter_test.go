@@ -0,0 +1,98 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/parser"
+	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+var letters = []service.DeadLetter{
+	{ImportID: "one", Source: "supplier-a", Conflict: database.ConflictFillEmpty, Key: "BAD", Error: "invalid coordinates", Record: json.RawMessage(`{"name":"bad","coordinates":[]}`)},
+	{ImportID: "two", Key: "OTHER", Error: "upsert failed", Record: json.RawMessage(`{"name":"other"}`)},
+	{ImportID: "one", Error: "unexpected EOF"},
+}
+
+func collect(t *testing.T, sink service.DeadLetters, importID string) []service.DeadLetter {
+	t.Helper()
+
+	var out []service.DeadLetter
+	require.NoError(t, sink.Each(context.Background(), importID, func(l *service.DeadLetter) error {
+		out = append(out, *l)
+		return nil
+	}))
+	return out
+}
+
+func TestSinks(t *testing.T) {
+	sinks := map[string]service.DeadLetters{
+		"file":     NewFile(filepath.Join(t.TempDir(), "rejects.ndjson")),
+		"database": NewDatabase(memory.New()),
+	}
+
+	for name, sink := range sinks {
+		t.Run(name, func(t *testing.T) {
+			require.Empty(t, collect(t, sink, "one"))
+
+			now := time.Now().UTC().Truncate(time.Second)
+			for i := range letters {
+				l := letters[i]
+				l.RejectedAt = now
+				require.NoError(t, sink.Write(context.Background(), &l))
+			}
+
+			got := collect(t, sink, "one")
+			require.Len(t, got, 2)
+			require.Equal(t, "BAD", got[0].Key)
+			require.Equal(t, "supplier-a", got[0].Source)
+			require.Equal(t, database.ConflictFillEmpty, got[0].Conflict)
+			require.JSONEq(t, string(letters[0].Record), string(got[0].Record))
+			require.True(t, now.Equal(got[0].RejectedAt))
+			require.Equal(t, "unexpected EOF", got[1].Error)
+			require.Empty(t, got[1].Record)
+		})
+	}
+}
+
+func TestOpen(t *testing.T) {
+	sink, err := Open(config.DeadLetter{Sink: config.SinkNone}, memory.New())
+	require.NoError(t, err)
+	require.Nil(t, sink)
+
+	sink, err = Open(config.DeadLetter{Sink: config.SinkDatabase}, memory.New())
+	require.NoError(t, err)
+	require.IsType(t, &Database{}, sink)
+}
+
+func TestSource(t *testing.T) {
+	input := append(letters, service.DeadLetter{ImportID: "one", Key: "OLD", Record: json.RawMessage(`{"key":"NEW","name":"fixed"}`)})
+
+	src := NewSource(func(fn func(*service.DeadLetter) error) error {
+		for i := range input {
+			if input[i].ImportID == "one" {
+				if err := fn(&input[i]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	var got []parser.Packet
+	for p := range src.Stream(context.Background()) {
+		got = append(got, p)
+	}
+
+	require.Len(t, got, 2)
+	require.Equal(t, &parser.Port{Key: "BAD", Name: "bad", Coordinates: []float64{}}, got[0].Port)
+	require.Equal(t, &parser.Port{Key: "NEW", Name: "fixed"}, got[1].Port)
+	require.Equal(t, 1, src.Skipped())
+}
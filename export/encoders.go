@@ -0,0 +1,196 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/agukrapo/ports/database"
+)
+
+// ListSeparator joins list values in CSV cells.
+const ListSeparator = "|"
+
+type jsonEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonEncoder) Encode(in *database.Port) error {
+	key, err := json.Marshal(in.Key)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.MarshalIndent(from(in), "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n  "
+	if e.count == 0 {
+		sep = "{\n  "
+	}
+	e.count++
+
+	_, err = io.WriteString(e.w, sep+string(key)+": "+string(value))
+	return err
+}
+
+func (e *jsonEncoder) Close() error {
+	end := "\n}\n"
+	if e.count == 0 {
+		end = "{}\n"
+	}
+
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+type ndjsonEncoder struct {
+	enc *json.Encoder
+}
+
+func newNDJSONEncoder(w io.Writer) *ndjsonEncoder {
+	return &ndjsonEncoder{enc: json.NewEncoder(w)}
+}
+
+type keyedPort struct {
+	Key string `json:"key"`
+	port
+}
+
+func (e *ndjsonEncoder) Encode(in *database.Port) error {
+	return e.enc.Encode(keyedPort{Key: in.Key, port: from(in)})
+}
+
+func (e *ndjsonEncoder) Close() error {
+	return nil
+}
+
+var csvHeader = []string{"key", "name", "city", "province", "country", "alias", "latitude", "longitude", "timezone", "unlocs", "code"}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVEncoder(w io.Writer) *csvEncoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+func (e *csvEncoder) Encode(in *database.Port) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	return e.w.Write([]string{
+		in.Key,
+		in.Name,
+		in.City,
+		in.Province,
+		in.Country,
+		strings.Join(in.Alias, ListSeparator),
+		// The coordinates are stored in the order the source files give them, longitude first.
+		strconv.FormatFloat(in.Longitude, 'f', -1, 64),
+		strconv.FormatFloat(in.Latitude, 'f', -1, 64),
+		in.Timezone,
+		strings.Join(in.Unlocs, ListSeparator),
+		in.Code,
+	})
+}
+
+func (e *csvEncoder) writeHeader() error {
+	if e.header {
+		return nil
+	}
+	e.header = true
+
+	return e.w.Write(csvHeader)
+}
+
+func (e *csvEncoder) Close() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type geoJSONEncoder struct {
+	w     io.Writer
+	count int
+}
+
+type feature struct {
+	Type       string     `json:"type"`
+	ID         string     `json:"id"`
+	Geometry   point      `json:"geometry"`
+	Properties properties `json:"properties"`
+}
+
+type point struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type properties struct {
+	Name     string   `json:"name"`
+	City     string   `json:"city"`
+	Province string   `json:"province"`
+	Country  string   `json:"country"`
+	Alias    []string `json:"alias"`
+	Timezone string   `json:"timezone"`
+	Unlocs   []string `json:"unlocs"`
+	Code     string   `json:"code"`
+}
+
+const geoJSONStart = `{"type":"FeatureCollection","features":[`
+
+func (e *geoJSONEncoder) Encode(in *database.Port) error {
+	// The source coordinates already are in GeoJSON [longitude, latitude] order,
+	// they are emitted the same way they were read.
+	value, err := json.Marshal(feature{
+		Type: "Feature",
+		ID:   in.Key,
+		Geometry: point{
+			Type:        "Point",
+			Coordinates: []float64{in.Latitude, in.Longitude},
+		},
+		Properties: properties{
+			Name:     in.Name,
+			City:     in.City,
+			Province: in.Province,
+			Country:  in.Country,
+			Alias:    in.Alias,
+			Timezone: in.Timezone,
+			Unlocs:   in.Unlocs,
+			Code:     in.Code,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n"
+	if e.count == 0 {
+		sep = geoJSONStart + "\n"
+	}
+	e.count++
+
+	_, err = io.WriteString(e.w, sep+string(value))
+	return err
+}
+
+func (e *geoJSONEncoder) Close() error {
+	end := "\n]}\n"
+	if e.count == 0 {
+		end = geoJSONStart + "]}\n"
+	}
+
+	_, err := io.WriteString(e.w, end)
+	return err
+}
@@ -0,0 +1,106 @@
+// Package export includes port serialization utilities.
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/agukrapo/ports/database"
+)
+
+// Format represents an export output format.
+type Format string
+
+// Available formats.
+const (
+	// JSON is the keyed object format read by the parser, so an export can be imported back.
+	JSON Format = "json"
+	// NDJSON writes a port object per line, including its key.
+	NDJSON Format = "ndjson"
+	// CSV writes a header followed by a port per row, alias and unlocs are joined by ListSeparator.
+	CSV Format = "csv"
+	// GeoJSON writes a FeatureCollection with a Point Feature per port.
+	GeoJSON Format = "geojson"
+)
+
+// Formats lists every available Format.
+var Formats = []Format{JSON, NDJSON, CSV, GeoJSON}
+
+// ParseFormat validates a Format name, an empty name defaults to JSON.
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return JSON, nil
+	}
+
+	for _, f := range Formats {
+		if Format(strings.ToLower(s)) == f {
+			return f, nil
+		}
+	}
+
+	return "", fmt.Errorf("invalid format %q, must be one of %v", s, Formats)
+}
+
+// ContentType returns the media type of the Format.
+func (f Format) ContentType() string {
+	switch f {
+	case NDJSON:
+		return "application/x-ndjson"
+	case CSV:
+		return "text/csv"
+	case GeoJSON:
+		return "application/geo+json"
+	default:
+		return "application/json"
+	}
+}
+
+// Encoder writes ports in a Format, Close must be called to complete the output.
+type Encoder interface {
+	Encode(*database.Port) error
+	Close() error
+}
+
+// NewEncoder instantiates an Encoder of the given Format.
+func NewEncoder(f Format, w io.Writer) (Encoder, error) {
+	switch f {
+	case JSON:
+		return &jsonEncoder{w: w}, nil
+	case NDJSON:
+		return newNDJSONEncoder(w), nil
+	case CSV:
+		return newCSVEncoder(w), nil
+	case GeoJSON:
+		return &geoJSONEncoder{w: w}, nil
+	default:
+		return nil, fmt.Errorf("invalid format %q", f)
+	}
+}
+
+// port mirrors parser.Port, so exported ports are read back unchanged.
+type port struct {
+	Name        string    `json:"name"`
+	City        string    `json:"city"`
+	Province    string    `json:"province"`
+	Country     string    `json:"country"`
+	Alias       []string  `json:"alias"`
+	Coordinates []float64 `json:"coordinates"`
+	Timezone    string    `json:"timezone"`
+	Unlocs      []string  `json:"unlocs"`
+	Code        string    `json:"code"`
+}
+
+func from(in *database.Port) port {
+	return port{
+		Name:        in.Name,
+		City:        in.City,
+		Province:    in.Province,
+		Country:     in.Country,
+		Alias:       in.Alias,
+		Coordinates: []float64{in.Latitude, in.Longitude},
+		Timezone:    in.Timezone,
+		Unlocs:      in.Unlocs,
+		Code:        in.Code,
+	}
+}
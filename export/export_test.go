@@ -0,0 +1,152 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/parser"
+	"github.com/stretchr/testify/require"
+)
+
+var ports = []database.Port{
+	{
+		Key:       "AEAJM",
+		Code:      "52000",
+		Name:      "Ajman",
+		City:      "Ajman",
+		Province:  "Ajman",
+		Country:   "United Arab Emirates",
+		Timezone:  "Asia/Dubai",
+		Latitude:  55.5136433,
+		Longitude: 25.4052165,
+		Unlocs:    []string{"AEAJM"},
+		Alias:     []string{},
+	},
+	{
+		Key:       "AEAUH",
+		Name:      "Abu \"Dhabi\"",
+		Latitude:  54.37,
+		Longitude: 24.47,
+		Unlocs:    []string{"AEAUH", "AEAUX"},
+	},
+}
+
+func encode(t *testing.T, f Format) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc, err := NewEncoder(f, &buf)
+	require.NoError(t, err)
+
+	for i := range ports {
+		require.NoError(t, enc.Encode(&ports[i]))
+	}
+	require.NoError(t, enc.Close())
+
+	return buf.String()
+}
+
+func TestJSON_roundTrip(t *testing.T) {
+	it, err := parser.New(bytes.NewBufferString(encode(t, JSON)))
+	require.NoError(t, err)
+
+	var got []parser.Port
+	for it.More() {
+		var p parser.Port
+		require.NoError(t, it.Next(&p))
+		got = append(got, p)
+	}
+
+	require.Equal(t, []parser.Port{
+		{
+			Key:         "AEAJM",
+			Timezone:    "Asia/Dubai",
+			Coordinates: []float64{55.5136433, 25.4052165},
+			Name:        "Ajman",
+			City:        "Ajman",
+			Province:    "Ajman",
+			Country:     "United Arab Emirates",
+			Alias:       []string{},
+			Unlocs:      []string{"AEAJM"},
+			Code:        "52000",
+		},
+		{
+			Key:         "AEAUH",
+			Coordinates: []float64{54.37, 24.47},
+			Name:        "Abu \"Dhabi\"",
+			Unlocs:      []string{"AEAUH", "AEAUX"},
+		},
+	}, got)
+}
+
+func TestEncoders_empty(t *testing.T) {
+	for _, f := range Formats {
+		t.Run(string(f), func(t *testing.T) {
+			var buf bytes.Buffer
+			enc, err := NewEncoder(f, &buf)
+			require.NoError(t, err)
+			require.NoError(t, enc.Close())
+
+			if f == CSV {
+				require.Equal(t, "key,name,city,province,country,alias,latitude,longitude,timezone,unlocs,code\n", buf.String())
+				return
+			}
+			if f != NDJSON {
+				require.True(t, json.Valid(buf.Bytes()), buf.String())
+			}
+		})
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	require.Equal(t, `{"key":"AEAJM","name":"Ajman","city":"Ajman","province":"Ajman","country":"United Arab Emirates","alias":[],"coordinates":[55.5136433,25.4052165],"timezone":"Asia/Dubai","unlocs":["AEAJM"],"code":"52000"}
+{"key":"AEAUH","name":"Abu \"Dhabi\"","city":"","province":"","country":"","alias":null,"coordinates":[54.37,24.47],"timezone":"","unlocs":["AEAUH","AEAUX"],"code":""}
+`, encode(t, NDJSON))
+}
+
+func TestCSV(t *testing.T) {
+	require.Equal(t, `key,name,city,province,country,alias,latitude,longitude,timezone,unlocs,code
+AEAJM,Ajman,Ajman,Ajman,United Arab Emirates,,25.4052165,55.5136433,Asia/Dubai,AEAJM,52000
+AEAUH,"Abu ""Dhabi""",,,,,24.47,54.37,,AEAUH|AEAUX,
+`, encode(t, CSV))
+}
+
+func TestCSV_coordinates(t *testing.T) {
+	rows, err := csv.NewReader(strings.NewReader(encode(t, CSV))).ReadAll()
+	require.NoError(t, err)
+
+	column := make(map[string]string)
+	for i, name := range rows[0] {
+		column[name] = rows[1][i]
+	}
+
+	// Ajman is given as [55.5136433, 25.4052165], longitude first.
+	require.Equal(t, "AEAJM", column["key"])
+	require.Equal(t, "25.4052165", column["latitude"])
+	require.Equal(t, "55.5136433", column["longitude"])
+}
+
+func TestGeoJSON(t *testing.T) {
+	var got struct {
+		Type     string
+		Features []struct {
+			Type     string
+			ID       string
+			Geometry struct {
+				Type        string
+				Coordinates []float64
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal([]byte(encode(t, GeoJSON)), &got))
+
+	require.Equal(t, "FeatureCollection", got.Type)
+	require.Len(t, got.Features, 2)
+	require.Equal(t, "AEAUH", got.Features[1].ID)
+	require.Equal(t, "Point", got.Features[1].Geometry.Type)
+	require.Equal(t, []float64{54.37, 24.47}, got.Features[1].Geometry.Coordinates)
+}
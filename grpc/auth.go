@@ -0,0 +1,119 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/agukrapo/ports/auth"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// scopes maps the authenticated methods to their required scope, unlisted methods require auth.ScopeAdmin.
+var scopes = map[string]auth.Scope{
+	"/" + Upload_ServiceDesc.ServiceName + "/Upload":         auth.ScopeWrite,
+	"/" + Upload_ServiceDesc.ServiceName + "/WatchImport":    auth.ScopeRead,
+	"/" + Upload_ServiceDesc.ServiceName + "/CreateUpload":   auth.ScopeWrite,
+	"/" + Upload_ServiceDesc.ServiceName + "/UploadChunks":   auth.ScopeWrite,
+	"/" + Upload_ServiceDesc.ServiceName + "/GetUpload":      auth.ScopeWrite,
+	"/" + Upload_ServiceDesc.ServiceName + "/FinalizeUpload": auth.ScopeWrite,
+	"/" + Ports_ServiceDesc.ServiceName + "/Export":          auth.ScopeRead,
+	"/" + Ports_ServiceDesc.ServiceName + "/WatchPorts":      auth.ScopeRead,
+}
+
+// public lists the services reachable without credentials.
+var public = map[string]bool{
+	healthpb.Health_ServiceDesc.ServiceName: true,
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authorize(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &authStream{ServerStream: ss, ctx: ctx})
+}
+
+// authorize checks the request metadata credentials grant the method scope,
+// returning a context carrying the authenticated auth.Principal.
+func (s *Server) authorize(ctx context.Context, method string) (context.Context, error) {
+	service, _ := split(method)
+	if public[service] {
+		return ctx, nil
+	}
+
+	scope, ok := scopes[method]
+	if !ok {
+		scope = auth.ScopeAdmin
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	p, err := s.auth.Authorize(auth.Credentials{
+		APIKey:        first(md.Get("x-api-key")),
+		Authorization: first(md.Get("authorization")),
+	}, scope)
+	if errors.Is(err, auth.ErrForbidden) {
+		log.Warn().Err(err).Str("method", method).Msg("GRPC request forbidden")
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("method", method).Msg("GRPC request unauthenticated")
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	return auth.NewContext(ctx, p), nil
+}
+
+// split splits a full method name, /package.Service/Method, into its service and method names.
+func split(fullMethod string) (string, string) {
+	for i := len(fullMethod) - 1; i > 0; i-- {
+		if fullMethod[i] == '/' {
+			return fullMethod[1:i], fullMethod[i+1:]
+		}
+	}
+	return "", fullMethod
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// authStream overrides the context of a grpc.ServerStream.
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+// tokenCredentials sends an API key or a JWT as a bearer token on every call.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity allows sending the token over plaintext connections, the server decides whether to accept them.
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
@@ -2,30 +2,106 @@ package grpc
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"io"
 
+	"github.com/agukrapo/ports/database"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 )
 
-const defaultSize = 1024
-
-// Client represents am upload gRPC client.
+// Client represents a ports gRPC client.
 type Client struct {
-	c UploadClient
+	c         UploadClient
+	p         PortsClient
+	chunkSize int
+	retries   int
+	source    string
+	conflict  database.Conflict
+}
+
+// ClientOption configures a Client connection.
+type ClientOption func(*clientOptions)
+
+type clientOptions struct {
+	creds    credentials.TransportCredentials
+	dial     []grpc.DialOption
+	retries  int
+	source   string
+	conflict database.Conflict
+}
+
+// WithToken authenticates every call with an API key or a JWT, sent as a bearer token.
+func WithToken(token string) ClientOption {
+	return func(o *clientOptions) {
+		if token != "" {
+			o.dial = append(o.dial, grpc.WithPerRPCCredentials(tokenCredentials(token)))
+		}
+	}
+}
+
+// WithTLS dials the server over TLS, the connection is plaintext otherwise.
+func WithTLS(cfg *tls.Config) ClientOption {
+	return func(o *clientOptions) {
+		o.creds = credentials.NewTLS(cfg)
+	}
+}
+
+// WithRetries sets how many times UploadFile resumes an interrupted upload, 5 by default.
+func WithRetries(n int) ClientOption {
+	return func(o *clientOptions) {
+		o.retries = n
+	}
+}
+
+// WithSource tags the uploaded ports with the name of their source, the server default one if empty.
+func WithSource(name string) ClientOption {
+	return func(o *clientOptions) {
+		o.source = name
+	}
+}
+
+// WithConflict sets the policy the uploaded ports are written with, overwrite if empty.
+func WithConflict(c database.Conflict) ClientOption {
+	return func(o *clientOptions) {
+		o.conflict = c
+	}
+}
+
+// WithIdempotencyKey returns a context sending an idempotency key along the upload calls,
+// making the server return the original result of an upload submitted again.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, idempotencyKey, key)
 }
 
 // NewClient instantiates a new Client.
-func NewClient(address string) (*Client, error) {
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+func NewClient(address string, chunkSize int, opts ...ClientOption) (*Client, error) {
+	o := clientOptions{creds: insecure.NewCredentials(), retries: 5}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	dialOpts := append([]grpc.DialOption{grpc.WithTransportCredentials(o.creds)}, o.dial...)
+
+	conn, err := grpc.Dial(address, dialOpts...)
 	if err != nil {
 		return nil, err
 	}
 
 	return &Client{
-		c: NewUploadClient(conn),
+		c:         NewUploadClient(conn),
+		p:         NewPortsClient(conn),
+		chunkSize: chunkSize,
+		retries:   o.retries,
+		source:    o.source,
+		conflict:  o.conflict,
 	}, nil
 }
 
@@ -36,7 +112,7 @@ func (c *Client) Upload(ctx context.Context, r io.Reader) error {
 		return err
 	}
 
-	buf := make([]byte, defaultSize)
+	buf := make([]byte, c.chunkSize)
 	for {
 		n, err := r.Read(buf)
 		if errors.Is(err, io.EOF) {
@@ -47,8 +123,13 @@ func (c *Client) Upload(ctx context.Context, r io.Reader) error {
 		}
 
 		if err := stream.Send(&Request{
-			Chunk: buf[:n],
-		}); err != nil {
+			Chunk:    buf[:n],
+			Source:   c.source,
+			Conflict: string(c.conflict),
+		}); errors.Is(err, io.EOF) {
+			// The server ended the call, its status is returned by CloseAndRecv.
+			break
+		} else if err != nil {
 			return err
 		}
 	}
@@ -58,7 +139,38 @@ func (c *Client) Upload(ctx context.Context, r io.Reader) error {
 		return err
 	}
 
-	log.Info().Msgf("Server response: %s", res.Result)
+	header, _ := stream.Header()
+	logResponse(res, header)
 
 	return nil
 }
+
+func logResponse(res *Response, header metadata.MD) {
+	log.Info().
+		Str("import", res.Id).
+		Bool("replayed", first(header.Get(replayedKey)) == "true").
+		Interface("report", res.Report).
+		Msgf("Server response: %s", res.Result)
+}
+
+// WatchImport calls fn with every progress event of an import until its summary.
+func (c *Client) WatchImport(ctx context.Context, id string, fn func(*ImportEvent) error) error {
+	stream, err := c.c.WatchImport(ctx, &WatchImportRequest{Id: id})
+	if err != nil {
+		return err
+	}
+
+	for {
+		e, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/export"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Export streams the stored ports matching the request filter, encoded in the requested format.
+func (s *Server) Export(req *ExportRequest, stream Ports_ExportServer) error {
+	format, err := export.ParseFormat(req.Format)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	filter := database.Filter{
+		Keys:    req.Keys,
+		Country: req.Country,
+		City:    req.City,
+	}
+	if filter.BBox, err = bbox(req.BBox); err != nil {
+		return err
+	}
+	if req.AsOf != "" {
+		if filter.AsOf, err = database.ParseAsOf(req.AsOf); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	w := bufio.NewWriterSize(chunkWriter{stream}, s.cfg.ChunkSize)
+
+	enc, err := export.NewEncoder(format, w)
+	if err != nil {
+		return err
+	}
+
+	if err := s.service.Export(stream.Context(), filter, enc); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// bbox converts a request bounding box, nil when not given, failing with codes.InvalidArgument when invalid.
+func bbox(b *BBox) (*database.BBox, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	out := &database.BBox{MinX: b.MinX, MinY: b.MinY, MaxX: b.MaxX, MaxY: b.MaxY}
+	if err := out.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid bounding box: %v", err)
+	}
+
+	return out, nil
+}
+
+// chunkWriter sends every write as an ExportResponse chunk.
+type chunkWriter struct {
+	stream Ports_ExportServer
+}
+
+func (w chunkWriter) Write(p []byte) (int, error) {
+	if err := w.stream.Send(&ExportResponse{Chunk: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Export writes the server ports matching the request to w.
+func (c *Client) Export(ctx context.Context, req *ExportRequest, w io.Writer) error {
+	stream, err := c.p.Export(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		res, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.Write(res.Chunk); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/agukrapo/ports/limits"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_Export_bbox(t *testing.T) {
+	ctx := context.Background()
+	_, c, _ := newTestServer(t, limits.Limits{})
+
+	var buf bytes.Buffer
+	err := c.Export(ctx, &ExportRequest{Format: "ndjson", BBox: &BBox{MinX: 55, MinY: 25, MaxX: 54, MaxY: 24}}, &buf)
+	require.Equal(t, codes.InvalidArgument, status.Code(err), err)
+
+	require.NoError(t, c.Export(ctx, &ExportRequest{Format: "ndjson", BBox: &BBox{MinX: 54, MinY: 24, MaxX: 55, MaxY: 25}}, &buf))
+}
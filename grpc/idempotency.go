@@ -0,0 +1,55 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/agukrapo/ports/auth"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/idempotency"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	idempotencyKey = "idempotency-key"
+	replayedKey    = "idempotent-replayed"
+)
+
+// claim registers an upload submission by its idempotency-key metadata, or by its payload checksum,
+// scoped by source and conflict policy. A duplicate returns the original Response, sending its import ID
+// and the idempotent-replayed flag as header metadata.
+func (s *Server) claim(ctx context.Context, sendHeader func(metadata.MD) error, checksum, source, conflict string) (*idempotency.Claim, *Response, error) {
+	var subject string
+	if p, ok := auth.FromContext(ctx); ok {
+		subject = p.Subject
+	}
+	if source != "" && source != database.DefaultSource {
+		subject += "@" + source
+	}
+	if conflict != "" && database.Conflict(conflict) != database.ConflictOverwrite {
+		subject += "#" + conflict
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	key := idempotency.Key(subject, first(md.Get(idempotencyKey)), checksum)
+
+	claim, res, err := s.idempotency.Claim(ctx, key, checksum)
+	switch {
+	case errors.Is(err, idempotency.ErrInProgress):
+		_ = sendHeader(metadata.Pairs(importIDKey, res.ImportID))
+		return nil, nil, status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, idempotency.ErrMismatch):
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, nil, err
+	case res != nil:
+		if err := sendHeader(metadata.Pairs(importIDKey, res.ImportID, replayedKey, "true")); err != nil {
+			return nil, nil, err
+		}
+		return nil, &Response{Result: "ok", Id: res.ImportID, Report: reportMessage(res.Report)}, nil
+	}
+
+	return claim, nil, nil
+}
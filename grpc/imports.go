@@ -0,0 +1,77 @@
+package grpc
+
+import (
+	"github.com/agukrapo/ports/imports"
+	"github.com/agukrapo/ports/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// importIDKey is the header metadata key carrying the ID of the import started by an Upload call.
+const importIDKey = "import-id"
+
+// WatchImport streams an import progress until its summary event.
+func (s *Server) WatchImport(req *WatchImportRequest, stream Upload_WatchImportServer) error {
+	imp, ok := s.imports.Get(req.Id)
+	if !ok {
+		return status.Errorf(codes.NotFound, "import %s not found", req.Id)
+	}
+
+	events, unsubscribe := imp.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			if err := stream.Send(eventMessage(e)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-s.done:
+			return status.Error(codes.Unavailable, "server shutting down")
+		}
+	}
+}
+
+func eventMessage(e imports.Event) *ImportEvent {
+	switch {
+	case e.Progress != nil:
+		return &ImportEvent{Event: &ImportEvent_Progress{Progress: &Progress{
+			Processed: int64(e.Progress.Processed),
+			Rejected:  int64(e.Progress.Rejected),
+			Rate:      e.Progress.Rate,
+			EtaMillis: e.Progress.ETA.Milliseconds(),
+		}}}
+	case e.Reject != nil:
+		return &ImportEvent{Event: &ImportEvent_Reject{Reject: &Rejection{
+			Key:   e.Reject.Key,
+			Error: e.Reject.Error,
+		}}}
+	case e.Summary != nil:
+		return &ImportEvent{Event: &ImportEvent_Summary{Summary: &Summary{
+			Status: string(e.Summary.Status),
+			Report: reportMessage(e.Summary.Report),
+			Error:  e.Summary.Error,
+		}}}
+	}
+
+	return &ImportEvent{}
+}
+
+func reportMessage(r service.Report) *Report {
+	return &Report{
+		Processed:      int64(r.Processed),
+		Upserted:       int64(r.Upserted),
+		Rejected:       int64(r.Rejected),
+		Skipped:        int64(r.Skipped),
+		Retries:        int64(r.Retries),
+		DurationMillis: r.Duration.Milliseconds(),
+		Source:         r.Source,
+		Conflict:       string(r.Conflict),
+	}
+}
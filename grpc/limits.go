@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"context"
+	"net"
+
+	"github.com/agukrapo/ports/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func (s *Server) unaryThrottle(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := s.throttle(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (s *Server) streamThrottle(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.throttle(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+
+	return handler(srv, ss)
+}
+
+// throttle rejects the calls of the clients exceeding their rate limit,
+// clients are identified by their authenticated subject, or by their IP when anonymous.
+func (s *Server) throttle(ctx context.Context, method string) error {
+	if service, _ := split(method); public[service] {
+		return nil
+	}
+
+	var client string
+	if p, ok := auth.FromContext(ctx); ok && p.Subject != auth.Anonymous.Subject {
+		client = "subject " + p.Subject
+	} else if p, ok := peer.FromContext(ctx); ok {
+		client = "ip " + host(p.Addr)
+	}
+
+	if !s.limits.Clients.Allow(client) {
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+	}
+
+	return nil
+}
+
+func host(addr net.Addr) string {
+	h, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return h
+}
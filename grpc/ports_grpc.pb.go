@@ -0,0 +1,197 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.19.4
+// source: grpc/ports.proto
+
+package grpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// PortsClient is the client API for Ports service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PortsClient interface {
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (Ports_ExportClient, error)
+	// WatchPorts streams the port changes matching the request filter as they happen, from the database change log.
+	WatchPorts(ctx context.Context, in *WatchPortsRequest, opts ...grpc.CallOption) (Ports_WatchPortsClient, error)
+}
+
+type portsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPortsClient(cc grpc.ClientConnInterface) PortsClient {
+	return &portsClient{cc}
+}
+
+func (c *portsClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (Ports_ExportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Ports_ServiceDesc.Streams[0], "/grpc.Ports/Export", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &portsExportClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Ports_ExportClient interface {
+	Recv() (*ExportResponse, error)
+	grpc.ClientStream
+}
+
+type portsExportClient struct {
+	grpc.ClientStream
+}
+
+func (x *portsExportClient) Recv() (*ExportResponse, error) {
+	m := new(ExportResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *portsClient) WatchPorts(ctx context.Context, in *WatchPortsRequest, opts ...grpc.CallOption) (Ports_WatchPortsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Ports_ServiceDesc.Streams[1], "/grpc.Ports/WatchPorts", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &portsWatchPortsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Ports_WatchPortsClient interface {
+	Recv() (*PortChange, error)
+	grpc.ClientStream
+}
+
+type portsWatchPortsClient struct {
+	grpc.ClientStream
+}
+
+func (x *portsWatchPortsClient) Recv() (*PortChange, error) {
+	m := new(PortChange)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// PortsServer is the server API for Ports service.
+// All implementations must embed UnimplementedPortsServer
+// for forward compatibility
+type PortsServer interface {
+	Export(*ExportRequest, Ports_ExportServer) error
+	// WatchPorts streams the port changes matching the request filter as they happen, from the database change log.
+	WatchPorts(*WatchPortsRequest, Ports_WatchPortsServer) error
+	mustEmbedUnimplementedPortsServer()
+}
+
+// UnimplementedPortsServer must be embedded to have forward compatible implementations.
+type UnimplementedPortsServer struct {
+}
+
+func (UnimplementedPortsServer) Export(*ExportRequest, Ports_ExportServer) error {
+	return status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedPortsServer) WatchPorts(*WatchPortsRequest, Ports_WatchPortsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPorts not implemented")
+}
+func (UnimplementedPortsServer) mustEmbedUnimplementedPortsServer() {}
+
+// UnsafePortsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PortsServer will
+// result in compilation errors.
+type UnsafePortsServer interface {
+	mustEmbedUnimplementedPortsServer()
+}
+
+func RegisterPortsServer(s grpc.ServiceRegistrar, srv PortsServer) {
+	s.RegisterService(&Ports_ServiceDesc, srv)
+}
+
+func _Ports_Export_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ExportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PortsServer).Export(m, &portsExportServer{stream})
+}
+
+type Ports_ExportServer interface {
+	Send(*ExportResponse) error
+	grpc.ServerStream
+}
+
+type portsExportServer struct {
+	grpc.ServerStream
+}
+
+func (x *portsExportServer) Send(m *ExportResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Ports_WatchPorts_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPortsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PortsServer).WatchPorts(m, &portsWatchPortsServer{stream})
+}
+
+type Ports_WatchPortsServer interface {
+	Send(*PortChange) error
+	grpc.ServerStream
+}
+
+type portsWatchPortsServer struct {
+	grpc.ServerStream
+}
+
+func (x *portsWatchPortsServer) Send(m *PortChange) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Ports_ServiceDesc is the grpc.ServiceDesc for Ports service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Ports_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "grpc.Ports",
+	HandlerType: (*PortsServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Export",
+			Handler:       _Ports_Export_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchPorts",
+			Handler:       _Ports_WatchPorts_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "grpc/ports.proto",
+}
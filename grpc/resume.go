@@ -0,0 +1,161 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"hash/crc32"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	resumeBackoff    = 500 * time.Millisecond
+	resumeMaxBackoff = 10 * time.Second
+)
+
+// UploadFile sends a seekable reader of a known size through a resumable upload session,
+// resuming from the committed offset when the transfer is interrupted.
+func (c *Client) UploadFile(ctx context.Context, r io.ReadSeeker, size int64) error {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	digest := h.Sum(nil)
+
+	session, err := c.c.CreateUpload(ctx, &CreateUploadRequest{Size: size})
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := c.sendChunks(ctx, r, session.Id, session.Offset)
+		if err == nil {
+			break
+		}
+
+		if attempt >= c.retries || !resumable(err) {
+			return err
+		}
+
+		log.Warn().Err(err).Int("attempt", attempt+1).Msg("Upload interrupted, resuming")
+
+		if session, err = c.resume(ctx, session.Id, attempt); err != nil {
+			return err
+		}
+	}
+
+	res, header, err := c.finalize(ctx, &FinalizeUploadRequest{Id: session.Id, Sha256: digest, Source: c.source, Conflict: string(c.conflict)})
+	if err != nil {
+		return err
+	}
+
+	logResponse(res, header)
+
+	return nil
+}
+
+// finalize imports a complete upload session, retrying while the server is unavailable or busy,
+// which keeps the session.
+func (c *Client) finalize(ctx context.Context, req *FinalizeUploadRequest) (*Response, metadata.MD, error) {
+	for attempt := 0; ; attempt++ {
+		var header metadata.MD
+		res, err := c.c.FinalizeUpload(ctx, req, grpc.Header(&header))
+		if err == nil {
+			return res, header, nil
+		}
+
+		if code := status.Code(err); attempt >= c.retries || code != codes.Unavailable && code != codes.ResourceExhausted {
+			return nil, nil, err
+		}
+
+		log.Warn().Err(err).Int("attempt", attempt+1).Msg("Upload finalization failed, retrying")
+
+		if err := sleep(ctx, attempt); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// resume waits for a backoff delay, then queries the session committed offset.
+func (c *Client) resume(ctx context.Context, id string, attempt int) (*UploadSession, error) {
+	if err := sleep(ctx, attempt); err != nil {
+		return nil, err
+	}
+
+	return c.c.GetUpload(ctx, &GetUploadRequest{Id: id})
+}
+
+// sleep waits for the exponential backoff delay of a retry attempt, or until the context is done.
+func sleep(ctx context.Context, attempt int) error {
+	backoff := resumeBackoff << attempt
+	if backoff > resumeMaxBackoff || backoff <= 0 {
+		backoff = resumeMaxBackoff
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendChunks streams the reader content from an offset to its end.
+func (c *Client) sendChunks(ctx context.Context, r io.ReadSeeker, id string, offset int64) error {
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	stream, err := c.c.UploadChunks(ctx)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, c.chunkSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if serr := stream.Send(&Chunk{
+				Id:     id,
+				Offset: offset,
+				Data:   buf[:n],
+				Crc32C: crc32.Checksum(buf[:n], castagnoli),
+			}); errors.Is(serr, io.EOF) {
+				// The server ended the call, its status is returned by CloseAndRecv.
+				break
+			} else if serr != nil {
+				return serr
+			}
+			offset += int64(n)
+		}
+
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+// resumable tells whether an upload error may succeed when resumed from the committed offset.
+func resumable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Aborted, codes.FailedPrecondition, codes.ResourceExhausted, codes.DataLoss:
+		return true
+	}
+	return false
+}
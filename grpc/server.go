@@ -2,45 +2,114 @@
 package grpc
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"fmt"
 	"io"
 	"net"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/agukrapo/ports/auth"
+	"github.com/agukrapo/ports/cdc"
+	"github.com/agukrapo/ports/certs"
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/idempotency"
+	"github.com/agukrapo/ports/imports"
+	"github.com/agukrapo/ports/limits"
 	"github.com/agukrapo/ports/parser"
 	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/uploads"
+	"github.com/agukrapo/ports/webhooks"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+const (
+	checkInterval = 5 * time.Second
+	checkTimeout  = 2 * time.Second
+)
+
+type checker interface {
+	Check(context.Context) error
+}
+
 // Server represents am upload gRPC server.
 type Server struct {
 	UnimplementedUploadServer
+	UnimplementedPortsServer
 
 	s       *grpc.Server
-	address string
+	health  *health.Server
+	cfg     config.GRPC
 	service *service.Service
+	checker checker
+	auth    *auth.Authenticator
+	limits  limits.Limits
+	uploads *uploads.Store
+	imports *imports.Registry
+	// idempotency deduplicates the upload submissions.
+	idempotency *idempotency.Guard
+	// changes follows the change log once for all the WatchPorts streams.
+	changes *cdc.Hub
+	done    chan struct{}
 }
 
-// NewServer instantiates a new Server.
-func NewServer(port string, service *service.Service) *Server {
-	s := grpc.NewServer()
+// NewServer instantiates a new Server, serving TLS when a certificate is configured.
+func NewServer(cfg config.GRPC, service *service.Service, checker checker, authenticator *auth.Authenticator, limits limits.Limits, uploads *uploads.Store, guard *idempotency.Guard, hooks *webhooks.Registry) (*Server, error) {
 	out := &Server{
-		s:       s,
-		address: fmt.Sprintf(":%s", port),
-		service: service,
+		health:      health.NewServer(),
+		cfg:         cfg,
+		service:     service,
+		checker:     checker,
+		auth:        authenticator,
+		limits:      limits,
+		uploads:     uploads,
+		imports:     imports.NewRegistry(hooks.ImportFinished),
+		idempotency: guard,
+		changes:     cdc.NewHub(service),
+		done:        make(chan struct{}),
+	}
+
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(out.unaryAuth, out.unaryThrottle),
+		grpc.ChainStreamInterceptor(out.streamAuth, out.streamThrottle),
 	}
 
+	if cfg.TLS.Enabled() {
+		tlsCfg, err := certs.Server(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+
+	s := grpc.NewServer(opts...)
+	out.s = s
+
 	RegisterUploadServer(s, out)
+	RegisterPortsServer(s, out)
+	healthpb.RegisterHealthServer(s, out.health)
+
+	out.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
 
-	return out
+	return out, nil
 }
 
 // Start starts a Server.
 func (s *Server) Start() {
+	go s.watch()
+
 	go func() {
 		if err := s.start(); err != nil {
 			log.Error().Err(err).Msg("GRPC server start failed")
@@ -48,11 +117,11 @@ func (s *Server) Start() {
 			_ = syscall.Kill(syscall.Getpid(), syscall.SIGINT)
 		}
 	}()
-	log.Info().Msgf("GRPC server started at %s", s.address)
+	log.Info().Bool("tls", s.cfg.TLS.Enabled()).Msgf("GRPC server started at %s", s.cfg.Address)
 }
 
 func (s *Server) start() error {
-	lis, err := net.Listen("tcp", s.address)
+	lis, err := net.Listen("tcp", s.cfg.Address)
 	if err != nil {
 		return err
 	}
@@ -70,44 +139,213 @@ func (s *Server) Listen() {
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 
-	s.s.GracefulStop()
+	close(s.done)
+	s.health.Shutdown()
+	s.stop()
 
 	log.Info().Msg("GRPC server closed")
 }
 
+// stop gracefully stops the gRPC server, forcing it after the shutdown timeout.
+func (s *Server) stop() {
+	stopped := make(chan struct{})
+	go func() {
+		s.s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(s.cfg.ShutdownTimeout):
+		log.Error().Msg("GRPC server graceful shutdown timed out")
+		s.s.Stop()
+	}
+}
+
+// watch periodically updates the served health status according to the checker until the Server stops.
+func (s *Server) watch() {
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		s.check()
+
+		select {
+		case <-ticker.C:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Server) check() {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	if err := s.checker.Check(ctx); err != nil {
+		log.Error().Err(err).Msg("GRPC server health check failed")
+		s.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+		return
+	}
+
+	s.setStatus(healthpb.HealthCheckResponse_SERVING)
+}
+
+func (s *Server) setStatus(status healthpb.HealthCheckResponse_ServingStatus) {
+	s.health.SetServingStatus("", status)
+	s.health.SetServingStatus(Upload_ServiceDesc.ServiceName, status)
+	s.health.SetServingStatus(Ports_ServiceDesc.ServiceName, status)
+}
+
 func (s *Server) Upload(stream Upload_UploadServer) error {
-	tmp, err := os.CreateTemp(os.TempDir(), "upload")
+	tmp, size, sum, head, err := spool(stream)
 	if err != nil {
 		return err
 	}
-	defer safeClose(tmp)
+	defer discard(tmp)
 
+	opts, err := importOptions(head.Source, head.Conflict)
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+
+	claim, dup, err := s.claim(ctx, stream.SendHeader, sum, head.Source, head.Conflict)
+	if err != nil {
+		return err
+	}
+	if dup != nil {
+		return stream.SendAndClose(dup)
+	}
+
+	imp, release, err := s.startImport(ctx, stream.SendHeader)
+	if err != nil {
+		claim.Release()
+		return err
+	}
+	defer release()
+	claim.Start(imp.ID)
+
+	report, err := s.process(ctx, tmp, size, imp, opts...)
+	imp.Finish(report, err)
+	if err != nil {
+		claim.Release()
+		return err
+	}
+	claim.Finish("", report, nil)
+
+	return stream.SendAndClose(&Response{Result: "ok", Id: imp.ID, Report: reportMessage(report)})
+}
+
+// startImport registers an import once a concurrent import slot is available, sending its ID as header metadata.
+// The returned function releases the slot.
+func (s *Server) startImport(ctx context.Context, sendHeader func(metadata.MD) error) (*imports.Import, func(), error) {
+	release, err := s.limits.Imports.Acquire(ctx)
+	if errors.Is(err, limits.ErrBusy) {
+		return nil, nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	imp := s.imports.Start("grpc")
+	if err := sendHeader(metadata.Pairs(importIDKey, imp.ID)); err != nil {
+		imp.Finish(service.Report{}, err)
+		release()
+		return nil, nil, err
+	}
+
+	return imp, release, nil
+}
+
+// spool stores the uploaded chunks in a temporary file, returning its size, hex encoded SHA-256
+// and the first message, carrying the import options.
+func spool(stream Upload_UploadServer) (*os.File, int64, string, *Request, error) {
+	tmp, err := os.CreateTemp(os.TempDir(), "upload")
+	if err != nil {
+		return nil, 0, "", nil, err
+	}
+
+	fail := func(err error) (*os.File, int64, string, *Request, error) {
+		discard(tmp)
+		return nil, 0, "", nil, err
+	}
+
+	h := sha256.New()
+	w := io.MultiWriter(tmp, h)
+
+	var (
+		size int64
+		head *Request
+	)
 	for {
 		req, err := stream.Recv()
 		if errors.Is(err, io.EOF) {
 			break
 		}
 		if err != nil {
-			return err
+			return fail(err)
+		}
+
+		if head == nil {
+			head = req
 		}
 
-		if _, err := tmp.Write(req.Chunk); err != nil {
-			return err
+		n, err := w.Write(req.Chunk)
+		if err != nil {
+			return fail(err)
 		}
+		size += int64(n)
 	}
 
 	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
-		return err
+		return fail(err)
+	}
+
+	if head == nil {
+		head = &Request{}
+	}
+
+	return tmp, size, hex.EncodeToString(h.Sum(nil)), head, nil
+}
+
+// importOptions validates the source and conflict policy of an upload.
+func importOptions(source, conflict string) ([]service.Option, error) {
+	if err := service.ValidateSource(source); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	p, err := parser.New(tmp)
+	c, err := database.ParseConflict(conflict)
 	if err != nil {
-		return err
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	s.service.Process(stream.Context(), p)
+	return []service.Option{service.WithSource(source), service.WithConflict(c)}, nil
+}
+
+// process imports a JSON document of a known size, failing when the context is done before the import finishes.
+func (s *Server) process(ctx context.Context, r io.Reader, size int64, imp *imports.Import, opts ...service.Option) (service.Report, error) {
+	p, err := parser.New(r)
+	if err != nil {
+		return service.Report{}, status.Error(codes.InvalidArgument, err.Error())
+	}
 
-	return stream.SendAndClose(&Response{Result: "ok"})
+	opts = append(opts, service.WithHooks(imp.Hooks()), service.WithImportID(imp.ID), service.WithSize(size))
+	report := s.service.Process(ctx, p, opts...)
+	if err := ctx.Err(); err != nil {
+		return report, status.FromContextError(err).Err()
+	}
+
+	return report, nil
+}
+
+// discard closes and removes a temporary file.
+func discard(f *os.File) {
+	safeClose(f)
+	if err := os.Remove(f.Name()); err != nil {
+		log.Error().Err(err).Msg("Temporary file removal failed")
+	}
 }
 
 func safeClose(c io.Closer) {
@@ -0,0 +1,149 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	"github.com/agukrapo/ports/uploads"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// CreateUpload starts a resumable upload session.
+func (s *Server) CreateUpload(_ context.Context, req *CreateUploadRequest) (*UploadSession, error) {
+	session, err := s.uploads.Create(req.Size, "")
+	if err != nil {
+		return nil, sessionError(err)
+	}
+
+	return sessionMessage(session), nil
+}
+
+// UploadChunks appends chunks to upload sessions, each at the session committed offset.
+// The chunks received before an error stay committed.
+func (s *Server) UploadChunks(stream Upload_UploadChunksServer) error {
+	var last uploads.Session
+
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if crc32.Checksum(chunk.Data, castagnoli) != chunk.Crc32C {
+			return status.Errorf(codes.DataLoss, "chunk at offset %d: checksum mismatch", chunk.Offset)
+		}
+
+		if last, err = s.uploads.Append(chunk.Id, chunk.Offset, bytes.NewReader(chunk.Data)); err != nil {
+			return sessionError(err)
+		}
+	}
+
+	return stream.SendAndClose(sessionMessage(last))
+}
+
+// GetUpload returns an upload session state, its offset tells where to resume from.
+func (s *Server) GetUpload(_ context.Context, req *GetUploadRequest) (*UploadSession, error) {
+	session, err := s.uploads.Get(req.Id)
+	if err != nil {
+		return nil, sessionError(err)
+	}
+
+	return sessionMessage(session), nil
+}
+
+// FinalizeUpload checks a complete upload session digest, then imports it and deletes the session.
+// The session is kept when the import does not run, fails or is canceled, so that finalizing it can be retried.
+func (s *Server) FinalizeUpload(ctx context.Context, req *FinalizeUploadRequest) (*Response, error) {
+	opts, err := importOptions(req.Source, req.Conflict)
+	if err != nil {
+		return nil, err
+	}
+
+	f, done, err := s.uploads.Open(req.Id)
+	if err != nil {
+		return nil, sessionError(err)
+	}
+
+	remove := false
+	defer func() { done(remove) }()
+
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(h.Sum(nil), req.Sha256) {
+		remove = true
+		return nil, status.Error(codes.DataLoss, "upload SHA-256 mismatch, the session is discarded")
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	sendHeader := func(md metadata.MD) error { return grpc.SendHeader(ctx, md) }
+
+	claim, dup, err := s.claim(ctx, sendHeader, hex.EncodeToString(req.Sha256), req.Source, req.Conflict)
+	if err != nil {
+		return nil, err
+	}
+	if dup != nil {
+		remove = true
+		return dup, nil
+	}
+
+	imp, release, err := s.startImport(ctx, sendHeader)
+	if err != nil {
+		claim.Release()
+		return nil, err
+	}
+	defer release()
+	claim.Start(imp.ID)
+
+	report, err := s.process(ctx, f, size, imp, opts...)
+	imp.Finish(report, err)
+	if err != nil {
+		claim.Release()
+		return nil, err
+	}
+	claim.Finish("", report, nil)
+	remove = true
+
+	return &Response{Result: "ok", Id: imp.ID, Report: reportMessage(report)}, nil
+}
+
+func sessionMessage(s uploads.Session) *UploadSession {
+	return &UploadSession{
+		Id:            s.ID,
+		Size:          s.Size,
+		Offset:        s.Offset,
+		ExpiresAtUnix: s.ExpiresAt.Unix(),
+	}
+}
+
+func sessionError(err error) error {
+	switch {
+	case errors.Is(err, uploads.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, uploads.ErrOffset), errors.Is(err, uploads.ErrIncomplete):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, uploads.ErrLocked):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, uploads.ErrTooLarge):
+		return status.Error(codes.OutOfRange, err.Error())
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
@@ -0,0 +1,282 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/idempotency"
+	"github.com/agukrapo/ports/imports"
+	"github.com/agukrapo/ports/limits"
+	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/storage/memory"
+	"github.com/agukrapo/ports/uploads"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const testInput = `{"AEAJM": {"name": "Ajman", "coordinates": [55.5, 25.4]}, "AEAUH": {"name": "Abu Dhabi", "coordinates": [54.37, 24.47]}}`
+
+// newTestServer serves a Server deduplicating uploads over an in-memory connection, returning it along with a Client and its storage.
+func newTestServer(t *testing.T, lim limits.Limits) (*Server, *Client, *memory.Memory) {
+	store := memory.New()
+
+	sessions, err := uploads.NewStore(config.Uploads{Dir: t.TempDir(), SessionTTL: time.Hour, MaxSize: 1 << 20})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, sessions.Close()) })
+
+	s, err := NewServer(config.GRPC{}, service.New(store), store, nil, lim, sessions, idempotency.New(store, time.Hour, time.Minute), nil)
+	require.NoError(t, err)
+
+	return s, serve(t, s), store
+}
+
+// serve serves a Server over an in-memory connection, returning a Client of it.
+func serve(t *testing.T, s *Server) *Client {
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = s.s.Serve(lis) }()
+	t.Cleanup(s.s.Stop)
+
+	dialer := func(o *clientOptions) {
+		o.dial = append(o.dial, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	}
+
+	c, err := NewClient("bufnet", 16, dialer, WithRetries(2))
+	require.NoError(t, err)
+
+	return c
+}
+
+// createUpload uploads the whole input in a session, returning its ID.
+func createUpload(t *testing.T, c *Client, input string) string {
+	ctx := context.Background()
+
+	session, err := c.c.CreateUpload(ctx, &CreateUploadRequest{Size: int64(len(input))})
+	require.NoError(t, err)
+
+	require.NoError(t, c.sendChunks(ctx, strings.NewReader(input), session.Id, 0))
+
+	return session.Id
+}
+
+func digest(input string) []byte {
+	sum := sha256.Sum256([]byte(input))
+	return sum[:]
+}
+
+func TestServer_FinalizeUpload_busy(t *testing.T) {
+	ctx := context.Background()
+
+	s, c, store := newTestServer(t, limits.Limits{Imports: limits.NewImports(1, 0)})
+	id := createUpload(t, c, testInput)
+
+	release, err := s.limits.Imports.Acquire(ctx)
+	require.NoError(t, err)
+
+	_, err = c.c.FinalizeUpload(ctx, &FinalizeUploadRequest{Id: id, Sha256: digest(testInput)})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	session, err := c.c.GetUpload(ctx, &GetUploadRequest{Id: id})
+	require.NoError(t, err)
+	require.Equal(t, int64(len(testInput)), session.Offset)
+
+	release()
+
+	res, err := c.c.FinalizeUpload(ctx, &FinalizeUploadRequest{Id: id, Sha256: digest(testInput)})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), res.Report.Upserted)
+
+	_, err = c.c.GetUpload(ctx, &GetUploadRequest{Id: id})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	port, err := store.Get(ctx, "AEAJM")
+	require.NoError(t, err)
+	require.Equal(t, "Ajman", port.Name)
+}
+
+func TestServer_FinalizeUpload_invalid(t *testing.T) {
+	ctx := context.Background()
+
+	_, c, _ := newTestServer(t, limits.Limits{})
+	id := createUpload(t, c, "not json")
+
+	_, err := c.c.FinalizeUpload(ctx, &FinalizeUploadRequest{Id: id, Sha256: digest("not json")})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = c.c.GetUpload(ctx, &GetUploadRequest{Id: id})
+	require.NoError(t, err)
+
+	_, err = c.c.FinalizeUpload(ctx, &FinalizeUploadRequest{Id: id, Sha256: digest("other")})
+	require.Equal(t, codes.DataLoss, status.Code(err))
+
+	_, err = c.c.GetUpload(ctx, &GetUploadRequest{Id: id})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+// flakyClient fails the first UploadChunks stream after its first chunk, and the first FinalizeUpload call.
+type flakyClient struct {
+	UploadClient
+	chunksFailed, finalizeFailed bool
+}
+
+func (f *flakyClient) UploadChunks(ctx context.Context, opts ...grpc.CallOption) (Upload_UploadChunksClient, error) {
+	stream, err := f.UploadClient.UploadChunks(ctx, opts...)
+	if err != nil || f.chunksFailed {
+		return stream, err
+	}
+
+	f.chunksFailed = true
+	return &droppedStream{Upload_UploadChunksClient: stream}, nil
+}
+
+func (f *flakyClient) FinalizeUpload(ctx context.Context, in *FinalizeUploadRequest, opts ...grpc.CallOption) (*Response, error) {
+	if !f.finalizeFailed {
+		f.finalizeFailed = true
+		return nil, status.Error(codes.Unavailable, "connection dropped")
+	}
+
+	return f.UploadClient.FinalizeUpload(ctx, in, opts...)
+}
+
+// droppedStream commits its first chunk, then fails as a dropped connection.
+type droppedStream struct {
+	Upload_UploadChunksClient
+	sent bool
+}
+
+func (d *droppedStream) Send(c *Chunk) error {
+	if d.sent {
+		return status.Error(codes.Unavailable, "connection dropped")
+	}
+
+	d.sent = true
+	if err := d.Upload_UploadChunksClient.Send(c); err != nil {
+		return err
+	}
+	_, err := d.Upload_UploadChunksClient.CloseAndRecv()
+	return err
+}
+
+func TestClient_UploadFile_resume(t *testing.T) {
+	ctx := context.Background()
+
+	_, c, store := newTestServer(t, limits.Limits{})
+	flaky := &flakyClient{UploadClient: c.c}
+	c.c = flaky
+
+	require.NoError(t, c.UploadFile(ctx, strings.NewReader(testInput), int64(len(testInput))))
+	require.True(t, flaky.chunksFailed)
+	require.True(t, flaky.finalizeFailed)
+
+	for key, name := range map[string]string{"AEAJM": "Ajman", "AEAUH": "Abu Dhabi"} {
+		port, err := store.Get(ctx, key)
+		require.NoError(t, err)
+		require.Equal(t, name, port.Name)
+	}
+}
+
+func TestServer_FinalizeUpload_scope(t *testing.T) {
+	ctx := context.Background()
+	_, c, _ := newTestServer(t, limits.Limits{})
+
+	finalize := func(source, conflict string) *Response {
+		res, err := c.c.FinalizeUpload(ctx, &FinalizeUploadRequest{Id: createUpload(t, c, testInput), Sha256: digest(testInput), Source: source, Conflict: conflict})
+		require.NoError(t, err)
+		return res
+	}
+
+	first := finalize("", "")
+	require.Equal(t, first.Id, finalize("", "overwrite").Id, "the same payload is a duplicate")
+	require.NotEqual(t, first.Id, finalize("geo", "").Id, "the same payload from another source is imported")
+
+	filled := finalize("", "fill-empty")
+	require.NotEqual(t, first.Id, filled.Id, "the same payload with another conflict policy is imported")
+	require.Equal(t, filled.Id, finalize("", "fill-empty").Id)
+}
+
+// blockingStore represents a storage whose merges wait for the import to be canceled.
+type blockingStore struct {
+	*memory.Memory
+}
+
+func (blockingStore) Merge(ctx context.Context, _ *database.Port, _ database.MergeOptions) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// newBlockingServer serves a Server whose imports run until canceled, returning it along with a Client.
+func newBlockingServer(t *testing.T) (*Server, *Client) {
+	store := blockingStore{memory.New()}
+
+	sessions, err := uploads.NewStore(config.Uploads{Dir: t.TempDir(), SessionTTL: time.Hour, MaxSize: 1 << 20})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, sessions.Close()) })
+
+	s, err := NewServer(config.GRPC{}, service.New(store), store, nil, limits.Limits{}, sessions, idempotency.New(store, time.Hour, time.Minute), nil)
+	require.NoError(t, err)
+
+	return s, serve(t, s)
+}
+
+func TestServer_FinalizeUpload_canceled(t *testing.T) {
+	s, c := newBlockingServer(t)
+
+	id := createUpload(t, c, testInput)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var header metadata.MD
+	_, err := c.c.FinalizeUpload(ctx, &FinalizeUploadRequest{Id: id, Sha256: digest(testInput)}, grpc.Header(&header))
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	imp, ok := s.imports.Get(first(header.Get(importIDKey)))
+	require.True(t, ok)
+	require.Eventually(t, func() bool { return imp.State().Status == imports.Failed }, time.Second, 10*time.Millisecond)
+
+	// The session is kept, and its claim released, for the finalization to be retried.
+	require.Eventually(t, func() bool {
+		_, err := c.c.GetUpload(context.Background(), &GetUploadRequest{Id: id})
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	header = nil
+	_, err = c.c.FinalizeUpload(ctx, &FinalizeUploadRequest{Id: id, Sha256: digest(testInput)}, grpc.Header(&header))
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	require.Empty(t, header.Get(replayedKey), "the canceled import is not replayed")
+	require.NotEqual(t, imp.ID, first(header.Get(importIDKey)))
+}
+
+func TestServer_Upload_canceled(t *testing.T) {
+	s, c := newBlockingServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	stream, err := c.c.Upload(ctx)
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&Request{Chunk: []byte(testInput)}))
+	_, err = stream.CloseAndRecv()
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	header, err := stream.Header()
+	require.NoError(t, err)
+
+	imp, ok := s.imports.Get(first(header.Get(importIDKey)))
+	require.True(t, ok)
+	require.Eventually(t, func() bool { return imp.State().Status == imports.Failed }, time.Second, 10*time.Millisecond, "an aborted import is not completed")
+}
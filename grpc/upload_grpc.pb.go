@@ -23,6 +23,13 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type UploadClient interface {
 	Upload(ctx context.Context, opts ...grpc.CallOption) (Upload_UploadClient, error)
+	WatchImport(ctx context.Context, in *WatchImportRequest, opts ...grpc.CallOption) (Upload_WatchImportClient, error)
+	// Resumable uploads: create a session, send its chunks, query the committed offset to resume after a failure,
+	// then finalize it to start the import.
+	CreateUpload(ctx context.Context, in *CreateUploadRequest, opts ...grpc.CallOption) (*UploadSession, error)
+	UploadChunks(ctx context.Context, opts ...grpc.CallOption) (Upload_UploadChunksClient, error)
+	GetUpload(ctx context.Context, in *GetUploadRequest, opts ...grpc.CallOption) (*UploadSession, error)
+	FinalizeUpload(ctx context.Context, in *FinalizeUploadRequest, opts ...grpc.CallOption) (*Response, error)
 }
 
 type uploadClient struct {
@@ -67,11 +74,111 @@ func (x *uploadUploadClient) CloseAndRecv() (*Response, error) {
 	return m, nil
 }
 
+func (c *uploadClient) WatchImport(ctx context.Context, in *WatchImportRequest, opts ...grpc.CallOption) (Upload_WatchImportClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Upload_ServiceDesc.Streams[1], "/grpc.Upload/WatchImport", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &uploadWatchImportClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Upload_WatchImportClient interface {
+	Recv() (*ImportEvent, error)
+	grpc.ClientStream
+}
+
+type uploadWatchImportClient struct {
+	grpc.ClientStream
+}
+
+func (x *uploadWatchImportClient) Recv() (*ImportEvent, error) {
+	m := new(ImportEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *uploadClient) CreateUpload(ctx context.Context, in *CreateUploadRequest, opts ...grpc.CallOption) (*UploadSession, error) {
+	out := new(UploadSession)
+	err := c.cc.Invoke(ctx, "/grpc.Upload/CreateUpload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uploadClient) UploadChunks(ctx context.Context, opts ...grpc.CallOption) (Upload_UploadChunksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Upload_ServiceDesc.Streams[2], "/grpc.Upload/UploadChunks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &uploadUploadChunksClient{stream}
+	return x, nil
+}
+
+type Upload_UploadChunksClient interface {
+	Send(*Chunk) error
+	CloseAndRecv() (*UploadSession, error)
+	grpc.ClientStream
+}
+
+type uploadUploadChunksClient struct {
+	grpc.ClientStream
+}
+
+func (x *uploadUploadChunksClient) Send(m *Chunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *uploadUploadChunksClient) CloseAndRecv() (*UploadSession, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(UploadSession)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *uploadClient) GetUpload(ctx context.Context, in *GetUploadRequest, opts ...grpc.CallOption) (*UploadSession, error) {
+	out := new(UploadSession)
+	err := c.cc.Invoke(ctx, "/grpc.Upload/GetUpload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *uploadClient) FinalizeUpload(ctx context.Context, in *FinalizeUploadRequest, opts ...grpc.CallOption) (*Response, error) {
+	out := new(Response)
+	err := c.cc.Invoke(ctx, "/grpc.Upload/FinalizeUpload", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // UploadServer is the server API for Upload service.
 // All implementations must embed UnimplementedUploadServer
 // for forward compatibility
 type UploadServer interface {
 	Upload(Upload_UploadServer) error
+	WatchImport(*WatchImportRequest, Upload_WatchImportServer) error
+	// Resumable uploads: create a session, send its chunks, query the committed offset to resume after a failure,
+	// then finalize it to start the import.
+	CreateUpload(context.Context, *CreateUploadRequest) (*UploadSession, error)
+	UploadChunks(Upload_UploadChunksServer) error
+	GetUpload(context.Context, *GetUploadRequest) (*UploadSession, error)
+	FinalizeUpload(context.Context, *FinalizeUploadRequest) (*Response, error)
 	mustEmbedUnimplementedUploadServer()
 }
 
@@ -82,6 +189,21 @@ type UnimplementedUploadServer struct {
 func (UnimplementedUploadServer) Upload(Upload_UploadServer) error {
 	return status.Errorf(codes.Unimplemented, "method Upload not implemented")
 }
+func (UnimplementedUploadServer) WatchImport(*WatchImportRequest, Upload_WatchImportServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchImport not implemented")
+}
+func (UnimplementedUploadServer) CreateUpload(context.Context, *CreateUploadRequest) (*UploadSession, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateUpload not implemented")
+}
+func (UnimplementedUploadServer) UploadChunks(Upload_UploadChunksServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadChunks not implemented")
+}
+func (UnimplementedUploadServer) GetUpload(context.Context, *GetUploadRequest) (*UploadSession, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUpload not implemented")
+}
+func (UnimplementedUploadServer) FinalizeUpload(context.Context, *FinalizeUploadRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinalizeUpload not implemented")
+}
 func (UnimplementedUploadServer) mustEmbedUnimplementedUploadServer() {}
 
 // UnsafeUploadServer may be embedded to opt out of forward compatibility for this service.
@@ -121,19 +243,143 @@ func (x *uploadUploadServer) Recv() (*Request, error) {
 	return m, nil
 }
 
+func _Upload_WatchImport_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchImportRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UploadServer).WatchImport(m, &uploadWatchImportServer{stream})
+}
+
+type Upload_WatchImportServer interface {
+	Send(*ImportEvent) error
+	grpc.ServerStream
+}
+
+type uploadWatchImportServer struct {
+	grpc.ServerStream
+}
+
+func (x *uploadWatchImportServer) Send(m *ImportEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Upload_CreateUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateUploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadServer).CreateUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc.Upload/CreateUpload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadServer).CreateUpload(ctx, req.(*CreateUploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Upload_UploadChunks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(UploadServer).UploadChunks(&uploadUploadChunksServer{stream})
+}
+
+type Upload_UploadChunksServer interface {
+	SendAndClose(*UploadSession) error
+	Recv() (*Chunk, error)
+	grpc.ServerStream
+}
+
+type uploadUploadChunksServer struct {
+	grpc.ServerStream
+}
+
+func (x *uploadUploadChunksServer) SendAndClose(m *UploadSession) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *uploadUploadChunksServer) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Upload_GetUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadServer).GetUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc.Upload/GetUpload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadServer).GetUpload(ctx, req.(*GetUploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Upload_FinalizeUpload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinalizeUploadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(UploadServer).FinalizeUpload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/grpc.Upload/FinalizeUpload",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(UploadServer).FinalizeUpload(ctx, req.(*FinalizeUploadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Upload_ServiceDesc is the grpc.ServiceDesc for Upload service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Upload_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "grpc.Upload",
 	HandlerType: (*UploadServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateUpload",
+			Handler:    _Upload_CreateUpload_Handler,
+		},
+		{
+			MethodName: "GetUpload",
+			Handler:    _Upload_GetUpload_Handler,
+		},
+		{
+			MethodName: "FinalizeUpload",
+			Handler:    _Upload_FinalizeUpload_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Upload",
 			Handler:       _Upload_Upload_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "WatchImport",
+			Handler:       _Upload_WatchImport_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "UploadChunks",
+			Handler:       _Upload_UploadChunks_Handler,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "grpc/upload.proto",
 }
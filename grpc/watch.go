@@ -0,0 +1,146 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/agukrapo/ports/cdc"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/service"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WatchPorts streams the port changes matching the request filter, a change matching when the port does before or after it.
+func (s *Server) WatchPorts(req *WatchPortsRequest, stream Ports_WatchPortsServer) error {
+	filter := database.Filter{
+		Keys:    req.Keys,
+		Country: req.Country,
+	}
+	var err error
+	if filter.BBox, err = bbox(req.BBox); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-s.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err = s.changes.Follow(ctx, req.ResumeToken, func(e *cdc.Event) error {
+		if !matches(filter, e) {
+			return nil
+		}
+		return stream.Send(changeMessage(e))
+	})
+
+	switch {
+	case errors.Is(err, service.ErrNoChangeLog):
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.Is(err, cdc.ErrInvalidToken):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, cdc.ErrExpiredToken):
+		return status.Error(codes.OutOfRange, err.Error())
+	case stream.Context().Err() != nil:
+		return stream.Context().Err()
+	case isClosed(s.done):
+		return status.Error(codes.Unavailable, "server shutting down")
+	default:
+		return err
+	}
+}
+
+func matches(filter database.Filter, e *cdc.Event) bool {
+	return (e.Before != nil && filter.Match(e.Before)) || (e.After != nil && filter.Match(e.After))
+}
+
+func isClosed(ch chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func changeMessage(e *cdc.Event) *PortChange {
+	return &PortChange{
+		Key:           e.Key,
+		Type:          e.Type,
+		Fields:        e.Fields,
+		Before:        portMessage(e.Before),
+		After:         portMessage(e.After),
+		TimeUnixMilli: e.Time.UnixMilli(),
+		ResumeToken:   e.Token(),
+	}
+}
+
+func portMessage(p *database.Port) *Port {
+	if p == nil {
+		return nil
+	}
+
+	return &Port{
+		Key:       p.Key,
+		Code:      p.Code,
+		Name:      p.Name,
+		City:      p.City,
+		Province:  p.Province,
+		Country:   p.Country,
+		Timezone:  p.Timezone,
+		Latitude:  p.Latitude,
+		Longitude: p.Longitude,
+		Unlocs:    p.Unlocs,
+		Alias:     p.Alias,
+	}
+}
+
+// WatchPorts calls fn for every port change matching the request, resuming from the last change received
+// when the stream is interrupted, until the context is done or fn fails.
+func (c *Client) WatchPorts(ctx context.Context, req *WatchPortsRequest, fn func(*PortChange) error) error {
+	for attempt := 0; ; attempt++ {
+		err := c.watchPorts(ctx, req, func(change *PortChange) error {
+			attempt = 0
+			req.ResumeToken = change.ResumeToken
+			return fn(change)
+		})
+		if err == nil || ctx.Err() != nil || attempt >= c.retries || status.Code(err) != codes.Unavailable {
+			return err
+		}
+
+		log.Warn().Err(err).Int("attempt", attempt+1).Msg("Watch interrupted, resuming")
+
+		if err := sleep(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *Client) watchPorts(ctx context.Context, req *WatchPortsRequest, fn func(*PortChange) error) error {
+	stream, err := c.p.WatchPorts(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for {
+		change, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(change); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/idempotency"
+	"github.com/agukrapo/ports/limits"
+	"github.com/agukrapo/ports/service"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newWatchServer serves a Server keeping a change log, returning a Client of it along with its database.
+func newWatchServer(t *testing.T) (*Client, *database.Database) {
+	cfg := config.Default().Database
+	cfg.DSN = "sqlite://" + filepath.Join(t.TempDir(), "ports.db")
+
+	m, err := database.NewMigrator(cfg)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+
+	db, err := database.New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	s, err := NewServer(config.GRPC{}, service.New(db), db, nil, limits.Limits{}, nil, idempotency.New(nil, 0, 0), nil)
+	require.NoError(t, err)
+
+	return serve(t, s), db
+}
+
+var errDone = errors.New("done")
+
+// watch returns the keys of the first n changes watched, sending them through a channel once done.
+func watch(c *Client, req *WatchPortsRequest, n int) (<-chan []string, <-chan error) {
+	keys := make(chan []string, 1)
+	errs := make(chan error, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+	go func() {
+		defer cancel()
+
+		var got []string
+		err := c.WatchPorts(ctx, req, func(change *PortChange) error {
+			got = append(got, change.Key)
+			if len(got) == n {
+				return errDone
+			}
+			return nil
+		})
+		if errors.Is(err, errDone) {
+			err = nil
+		}
+
+		keys <- got
+		errs <- err
+	}()
+
+	return keys, errs
+}
+
+func TestServer_WatchPorts(t *testing.T) {
+	ctx := context.Background()
+	c, db := newWatchServer(t)
+
+	uae, uaeErr := watch(c, &WatchPortsRequest{Country: "United Arab Emirates"}, 1)
+	all, allErr := watch(c, &WatchPortsRequest{}, 2)
+
+	// The streams follow the changes from now on, once the follower starts.
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, db.Merge(ctx, &database.Port{Key: "ARBUE", Name: "Buenos Aires", Country: "Argentina", Unlocs: pq.StringArray{}, Alias: pq.StringArray{}}, database.MergeOptions{}))
+	require.NoError(t, db.Merge(ctx, &database.Port{Key: "AEAJM", Name: "Ajman", Country: "United Arab Emirates", Unlocs: pq.StringArray{}, Alias: pq.StringArray{}}, database.MergeOptions{}))
+	_, err := db.SequenceChanges(ctx, 10)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"AEAJM"}, <-uae)
+	require.NoError(t, <-uaeErr)
+	require.Equal(t, []string{"ARBUE", "AEAJM"}, <-all)
+	require.NoError(t, <-allErr)
+
+	resumed, resumedErr := watch(c, &WatchPortsRequest{ResumeToken: "1"}, 1)
+	require.Equal(t, []string{"AEAJM"}, <-resumed)
+	require.NoError(t, <-resumedErr)
+
+	_, badErr := watch(c, &WatchPortsRequest{ResumeToken: "x"}, 1)
+	require.Equal(t, codes.InvalidArgument, status.Code(<-badErr))
+}
@@ -0,0 +1,225 @@
+// Package idempotency includes the upload deduplication utilities.
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/service"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	// ErrInProgress is returned when the original submission is still being imported.
+	ErrInProgress = errors.New("a submission with the same idempotency key is in progress")
+	// ErrMismatch is returned when an idempotency key is reused with a different payload.
+	ErrMismatch = errors.New("idempotency key reused with a different payload")
+)
+
+// timeout bounds the updates of a Claim, made outside the request context, which may be canceled by then.
+const timeout = 5 * time.Second
+
+// Store represents the storage of the submissions, implemented by the database and memory backends.
+type Store interface {
+	ClaimIdempotencyKey(context.Context, *database.IdempotencyKey, time.Time) (*database.IdempotencyKey, error)
+	SaveIdempotencyKey(context.Context, *database.IdempotencyKey) error
+	RenewIdempotencyKey(context.Context, string, time.Time) error
+	DeleteIdempotencyKey(context.Context, string) error
+}
+
+// Guard deduplicates the upload submissions received within a time window.
+type Guard struct {
+	store  Store
+	window time.Duration
+	// lease is how long a pending submission holds its key, renewed every third of it until finished or released.
+	lease time.Duration
+}
+
+// New instantiates a new Guard, which lets every submission through when the store is nil or the window zero.
+// A submission still pending once its lease ends, because its server stopped, can be claimed again.
+func New(store Store, window, lease time.Duration) *Guard {
+	return &Guard{
+		store:  store,
+		window: window,
+		lease:  lease,
+	}
+}
+
+// Enabled tells whether the Guard deduplicates submissions.
+func (g *Guard) Enabled() bool {
+	return g != nil && g.store != nil && g.window > 0
+}
+
+// Key identifies a submission of a client by its idempotency key if given, by its payload checksum otherwise.
+// It returns an empty key, which is never deduplicated, when both are missing.
+func Key(subject, idempotencyKey, checksum string) string {
+	switch {
+	case idempotencyKey != "":
+		return "key:" + subject + ":" + idempotencyKey
+	case checksum != "":
+		return "sha256:" + subject + ":" + checksum
+	default:
+		return ""
+	}
+}
+
+// Result represents the outcome of the original submission.
+type Result struct {
+	ImportID string
+	Report   service.Report
+}
+
+// Claim represents a submission being imported, its methods do nothing on a nil Claim.
+type Claim struct {
+	guard *Guard
+	row   database.IdempotencyKey
+
+	// stop ends the lease renewals, once the Claim is finished or released.
+	stop     chan struct{}
+	stopOnce sync.Once
+	renewals sync.WaitGroup
+}
+
+// Claim registers a submission, the payload checksum being optional when it is not known yet.
+// A duplicate returns the original Result, along with ErrInProgress if it is still running;
+// the Claim is nil for duplicates, empty keys and a disabled Guard.
+func (g *Guard) Claim(ctx context.Context, key, checksum string) (*Claim, *Result, error) {
+	if !g.Enabled() || key == "" {
+		return nil, nil, nil
+	}
+
+	now := time.Now().UTC()
+	c := &Claim{
+		guard: g,
+		row: database.IdempotencyKey{
+			Key:          key,
+			Checksum:     checksum,
+			Status:       database.IdempotencyPending,
+			CreatedAt:    now,
+			PendingUntil: now.Add(g.lease),
+		},
+		stop: make(chan struct{}),
+	}
+
+	found, err := g.store.ClaimIdempotencyKey(ctx, &c.row, now.Add(-g.window))
+	if err != nil {
+		return nil, nil, err
+	}
+	if found == nil {
+		c.renewals.Add(1)
+		go c.renew()
+		return c, nil, nil
+	}
+
+	if checksum != "" && found.Checksum != "" && checksum != found.Checksum {
+		return nil, nil, ErrMismatch
+	}
+
+	res := &Result{ImportID: found.ImportID}
+	if found.Status != database.IdempotencyCompleted {
+		return nil, res, ErrInProgress
+	}
+
+	if err := json.Unmarshal([]byte(found.Report), &res.Report); err != nil {
+		return nil, nil, err
+	}
+
+	return nil, res, nil
+}
+
+// Start records the import running the submission.
+func (c *Claim) Start(importID string) {
+	if c == nil {
+		return
+	}
+
+	c.row.ImportID = importID
+	c.row.PendingUntil = time.Now().UTC().Add(c.guard.lease)
+	c.save()
+}
+
+// Finish records the import report, to be returned to the duplicates, and the checksum if it was not known when claimed.
+// Failed imports release the Claim instead, so the submission can be retried.
+func (c *Claim) Finish(checksum string, report service.Report, err error) {
+	if c == nil {
+		return
+	}
+
+	if err != nil {
+		c.Release()
+		return
+	}
+
+	c.stopRenewals()
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		log.Error().Err(err).Msg("Idempotency report encoding failed")
+		c.Release()
+		return
+	}
+
+	if checksum != "" {
+		c.row.Checksum = checksum
+	}
+	c.row.Status = database.IdempotencyCompleted
+	c.row.Report = string(data)
+	c.save()
+}
+
+// Release forgets the submission, for those not imported.
+func (c *Claim) Release() {
+	if c == nil {
+		return
+	}
+
+	c.stopRenewals()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := c.guard.store.DeleteIdempotencyKey(ctx, c.row.Key); err != nil {
+		log.Error().Err(err).Str("key", c.row.Key).Msg("Idempotency key release failed")
+	}
+}
+
+func (c *Claim) save() {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := c.guard.store.SaveIdempotencyKey(ctx, &c.row); err != nil {
+		log.Error().Err(err).Str("key", c.row.Key).Msg("Idempotency key save failed")
+	}
+}
+
+// renew extends the lease of the submission until the Claim is finished or released.
+func (c *Claim) renew() {
+	defer c.renewals.Done()
+
+	ticker := time.NewTicker(c.guard.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.stop:
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		if err := c.guard.store.RenewIdempotencyKey(ctx, c.row.Key, time.Now().UTC().Add(c.guard.lease)); err != nil {
+			log.Error().Err(err).Str("key", c.row.Key).Msg("Idempotency key lease renewal failed")
+		}
+		cancel()
+	}
+}
+
+// stopRenewals ends the lease renewals, waiting for a running one so it does not follow the last save.
+func (c *Claim) stopRenewals() {
+	c.stopOnce.Do(func() { close(c.stop) })
+	c.renewals.Wait()
+}
@@ -0,0 +1,147 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func sqliteStore(t *testing.T) Store {
+	cfg := config.Default().Database
+	cfg.DSN = "sqlite://" + filepath.Join(t.TempDir(), "ports.db")
+
+	m, err := database.NewMigrator(cfg)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+
+	db, err := database.New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	return db
+}
+
+func TestGuard(t *testing.T) {
+	stores := map[string]func(*testing.T) Store{
+		"memory": func(*testing.T) Store { return memory.New() },
+		"sqlite": sqliteStore,
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			g := New(open(t), time.Hour, time.Minute)
+			key := Key("ci", "", "abc")
+
+			c, res, err := g.Claim(ctx, key, "abc")
+			require.NoError(t, err)
+			require.Nil(t, res)
+			require.NotNil(t, c)
+
+			c.Start("import-1")
+
+			_, res, err = g.Claim(ctx, key, "abc")
+			require.ErrorIs(t, err, ErrInProgress)
+			require.Equal(t, "import-1", res.ImportID)
+
+			report := service.Report{Processed: 3, Upserted: 2, Rejected: 1}
+			c.Finish("", report, nil)
+
+			dup, res, err := g.Claim(ctx, key, "abc")
+			require.NoError(t, err)
+			require.Nil(t, dup)
+			require.Equal(t, &Result{ImportID: "import-1", Report: report}, res)
+
+			_, _, err = g.Claim(ctx, Key("other", "", "abc"), "abc")
+			require.NoError(t, err, "checksums are scoped to the client")
+
+			keyed, _, err := g.Claim(ctx, Key("ci", "retry-1", ""), "")
+			require.NoError(t, err)
+			keyed.Finish("def", report, nil)
+
+			_, _, err = g.Claim(ctx, Key("ci", "retry-1", ""), "xyz")
+			require.ErrorIs(t, err, ErrMismatch)
+
+			failed, _, err := g.Claim(ctx, Key("ci", "retry-2", ""), "")
+			require.NoError(t, err)
+			failed.Finish("", service.Report{}, errors.New("boom"))
+
+			again, res, err := g.Claim(ctx, Key("ci", "retry-2", ""), "")
+			require.NoError(t, err)
+			require.Nil(t, res, "failed imports can be retried")
+			require.NotNil(t, again)
+		})
+	}
+}
+
+func TestGuard_window(t *testing.T) {
+	ctx := context.Background()
+	g := New(sqliteStore(t), time.Millisecond, time.Minute)
+
+	c, _, err := g.Claim(ctx, "k", "")
+	require.NoError(t, err)
+	c.Finish("", service.Report{}, nil)
+
+	time.Sleep(5 * time.Millisecond)
+
+	c, res, err := g.Claim(ctx, "k", "")
+	require.NoError(t, err)
+	require.Nil(t, res)
+	require.NotNil(t, c)
+}
+
+func TestGuard_lease(t *testing.T) {
+	stores := map[string]func(*testing.T) Store{
+		"memory": func(*testing.T) Store { return memory.New() },
+		"sqlite": sqliteStore,
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			g := New(open(t), time.Hour, 60*time.Millisecond)
+
+			c, _, err := g.Claim(ctx, "k", "")
+			require.NoError(t, err)
+			c.Start("import-1")
+
+			time.Sleep(150 * time.Millisecond)
+
+			_, res, err := g.Claim(ctx, "k", "")
+			require.ErrorIs(t, err, ErrInProgress, "the lease is renewed while importing")
+			require.Equal(t, "import-1", res.ImportID)
+
+			// The server importing the submission stops without finishing nor releasing it.
+			c.stopRenewals()
+			time.Sleep(100 * time.Millisecond)
+
+			again, res, err := g.Claim(ctx, "k", "")
+			require.NoError(t, err)
+			require.Nil(t, res, "expired pending claims are taken over")
+			require.NotNil(t, again)
+			again.Release()
+		})
+	}
+}
+
+func TestGuard_disabled(t *testing.T) {
+	for _, g := range []*Guard{nil, New(nil, time.Hour, time.Minute), New(memory.New(), 0, time.Minute)} {
+		c, res, err := g.Claim(context.Background(), "k", "")
+		require.NoError(t, err)
+		require.Nil(t, res)
+		require.Nil(t, c)
+
+		c.Start("id")
+		c.Finish("", service.Report{}, nil)
+	}
+}
@@ -0,0 +1,286 @@
+// Package imports includes the registry of running and recently finished imports.
+package imports
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/agukrapo/ports/service"
+)
+
+const (
+	// maxFinished is the number of finished imports kept by a Registry.
+	maxFinished = 100
+	// subscriberBuffer is the number of events buffered per subscriber, newer events are dropped once full.
+	subscriberBuffer = 64
+)
+
+// Status represents an Import state.
+type Status string
+
+// Available statuses.
+const (
+	Running   Status = "running"
+	Completed Status = "completed"
+	Failed    Status = "failed"
+)
+
+// EventType represents the kind of an Event.
+type EventType string
+
+// Available event types.
+const (
+	ProgressEvent EventType = "progress"
+	RejectEvent   EventType = "reject"
+	SummaryEvent  EventType = "summary"
+)
+
+// Event represents an Import progress notification, only the field matching its Type is set.
+type Event struct {
+	Type     EventType          `json:"type"`
+	Progress *service.Progress  `json:"progress,omitempty"`
+	Reject   *service.Rejection `json:"reject,omitempty"`
+	Summary  *Summary           `json:"summary,omitempty"`
+}
+
+// Summary represents the final state of an Import.
+type Summary struct {
+	Status Status         `json:"status"`
+	Report service.Report `json:"report"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// Import represents a single import job, it is safe for concurrent use.
+type Import struct {
+	ID     string
+	Source string
+
+	// observers are notified once the Import finishes.
+	observers []func(State)
+
+	mu          sync.Mutex
+	status      Status
+	startedAt   time.Time
+	finishedAt  time.Time
+	progress    service.Progress
+	summary     *Summary
+	subscribers map[chan Event]struct{}
+}
+
+// State represents an Import snapshot.
+type State struct {
+	ID         string           `json:"id"`
+	Source     string           `json:"source"`
+	Status     Status           `json:"status"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Progress   service.Progress `json:"progress"`
+	Summary    *Summary         `json:"summary,omitempty"`
+}
+
+// State returns a snapshot of the Import.
+func (i *Import) State() State {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	out := State{
+		ID:        i.ID,
+		Source:    i.Source,
+		Status:    i.status,
+		StartedAt: i.startedAt,
+		Progress:  i.progress,
+		Summary:   i.summary,
+	}
+
+	if !i.finishedAt.IsZero() {
+		t := i.finishedAt
+		out.FinishedAt = &t
+	}
+
+	return out
+}
+
+// Hooks returns the service.Hooks publishing the Import progress to its subscribers.
+func (i *Import) Hooks() service.Hooks {
+	return service.Hooks{
+		Progress: func(p service.Progress) {
+			i.mu.Lock()
+			defer i.mu.Unlock()
+
+			i.progress = p
+			i.publish(Event{Type: ProgressEvent, Progress: &p})
+		},
+		Reject: func(r service.Rejection) {
+			i.mu.Lock()
+			defer i.mu.Unlock()
+
+			i.publish(Event{Type: RejectEvent, Reject: &r})
+		},
+	}
+}
+
+// Finish records the Import outcome, notifies and releases its subscribers, then notifies the Registry observers.
+func (i *Import) Finish(report service.Report, err error) {
+	i.finish(report, err)
+
+	state := i.State()
+	for _, fn := range i.observers {
+		fn(state)
+	}
+}
+
+func (i *Import) finish(report service.Report, err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	summary := &Summary{Status: Completed, Report: report}
+	if err != nil {
+		summary.Status = Failed
+		summary.Error = err.Error()
+	}
+
+	i.status = summary.Status
+	i.summary = summary
+	i.finishedAt = time.Now()
+
+	i.publish(Event{Type: SummaryEvent, Summary: summary})
+
+	for ch := range i.subscribers {
+		close(ch)
+		delete(i.subscribers, ch)
+	}
+}
+
+// Subscribe returns a channel receiving the Import events, starting with its current progress.
+// The channel is closed after the summary event, the returned function unsubscribes.
+func (i *Import) Subscribe() (<-chan Event, func()) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+
+	if i.summary != nil {
+		ch <- Event{Type: SummaryEvent, Summary: i.summary}
+		close(ch)
+		return ch, func() {}
+	}
+
+	p := i.progress
+	ch <- Event{Type: ProgressEvent, Progress: &p}
+	i.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		i.mu.Lock()
+		defer i.mu.Unlock()
+
+		if _, ok := i.subscribers[ch]; ok {
+			delete(i.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish sends an event to every subscriber without blocking, must be called holding the lock.
+func (i *Import) publish(e Event) {
+	for ch := range i.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Registry keeps track of the running imports and the last finished ones, it is safe for concurrent use.
+type Registry struct {
+	mu        sync.Mutex
+	imports   map[string]*Import
+	observers []func(State)
+}
+
+// NewRegistry instantiates a new Registry, its observers being called with the final State of every Import.
+func NewRegistry(observers ...func(State)) *Registry {
+	return &Registry{
+		imports:   make(map[string]*Import),
+		observers: observers,
+	}
+}
+
+// Start registers a new running Import, source describes where its ports come from.
+func (r *Registry) Start(source string) *Import {
+	i := &Import{
+		ID:          NewID(),
+		Source:      source,
+		observers:   r.observers,
+		status:      Running,
+		startedAt:   time.Now(),
+		subscribers: make(map[chan Event]struct{}),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.imports[i.ID] = i
+	r.evict()
+
+	return i
+}
+
+// Get returns the Import with the given ID, if known.
+func (r *Registry) Get(id string) (*Import, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i, ok := r.imports[id]
+	return i, ok
+}
+
+// List returns a snapshot of every known Import, newest first.
+func (r *Registry) List() []State {
+	r.mu.Lock()
+	all := make([]*Import, 0, len(r.imports))
+	for _, i := range r.imports {
+		all = append(all, i)
+	}
+	r.mu.Unlock()
+
+	out := make([]State, 0, len(all))
+	for _, i := range all {
+		out = append(out, i.State())
+	}
+
+	sort.Slice(out, func(a, b int) bool { return out[a].StartedAt.After(out[b].StartedAt) })
+
+	return out
+}
+
+// evict removes the oldest finished imports over maxFinished, must be called holding the lock.
+func (r *Registry) evict() {
+	var finished []State
+	for _, i := range r.imports {
+		if s := i.State(); s.FinishedAt != nil {
+			finished = append(finished, s)
+		}
+	}
+
+	if len(finished) <= maxFinished {
+		return
+	}
+
+	sort.Slice(finished, func(a, b int) bool { return finished[a].FinishedAt.Before(*finished[b].FinishedAt) })
+
+	for _, s := range finished[:len(finished)-maxFinished] {
+		delete(r.imports, s.ID)
+	}
+}
+
+// NewID returns a random import ID, also identifying the imports run outside a Registry.
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,82 @@
+package imports
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/agukrapo/ports/service"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImport_Subscribe(t *testing.T) {
+	r := NewRegistry()
+	imp := r.Start("test")
+
+	got, ok := r.Get(imp.ID)
+	require.True(t, ok)
+	require.Same(t, imp, got)
+
+	events, unsubscribe := imp.Subscribe()
+	defer unsubscribe()
+
+	require.Equal(t, Event{Type: ProgressEvent, Progress: &service.Progress{}}, <-events)
+
+	hooks := imp.Hooks()
+	hooks.Progress(service.Progress{Processed: 10, Rate: 5})
+	hooks.Reject(service.Rejection{Key: "KEY", Error: "invalid"})
+	imp.Finish(service.Report{Processed: 10, Upserted: 9, Rejected: 1}, nil)
+
+	require.Equal(t, Event{Type: ProgressEvent, Progress: &service.Progress{Processed: 10, Rate: 5}}, <-events)
+	require.Equal(t, Event{Type: RejectEvent, Reject: &service.Rejection{Key: "KEY", Error: "invalid"}}, <-events)
+
+	summary := <-events
+	require.Equal(t, SummaryEvent, summary.Type)
+	require.Equal(t, Completed, summary.Summary.Status)
+	require.Equal(t, 9, summary.Summary.Report.Upserted)
+
+	_, open := <-events
+	require.False(t, open)
+
+	late, _ := imp.Subscribe()
+	require.Equal(t, SummaryEvent, (<-late).Type)
+	_, open = <-late
+	require.False(t, open)
+}
+
+func TestImport_Finish_failed(t *testing.T) {
+	imp := NewRegistry().Start("test")
+	imp.Finish(service.Report{}, errors.New("boom"))
+
+	state := imp.State()
+	require.Equal(t, Failed, state.Status)
+	require.NotNil(t, state.FinishedAt)
+	require.Equal(t, "boom", state.Summary.Error)
+}
+
+func TestRegistry_observers(t *testing.T) {
+	var got []State
+	r := NewRegistry(func(s State) { got = append(got, s) })
+
+	imp := r.Start("test")
+	require.Empty(t, got)
+
+	imp.Finish(service.Report{Upserted: 3}, nil)
+	require.Len(t, got, 1)
+	require.Equal(t, imp.ID, got[0].ID)
+	require.Equal(t, Completed, got[0].Status)
+	require.Equal(t, 3, got[0].Summary.Report.Upserted)
+}
+
+func TestRegistry_evict(t *testing.T) {
+	r := NewRegistry()
+
+	running := r.Start("running")
+	for i := 0; i < maxFinished+5; i++ {
+		r.Start("finished").Finish(service.Report{}, nil)
+	}
+	r.Start("trigger")
+
+	_, ok := r.Get(running.ID)
+	require.True(t, ok, "running imports are never evicted")
+	require.Len(t, r.List(), maxFinished+2)
+}
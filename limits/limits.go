@@ -0,0 +1,162 @@
+// Package limits includes the request rate and import concurrency limits shared by the servers.
+package limits
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"golang.org/x/time/rate"
+)
+
+// idleTimeout is the time after which the bucket of an inactive client is dropped.
+const idleTimeout = 10 * time.Minute
+
+// ErrBusy is returned when no import slot frees up in time.
+var ErrBusy = errors.New("too many concurrent imports")
+
+// Clients holds a token bucket per client, it is safe for concurrent use.
+type Clients struct {
+	limit rate.Limit
+	burst int
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	seen    time.Time
+}
+
+// NewClients instantiates a Clients allowing perSecond requests per client with bursts of burst requests,
+// a zero perSecond disables the limit.
+func NewClients(perSecond, burst int) *Clients {
+	if burst < 1 {
+		burst = perSecond
+	}
+
+	return &Clients{
+		limit:   rate.Limit(perSecond),
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		sweptAt: time.Now(),
+	}
+}
+
+// Allow tells whether a client may send a request now, consuming a token if so.
+func (c *Clients) Allow(client string) bool {
+	if c == nil || c.limit == 0 {
+		return true
+	}
+
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sweep(now)
+
+	b, ok := c.buckets[client]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(c.limit, c.burst)}
+		c.buckets[client] = b
+	}
+	b.seen = now
+
+	return b.limiter.AllowN(now, 1)
+}
+
+// sweep drops the buckets of idle clients, at most once per idleTimeout.
+func (c *Clients) sweep(now time.Time) {
+	if now.Sub(c.sweptAt) < idleTimeout {
+		return
+	}
+	c.sweptAt = now
+
+	for client, b := range c.buckets {
+		if now.Sub(b.seen) >= idleTimeout {
+			delete(c.buckets, client)
+		}
+	}
+}
+
+// Imports caps the number of concurrent imports.
+type Imports struct {
+	slots   chan struct{}
+	timeout time.Duration
+}
+
+// NewImports instantiates an Imports running max imports at once, a zero max disables the cap.
+// Extra imports wait up to timeout for a slot, they fail right away when timeout is zero.
+func NewImports(max int, timeout time.Duration) *Imports {
+	i := &Imports{timeout: timeout}
+	if max > 0 {
+		i.slots = make(chan struct{}, max)
+	}
+	return i
+}
+
+// Acquire takes an import slot, the returned function releases it.
+// It fails with ErrBusy when no slot frees up in time, or with the context error.
+func (i *Imports) Acquire(ctx context.Context) (func(), error) {
+	if i == nil || i.slots == nil {
+		return func() {}, nil
+	}
+
+	release := func() { <-i.slots }
+
+	select {
+	case i.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if i.timeout <= 0 {
+		return nil, ErrBusy
+	}
+
+	timer := time.NewTimer(i.timeout)
+	defer timer.Stop()
+
+	select {
+	case i.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, ErrBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Wait takes an import slot, waiting as long as it takes regardless of the queue timeout,
+// the returned function releases it. It only fails with the context error.
+func (i *Imports) Wait(ctx context.Context) (func(), error) {
+	if i == nil || i.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case i.slots <- struct{}{}:
+		return func() { <-i.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Limits groups the limits applied by a server.
+type Limits struct {
+	Clients *Clients
+	Imports *Imports
+}
+
+// New instantiates the Limits described by a configuration.
+func New(cfg config.Limits) Limits {
+	return Limits{
+		Clients: NewClients(cfg.RequestRate, cfg.RequestBurst),
+		Imports: NewImports(cfg.MaxImports, cfg.ImportQueueTimeout),
+	}
+}
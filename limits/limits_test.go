@@ -0,0 +1,79 @@
+package limits
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClients_Allow(t *testing.T) {
+	c := NewClients(1, 2)
+
+	require.True(t, c.Allow("a"))
+	require.True(t, c.Allow("a"))
+	require.False(t, c.Allow("a"))
+	require.True(t, c.Allow("b"))
+
+	unlimited := NewClients(0, 0)
+	for i := 0; i < 100; i++ {
+		require.True(t, unlimited.Allow("a"))
+	}
+}
+
+func TestImports_Acquire(t *testing.T) {
+	ctx := context.Background()
+
+	i := NewImports(1, 0)
+	release, err := i.Acquire(ctx)
+	require.NoError(t, err)
+
+	_, err = i.Acquire(ctx)
+	require.ErrorIs(t, err, ErrBusy)
+
+	release()
+	release, err = i.Acquire(ctx)
+	require.NoError(t, err)
+	release()
+}
+
+func TestImports_Acquire_queue(t *testing.T) {
+	ctx := context.Background()
+
+	i := NewImports(1, time.Second)
+	release, err := i.Acquire(ctx)
+	require.NoError(t, err)
+
+	time.AfterFunc(50*time.Millisecond, release)
+
+	next, err := i.Acquire(ctx)
+	require.NoError(t, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = i.Acquire(canceled)
+	require.ErrorIs(t, err, context.Canceled)
+
+	next()
+}
+
+func TestImports_Wait(t *testing.T) {
+	ctx := context.Background()
+
+	i := NewImports(1, 0)
+	release, err := i.Acquire(ctx)
+	require.NoError(t, err)
+
+	time.AfterFunc(50*time.Millisecond, release)
+
+	next, err := i.Wait(ctx)
+	require.NoError(t, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = i.Wait(canceled)
+	require.ErrorIs(t, err, context.Canceled)
+
+	next()
+}
@@ -2,6 +2,7 @@
 package parser
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,7 +12,7 @@ import (
 
 // Port represents a port json object.
 type Port struct {
-	Key         string
+	Key         string    `json:"key"`
 	Timezone    string    `json:"timezone"`
 	Coordinates []float64 `json:"coordinates"`
 	Name        string    `json:"name"`
@@ -23,21 +24,26 @@ type Port struct {
 	Code        string    `json:"code"`
 }
 
-// Iterator represents a port json stream iterator.
+// Iterator represents a port json stream iterator, reading a single object keyed by port key.
 type Iterator struct {
 	dec *json.Decoder
 }
 
-// New instantiates an Iterator.
+// New instantiates an Iterator, failing if the input does not start with a json object.
 func New(r io.Reader) (*Iterator, error) {
 	d := json.NewDecoder(r)
 
-	if _, err := d.Token(); errors.Is(err, io.EOF) {
+	token, err := d.Token()
+	if errors.Is(err, io.EOF) {
 		return nil, errors.New("empty input")
 	} else if err != nil {
 		return nil, err
 	}
 
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("invalid input: expected a json object, got %v", token)
+	}
+
 	return &Iterator{
 		dec: d,
 	}, nil
@@ -48,23 +54,63 @@ func (i *Iterator) More() bool {
 	return i.dec.More()
 }
 
+// Offset returns the number of input bytes read so far.
+func (i *Iterator) Offset() int64 {
+	return i.dec.InputOffset()
+}
+
 // Next populates the input port with the next Port in the Iterator.
 func (i *Iterator) Next(port *Port) error {
-	token, err := i.dec.Token()
+	key, raw, err := i.read()
 	if err != nil {
 		return err
 	}
 
+	return decode(key, raw, port)
+}
+
+func (i *Iterator) read() (string, json.RawMessage, error) {
+	token, err := i.dec.Token()
+	if err != nil {
+		return "", nil, err
+	}
+
 	k, ok := token.(string)
 	if !ok {
-		return fmt.Errorf("invalid key: %v", token)
+		return "", nil, fmt.Errorf("invalid key: %v", token)
 	}
 
-	if err := i.dec.Decode(&port); err != nil {
+	var raw json.RawMessage
+	if err := i.dec.Decode(&raw); err != nil {
+		return k, nil, err
+	}
+
+	return k, raw, nil
+}
+
+// Decode parses a raw port object, its key being taken from the object key field when empty.
+func Decode(key string, raw []byte) (*Port, error) {
+	var out Port
+	if err := decode(key, raw, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func decode(key string, raw []byte, port *Port) error {
+	*port = Port{}
+
+	if err := json.Unmarshal(raw, port); err != nil {
 		return err
 	}
 
-	port.Key = k
+	if key != "" {
+		port.Key = key
+	}
+	if port.Key == "" {
+		return errors.New("invalid key: missing")
+	}
+
 	return nil
 }
 
@@ -72,24 +118,116 @@ func (i *Iterator) Next(port *Port) error {
 type Packet struct {
 	Port *Port
 	Err  error
+	// Key and Raw hold the record key and JSON fragment, when they could be read, even if the record is invalid.
+	Key string
+	Raw json.RawMessage
+}
+
+// Source represents a Packet stream, implemented by Iterator and NDJSON.
+type Source interface {
+	Stream(context.Context) chan Packet
 }
 
 // Stream return a Packet unbuffered channel.
 func (i *Iterator) Stream(ctx context.Context) chan Packet {
+	return stream(ctx, i)
+}
+
+// NDJSON represents a newline delimited port json stream iterator, reading an object with a key field per line.
+type NDJSON struct {
+	dec     *json.Decoder
+	skipped int64
+}
+
+// NewNDJSON instantiates an NDJSON iterator, failing if the input does not start with a json object.
+func NewNDJSON(r io.Reader) (*NDJSON, error) {
+	br := bufio.NewReader(r)
+
+	var skipped int64
+	for {
+		b, err := br.Peek(1)
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty input")
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			_, _ = br.ReadByte()
+			skipped++
+			continue
+		case '{':
+			return &NDJSON{dec: json.NewDecoder(br), skipped: skipped}, nil
+		default:
+			return nil, fmt.Errorf("invalid input: expected a json object, got %q", b[0])
+		}
+	}
+}
+
+// More tells if there is a Port in the iterator.
+func (i *NDJSON) More() bool {
+	return i.dec.More()
+}
+
+// Offset returns the number of input bytes read so far.
+func (i *NDJSON) Offset() int64 {
+	return i.skipped + i.dec.InputOffset()
+}
+
+// Next populates the input port with the next Port in the iterator.
+func (i *NDJSON) Next(port *Port) error {
+	_, raw, err := i.read()
+	if err != nil {
+		return err
+	}
+
+	return decode("", raw, port)
+}
+
+func (i *NDJSON) read() (string, json.RawMessage, error) {
+	var raw json.RawMessage
+	if err := i.dec.Decode(&raw); err != nil {
+		return "", nil, err
+	}
+
+	return "", raw, nil
+}
+
+// Stream return a Packet unbuffered channel.
+func (i *NDJSON) Stream(ctx context.Context) chan Packet {
+	return stream(ctx, i)
+}
+
+// iterator reads the raw records, failing only when the following ones cannot be read.
+type iterator interface {
+	More() bool
+	read() (string, json.RawMessage, error)
+}
+
+func stream(ctx context.Context, it iterator) chan Packet {
 	out := make(chan Packet)
 
 	go func() {
 		defer close(out)
 
-		for i.More() {
-			var p Port
-			if err := i.Next(&p); err != nil {
-				out <- Packet{Err: err}
+		for it.More() {
+			key, raw, err := it.read()
+			if err != nil {
+				select {
+				case out <- Packet{Err: err, Key: key}:
+				case <-ctx.Done():
+				}
 				return
 			}
 
+			packet := Packet{Key: key, Raw: raw}
+			if packet.Port, packet.Err = Decode(key, raw); packet.Port != nil {
+				packet.Key = packet.Port.Key
+			}
+
 			select {
-			case out <- Packet{Port: &p}:
+			case out <- packet:
 			case <-ctx.Done():
 				return
 			}
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"context"
 	"io"
 	"strings"
 	"testing"
@@ -73,3 +74,70 @@ func TestParser(t *testing.T) {
 		})
 	}
 }
+
+func TestNew_invalid(t *testing.T) {
+	_, err := New(strings.NewReader(""))
+	require.EqualError(t, err, "empty input")
+
+	_, err = New(strings.NewReader(`["key"]`))
+	require.EqualError(t, err, "invalid input: expected a json object, got [")
+}
+
+func TestNDJSON(t *testing.T) {
+	_, err := NewNDJSON(strings.NewReader(" \n"))
+	require.EqualError(t, err, "empty input")
+
+	_, err = NewNDJSON(strings.NewReader(`["key"]`))
+	require.EqualError(t, err, `invalid input: expected a json object, got '['`)
+
+	p, err := NewNDJSON(strings.NewReader(`
+{"key": "one", "name": "one", "coordinates": [1, 2]}
+{"name": "missing key"}
+{"key": "two", "name": "two"}
+`))
+	require.NoError(t, err)
+
+	var got []Packet
+	for packet := range p.Stream(context.Background()) {
+		got = append(got, packet)
+	}
+
+	require.Len(t, got, 3)
+	require.Equal(t, &Port{Key: "one", Name: "one", Coordinates: []float64{1, 2}}, got[0].Port)
+	require.EqualError(t, got[1].Err, "invalid key: missing")
+	require.Equal(t, &Port{Key: "two", Name: "two"}, got[2].Port)
+}
+
+func TestStream_raw(t *testing.T) {
+	p, err := New(strings.NewReader(`{"one": {"name": "one"}, "two": {"name": 2}, "three": {"name": "three"}}`))
+	require.NoError(t, err)
+
+	var got []Packet
+	for packet := range p.Stream(context.Background()) {
+		got = append(got, packet)
+	}
+
+	require.Len(t, got, 3)
+	require.Equal(t, "one", got[0].Key)
+	require.JSONEq(t, `{"name": "one"}`, string(got[0].Raw))
+
+	require.Error(t, got[1].Err, "invalid records do not stop the stream")
+	require.Nil(t, got[1].Port)
+	require.Equal(t, "two", got[1].Key)
+	require.JSONEq(t, `{"name": 2}`, string(got[1].Raw))
+
+	require.Equal(t, &Port{Key: "three", Name: "three"}, got[2].Port)
+}
+
+func TestDecode(t *testing.T) {
+	port, err := Decode("", []byte(`{"key": "one", "name": "one"}`))
+	require.NoError(t, err)
+	require.Equal(t, &Port{Key: "one", Name: "one"}, port)
+
+	port, err = Decode("two", []byte(`{"key": "one"}`))
+	require.NoError(t, err)
+	require.Equal(t, "two", port.Key)
+
+	_, err = Decode("", []byte(`{"name": "one"}`))
+	require.EqualError(t, err, "invalid key: missing")
+}
@@ -0,0 +1,39 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/agukrapo/ports/auth"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const headerAPIKey = "X-API-Key"
+
+// require returns a middleware rejecting the requests whose credentials do not grant a scope.
+func (s *Server) require(scope auth.Scope) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+
+			p, err := s.auth.Authorize(auth.Credentials{
+				APIKey:        req.Header.Get(headerAPIKey),
+				Authorization: req.Header.Get(echo.HeaderAuthorization),
+			}, scope)
+			if errors.Is(err, auth.ErrForbidden) {
+				log.Warn().Err(err).Str("path", req.URL.Path).Msg("REST request forbidden")
+				return c.JSON(http.StatusForbidden, err.Error())
+			}
+			if err != nil {
+				log.Warn().Err(err).Str("path", req.URL.Path).Msg("REST request unauthenticated")
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return c.JSON(http.StatusUnauthorized, err.Error())
+			}
+
+			c.SetRequest(req.WithContext(auth.NewContext(req.Context(), p)))
+
+			return next(c)
+		}
+	}
+}
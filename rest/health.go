@@ -0,0 +1,36 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const checkTimeout = 2 * time.Second
+
+type health struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (s *Server) healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, health{Status: "ok"})
+}
+
+func (s *Server) readyz(c echo.Context) error {
+	if atomic.LoadInt32(&s.draining) == 1 {
+		return c.JSON(http.StatusServiceUnavailable, health{Status: "shutting down"})
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), checkTimeout)
+	defer cancel()
+
+	if err := s.checker.Check(ctx); err != nil {
+		return c.JSON(http.StatusServiceUnavailable, health{Status: "unavailable", Error: err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, health{Status: "ok"})
+}
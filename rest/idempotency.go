@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/agukrapo/ports/auth"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/idempotency"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerIdempotencyKey = "Idempotency-Key"
+	headerReplayed       = "Idempotent-Replayed"
+)
+
+// claim registers an upload submission by its Idempotency-Key header, or by its payload checksum when known.
+// Submissions are scoped by source and conflict policy, the same payload imported from another source,
+// or with another policy, not being a duplicate.
+// A duplicate returns the original result, its import ID being set in the response headers.
+func (s *Server) claim(c echo.Context, checksum string, opts importOpts) (*idempotency.Claim, *idempotency.Result, error) {
+	ctx := c.Request().Context()
+
+	var subject string
+	if p, ok := auth.FromContext(ctx); ok {
+		subject = p.Subject
+	}
+	if opts.source != database.DefaultSource {
+		subject += "@" + opts.source
+	}
+	if opts.conflict != database.ConflictOverwrite {
+		subject += "#" + string(opts.conflict)
+	}
+
+	key := idempotency.Key(subject, c.Request().Header.Get(headerIdempotencyKey), checksum)
+
+	claim, res, err := s.idempotency.Claim(ctx, key, checksum)
+	if res != nil {
+		h := c.Response().Header()
+		h.Set(headerImportID, res.ImportID)
+		if err == nil {
+			h.Set(headerReplayed, "true")
+		}
+	}
+
+	return claim, res, err
+}
+
+// claimError responds 409 to duplicates still running and 422 to reused keys, returning any other error as is.
+func claimError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, idempotency.ErrInProgress):
+		return c.JSON(http.StatusConflict, err.Error())
+	case errors.Is(err, idempotency.ErrMismatch):
+		return c.JSON(http.StatusUnprocessableEntity, err.Error())
+	}
+	return err
+}
+
+// checksum returns the hex encoded SHA-256 of a file, rewinding it.
+func checksum(f io.ReadSeeker) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
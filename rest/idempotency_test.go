@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/limits"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_claim_scope(t *testing.T) {
+	s, _ := newTestServer(t, time.Hour, limits.Limits{})
+	s.cfg.MaxBodySize = 1 << 20
+
+	put := func(query string) (string, bool) {
+		rec := serve(s, http.MethodPut, "/ports"+query, testInput, map[string]string{
+			"Content-Type":       "application/json",
+			headerIdempotencyKey: "key",
+		})
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Header().Get(headerImportID), rec.Header().Get(headerReplayed) == "true"
+	}
+
+	id, replayed := put("")
+	require.False(t, replayed)
+
+	again, replayed := put("?conflict=overwrite")
+	require.True(t, replayed, "the same key is a duplicate")
+	require.Equal(t, id, again)
+
+	other, replayed := put("?source=geo")
+	require.False(t, replayed, "the same key from another source is imported")
+	require.NotEqual(t, id, other)
+
+	filled, replayed := put("?conflict=fill-empty")
+	require.False(t, replayed, "the same key with another conflict policy is imported")
+	require.NotEqual(t, id, filled)
+
+	again, replayed = put("?conflict=fill-empty")
+	require.True(t, replayed)
+	require.Equal(t, filled, again)
+}
@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/agukrapo/ports/imports"
+	"github.com/agukrapo/ports/service"
+	"github.com/labstack/echo/v4"
+)
+
+const heartbeatInterval = 15 * time.Second
+
+func (s *Server) listImports(c echo.Context) error {
+	return c.JSON(http.StatusOK, s.imports.List())
+}
+
+func (s *Server) getImport(c echo.Context) error {
+	imp, ok := s.imports.Get(c.Param("id"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, "import not found")
+	}
+
+	return c.JSON(http.StatusOK, imp.State())
+}
+
+// importEvents streams an import progress as Server-Sent Events, until its summary event.
+func (s *Server) importEvents(c echo.Context) error {
+	imp, ok := s.imports.Get(c.Param("id"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, "import not found")
+	}
+
+	events, unsubscribe := imp.Subscribe()
+	defer unsubscribe()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			if err := writeEvent(res, e); err != nil {
+				return err
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return err
+			}
+			res.Flush()
+		case <-c.Request().Context().Done():
+			return nil
+		case <-s.closing:
+			return nil
+		}
+	}
+}
+
+func writeEvent(res *echo.Response, e imports.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+		return err
+	}
+
+	res.Flush()
+	return nil
+}
+
+// importRejects streams the dead letters of an import as NDJSON, to be fixed and replayed.
+func (s *Server) importRejects(c echo.Context) error {
+	id := c.Param("id")
+	res := c.Response()
+	enc := json.NewEncoder(res)
+
+	start := func() {
+		if !res.Committed {
+			res.Header().Set(echo.HeaderContentType, mimeNDJSON)
+			res.Header().Set(echo.HeaderContentDisposition, "attachment; filename=rejects-"+id+".ndjson")
+			res.WriteHeader(http.StatusOK)
+		}
+	}
+
+	err := s.service.DeadLetters(c.Request().Context(), id, func(l *service.DeadLetter) error {
+		start()
+		return enc.Encode(l)
+	})
+	if errors.Is(err, service.ErrNoDeadLetters) {
+		return c.JSON(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		// Once the headers are sent, a failure can only be reported by cutting the response short.
+		return err
+	}
+
+	start()
+	return nil
+}
@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/agukrapo/ports/auth"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	headerRetryAfter = "Retry-After"
+	// retryAfter is the number of seconds clients are asked to wait after a 429 response.
+	retryAfter = "1"
+)
+
+// throttle rejects the requests of the clients exceeding their rate limit,
+// clients are identified by their authenticated subject, or by their IP when anonymous.
+func (s *Server) throttle(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		client := "ip " + c.RealIP()
+		if p, ok := auth.FromContext(c.Request().Context()); ok && p.Subject != auth.Anonymous.Subject {
+			client = "subject " + p.Subject
+		}
+
+		if !s.limits.Clients.Allow(client) {
+			c.Response().Header().Set(headerRetryAfter, retryAfter)
+			return c.JSON(http.StatusTooManyRequests, "rate limit exceeded")
+		}
+
+		return next(c)
+	}
+}
+
+// ipExtractor returns the client IP extractor, only trusting the X-Forwarded-For header of the trusted proxies
+// so that clients cannot pick their own IP.
+func ipExtractor(proxies []*net.IPNet) echo.IPExtractor {
+	if len(proxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	opts := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, p := range proxies {
+		opts = append(opts, echo.TrustIPRange(p))
+	}
+
+	return echo.ExtractIPFromXFFHeader(opts...)
+}
@@ -0,0 +1,56 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/idempotency"
+	"github.com/agukrapo/ports/limits"
+	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_throttle(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies string
+		// codes are the statuses of the requests forwarded for each client IP.
+		forwarded []string
+		codes     []int
+	}{
+		{
+			name:      "spoofed header",
+			forwarded: []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"},
+			codes:     []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests},
+		},
+		{
+			name:      "trusted proxy",
+			proxies:   "192.0.2.0/24",
+			forwarded: []string{"203.0.113.1", "203.0.113.2", "203.0.113.1"},
+			codes:     []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.New()
+			lim := limits.Limits{Clients: limits.NewClients(1, 1)}
+
+			s, err := New(config.REST{TrustedProxies: tt.proxies}, service.New(store), store, nil, lim, nil, idempotency.New(nil, 0, 0), nil)
+			require.NoError(t, err)
+
+			for i, ip := range tt.forwarded {
+				// httptest requests come from 192.0.2.1.
+				req := httptest.NewRequest(http.MethodGet, "/imports", nil)
+				req.Header.Set("X-Forwarded-For", ip)
+				req.Header.Set("X-Real-Ip", ip)
+
+				rec := httptest.NewRecorder()
+				s.e.ServeHTTP(rec, req)
+				require.Equal(t, tt.codes[i], rec.Code, "request %d", i)
+			}
+		})
+	}
+}
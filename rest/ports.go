@@ -0,0 +1,133 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/export"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type page struct {
+	Ports  []database.Port `json:"ports"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+func (s *Server) list(c echo.Context) error {
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	limit, err := intParam(c, "limit", defaultLimit)
+	if err != nil || limit < 1 || limit > maxLimit {
+		return c.JSON(http.StatusBadRequest, "invalid limit, must be between 1 and "+strconv.Itoa(maxLimit))
+	}
+
+	offset, err := intParam(c, "offset", 0)
+	if err != nil || offset < 0 {
+		return c.JSON(http.StatusBadRequest, "invalid offset")
+	}
+
+	ports, err := s.service.List(c.Request().Context(), filter, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	if ports == nil {
+		ports = []database.Port{}
+	}
+
+	return c.JSON(http.StatusOK, page{Ports: ports, Limit: limit, Offset: offset})
+}
+
+func (s *Server) export(c echo.Context) error {
+	filter, err := parseFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	format, err := export.ParseFormat(c.QueryParam("format"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, format.ContentType())
+	res.Header().Set(echo.HeaderContentDisposition, "attachment; filename=ports."+string(format))
+	res.WriteHeader(http.StatusOK)
+
+	enc, err := export.NewEncoder(format, res)
+	if err != nil {
+		return err
+	}
+
+	// Headers are already sent, a failure can only be reported by cutting the response short.
+	return s.service.Export(c.Request().Context(), filter, enc)
+}
+
+// sources returns the values of every source of a port, along with the fields each one supplies.
+func (s *Server) sources(c echo.Context) error {
+	sources, err := s.service.Sources(c.Request().Context(), c.Param("key"))
+	if errors.Is(err, database.ErrNotFound) {
+		return c.JSON(http.StatusNotFound, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, sources)
+}
+
+// parseFilter reads a database.Filter from the query parameters key (repeated or comma separated), country, city, bbox
+// and as_of.
+func parseFilter(c echo.Context) (database.Filter, error) {
+	var out database.Filter
+
+	for _, v := range c.QueryParams()["key"] {
+		for _, k := range strings.Split(v, ",") {
+			if k = strings.TrimSpace(k); k != "" {
+				out.Keys = append(out.Keys, k)
+			}
+		}
+	}
+
+	out.Country = c.QueryParam("country")
+	out.City = c.QueryParam("city")
+
+	if v := c.QueryParam("bbox"); v != "" {
+		bbox, err := database.ParseBBox(v)
+		if err != nil {
+			return out, err
+		}
+		out.BBox = bbox
+	}
+
+	if v := c.QueryParam("as_of"); v != "" {
+		asOf, err := database.ParseAsOf(v)
+		if err != nil {
+			return out, err
+		}
+		out.AsOf = asOf
+	}
+
+	return out, nil
+}
+
+func intParam(c echo.Context, name string, def int) (int, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return def, nil
+	}
+
+	return strconv.Atoi(v)
+}
@@ -9,72 +9,188 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/agukrapo/ports/parser"
+	"github.com/agukrapo/ports/auth"
+	"github.com/agukrapo/ports/cdc"
+	"github.com/agukrapo/ports/certs"
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/idempotency"
+	"github.com/agukrapo/ports/imports"
+	"github.com/agukrapo/ports/limits"
 	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/uploads"
+	"github.com/agukrapo/ports/webhooks"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	glog "github.com/labstack/gommon/log"
 	"github.com/rs/zerolog/log"
 )
 
-const (
-	shutdownTimeout = 3 * time.Second
-)
+type checker interface {
+	Check(context.Context) error
+}
 
+// Server represents an upload REST server.
 type Server struct {
-	address string
+	cfg     config.REST
 	e       *echo.Echo
+	http    *http.Server
 	service *service.Service
+	checker checker
+	auth    *auth.Authenticator
+	limits  limits.Limits
+	uploads *uploads.Store
+	imports *imports.Registry
+	// idempotency deduplicates the upload submissions.
+	idempotency *idempotency.Guard
+	webhooks    *webhooks.Registry
+	// changes follows the change log once for all the WebSocket subscriptions.
+	changes  *cdc.Hub
+	draining int32
+
+	// ctx is canceled once the server stops accepting requests, stopping the background imports.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// closing is closed when the shutdown starts, once drained, ending the event streams.
+	closing    chan struct{}
+	background sync.WaitGroup
 }
 
-// New instantiates a new Server.
-func New(port string, service *service.Service) *Server {
+// New instantiates a new Server, serving TLS when a certificate is configured.
+func New(cfg config.REST, service *service.Service, checker checker, authenticator *auth.Authenticator, limits limits.Limits, uploads *uploads.Store, guard *idempotency.Guard, hooks *webhooks.Registry) (*Server, error) {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
 	e.Logger.SetLevel(glog.OFF)
 	e.Use(middleware.Recover(), loggingMW)
 
+	proxies, err := cfg.Proxies()
+	if err != nil {
+		return nil, err
+	}
+	e.IPExtractor = ipExtractor(proxies)
+
+	server := e.Server
+	if cfg.TLS.Enabled() {
+		tlsCfg, err := certs.Server(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+
+		server = e.TLSServer
+		server.TLSConfig = tlsCfg
+	}
+	server.Addr = cfg.Address
+	server.ReadHeaderTimeout = cfg.ReadHeaderTimeout
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	s := &Server{
-		e:       e,
-		address: fmt.Sprintf(":%s", port),
-		service: service,
+		e:           e,
+		http:        server,
+		cfg:         cfg,
+		service:     service,
+		checker:     checker,
+		auth:        authenticator,
+		limits:      limits,
+		uploads:     uploads,
+		imports:     imports.NewRegistry(hooks.ImportFinished),
+		idempotency: guard,
+		webhooks:    hooks,
+		changes:     cdc.NewHub(service),
+		ctx:         ctx,
+		cancel:      cancel,
+		closing:     make(chan struct{}),
 	}
 
-	e.PUT("/upload", s.upload)
+	e.GET("/healthz", s.healthz)
+	e.GET("/readyz", s.readyz)
+	read := []echo.MiddlewareFunc{s.require(auth.ScopeRead), s.throttle}
+	write := []echo.MiddlewareFunc{s.require(auth.ScopeWrite), s.throttle}
+
+	e.PUT("/upload", s.upload, write...)
+	e.PUT("/ports", s.uploadRaw, write...)
+	e.GET("/ports", s.list, read...)
+	e.GET("/ports/export", s.export, read...)
+	e.GET("/ports/:key/sources", s.sources, read...)
+	e.GET("/imports", s.listImports, read...)
+	e.GET("/imports/:id", s.getImport, read...)
+	e.GET("/imports/:id/events", s.importEvents, read...)
+	e.GET("/imports/:id/rejects", s.importRejects, read...)
+	e.GET("/ws", s.websocket, append([]echo.MiddlewareFunc{fromQuery}, read...)...)
+
+	tus := append([]echo.MiddlewareFunc{tusResumable}, write...)
+
+	e.OPTIONS("/uploads", s.tusOptions)
+	e.POST("/uploads", s.tusCreate, tus...)
+	e.HEAD("/uploads/:id", s.tusHead, tus...)
+	e.PATCH("/uploads/:id", s.tusPatch, tus...)
+	e.DELETE("/uploads/:id", s.tusDelete, tus...)
+
+	admin := []echo.MiddlewareFunc{s.require(auth.ScopeAdmin), s.throttle}
+
+	e.GET("/snapshots", s.listSnapshots, admin...)
+	e.POST("/snapshots", s.createSnapshot, admin...)
+	e.POST("/snapshots/:name/restore", s.restoreSnapshot, admin...)
+	e.DELETE("/snapshots/:name", s.deleteSnapshot, admin...)
+
+	if hooks.Enabled() {
+		e.GET("/webhooks", s.listWebhooks, admin...)
+		e.POST("/webhooks", s.createWebhook, admin...)
+		e.GET("/webhooks/:id", s.getWebhook, admin...)
+		e.PUT("/webhooks/:id", s.updateWebhook, admin...)
+		e.DELETE("/webhooks/:id", s.deleteWebhook, admin...)
+		e.GET("/webhooks/:id/deliveries", s.webhookDeliveries, admin...)
+	}
 
-	return s
+	return s, nil
 }
 
 // Start starts a Server.
 func (s *Server) Start() {
 	go func() {
-		if err := s.e.Start(s.address); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := s.e.StartServer(s.http); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Error().Err(err).Msg("REST server start failed")
 
 			_ = syscall.Kill(syscall.Getpid(), syscall.SIGINT)
 		}
 	}()
 
-	log.Info().Msgf("REST server started at %s", s.address)
+	log.Info().Bool("tls", s.cfg.TLS.Enabled()).Msgf("REST server started at %s", s.cfg.Address)
 }
 
-// Listen blocks until an os.Interrupt occurs.
+// Listen blocks until an os.Interrupt occurs, then shuts the Server down.
 func (s *Server) Listen() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt)
 	<-quit
 
-	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	s.shutdown()
+}
+
+// shutdown reports the Server is not ready while still serving for the drain delay, then shuts it down.
+func (s *Server) shutdown() {
+	atomic.StoreInt32(&s.draining, 1)
+
+	log.Info().Dur("delay", s.cfg.DrainDelay).Msg("REST server draining")
+	time.Sleep(s.cfg.DrainDelay)
+
+	close(s.closing)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := s.e.Shutdown(ctx); err != nil {
 		log.Error().Err(err).Msg("REST server shutdown failed")
 	}
 
+	s.cancel()
+	s.background.Wait()
+
 	log.Info().Msg("REST server closed")
 }
 
@@ -101,28 +217,6 @@ func loggingMW(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
-func (s *Server) upload(c echo.Context) error {
-	file, err := c.FormFile("file")
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
-	}
-
-	src, err := file.Open()
-	if err != nil {
-		return err
-	}
-	defer safeClose(src)
-
-	p, err := parser.New(src)
-	if err != nil {
-		return err
-	}
-
-	s.service.Process(c.Request().Context(), p)
-
-	return c.NoContent(http.StatusOK)
-}
-
 func safeClose(c io.Closer) {
 	if err := c.Close(); err != nil {
 		log.Error().Err(err).Msg("Close failed")
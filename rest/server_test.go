@@ -0,0 +1,57 @@
+package rest
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/limits"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer_shutdown(t *testing.T) {
+	s, _ := newTestServer(t, time.Hour, limits.Limits{})
+	s.cfg.DrainDelay = 500 * time.Millisecond
+	s.cfg.ShutdownTimeout = time.Second
+	s.http.Addr = "127.0.0.1:0"
+
+	go func() { _ = s.e.StartServer(s.http) }()
+	require.Eventually(t, func() bool { return s.e.ListenerAddr() != nil }, time.Second, 10*time.Millisecond)
+	url := "http://" + s.e.ListenerAddr().String()
+
+	get := func(path string) (int, error) {
+		res, err := http.Get(url + path)
+		if err != nil {
+			return 0, err
+		}
+		_ = res.Body.Close()
+		return res.StatusCode, nil
+	}
+
+	status, err := get("/readyz")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, status)
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.shutdown()
+	}()
+
+	// The server reports it is not ready while still serving, for the drain delay.
+	require.Eventually(t, func() bool {
+		status, err := get("/readyz")
+		return err == nil && status == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	status, err = get("/healthz")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, status)
+
+	<-done
+	require.GreaterOrEqual(t, time.Since(start), s.cfg.DrainDelay)
+
+	_, err = get("/healthz")
+	require.Error(t, err, "the server stops accepting requests once shut down")
+}
@@ -0,0 +1,82 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/agukrapo/ports/auth"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/service"
+	"github.com/labstack/echo/v4"
+)
+
+type snapshotInput struct {
+	Name string `json:"name"`
+}
+
+func (s *Server) listSnapshots(c echo.Context) error {
+	snapshots, err := s.service.Snapshots(c.Request().Context())
+	if err != nil {
+		return snapshotError(c, err)
+	}
+
+	if snapshots == nil {
+		snapshots = []database.Snapshot{}
+	}
+
+	return c.JSON(http.StatusOK, snapshots)
+}
+
+// createSnapshot copies every stored port to a new snapshot, recording the caller as its creator.
+func (s *Server) createSnapshot(c echo.Context) error {
+	var in snapshotInput
+	if err := c.Bind(&in); err != nil {
+		return c.JSON(http.StatusBadRequest, "invalid snapshot JSON")
+	}
+
+	ctx := c.Request().Context()
+
+	var createdBy string
+	if p, ok := auth.FromContext(ctx); ok {
+		createdBy = p.Subject
+	}
+
+	snapshot, err := s.service.CreateSnapshot(ctx, in.Name, createdBy)
+	if err != nil {
+		return snapshotError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, snapshot)
+}
+
+func (s *Server) restoreSnapshot(c echo.Context) error {
+	restore, err := s.service.RestoreSnapshot(c.Request().Context(), c.Param("name"))
+	if err != nil {
+		return snapshotError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, restore)
+}
+
+func (s *Server) deleteSnapshot(c echo.Context) error {
+	if err := s.service.DeleteSnapshot(c.Request().Context(), c.Param("name")); err != nil {
+		return snapshotError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func snapshotError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, database.ErrSnapshotNotFound):
+		return c.JSON(http.StatusNotFound, err.Error())
+	case errors.Is(err, database.ErrSnapshotExists):
+		return c.JSON(http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrInvalidSnapshotName):
+		return c.JSON(http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrNoSnapshots):
+		return c.JSON(http.StatusNotImplemented, err.Error())
+	default:
+		return err
+	}
+}
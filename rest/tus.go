@@ -0,0 +1,246 @@
+package rest
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/uploads"
+	"github.com/labstack/echo/v4"
+)
+
+// tus.io core protocol, with the creation, expiration and termination extensions.
+const (
+	tusVersion    = "1.0.0"
+	tusExtensions = "creation,expiration,termination"
+
+	headerTusResumable  = "Tus-Resumable"
+	headerTusVersion    = "Tus-Version"
+	headerTusExtension  = "Tus-Extension"
+	headerTusMaxSize    = "Tus-Max-Size"
+	headerUploadLength  = "Upload-Length"
+	headerUploadOffset  = "Upload-Offset"
+	headerUploadExpires = "Upload-Expires"
+	headerUploadMeta    = "Upload-Metadata"
+
+	mimeOffsetStream = "application/offset+octet-stream"
+)
+
+// tusOptions describes the supported protocol.
+func (s *Server) tusOptions(c echo.Context) error {
+	h := c.Response().Header()
+	h.Set(headerTusResumable, tusVersion)
+	h.Set(headerTusVersion, tusVersion)
+	h.Set(headerTusExtension, tusExtensions)
+	h.Set(headerTusMaxSize, strconv.FormatInt(s.uploads.MaxSize(), 10))
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// tusResumable rejects the requests of another protocol version.
+func tusResumable(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		c.Response().Header().Set(headerTusResumable, tusVersion)
+
+		if c.Request().Header.Get(headerTusResumable) != tusVersion {
+			c.Response().Header().Set(headerTusVersion, tusVersion)
+			return c.JSON(http.StatusPreconditionFailed, "unsupported "+headerTusResumable+" version")
+		}
+
+		return next(c)
+	}
+}
+
+// tusCreate opens an upload session of the size given by the Upload-Length header.
+func (s *Server) tusCreate(c echo.Context) error {
+	req := c.Request()
+
+	size, err := strconv.ParseInt(req.Header.Get(headerUploadLength), 10, 64)
+	if err != nil || size <= 0 {
+		return c.JSON(http.StatusBadRequest, "invalid "+headerUploadLength+" header")
+	}
+
+	if _, err := metadataOptions(req.Header.Get(headerUploadMeta)); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	session, err := s.uploads.Create(size, req.Header.Get(headerUploadMeta))
+	if err != nil {
+		return tusError(c, err)
+	}
+
+	setSession(c, session)
+	c.Response().Header().Set(echo.HeaderLocation, "/uploads/"+session.ID)
+
+	return c.NoContent(http.StatusCreated)
+}
+
+// tusHead returns the committed offset of an upload session.
+func (s *Server) tusHead(c echo.Context) error {
+	session, err := s.uploads.Get(c.Param("id"))
+	if err != nil {
+		return tusError(c, err)
+	}
+
+	setSession(c, session)
+	c.Response().Header().Set(headerUploadLength, strconv.FormatInt(session.Size, 10))
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+
+	return c.NoContent(http.StatusOK)
+}
+
+// tusPatch appends the body to an upload session at the Upload-Offset header, starting its import once complete.
+func (s *Server) tusPatch(c echo.Context) error {
+	req := c.Request()
+
+	if mediaType(c) != mimeOffsetStream {
+		return c.JSON(http.StatusUnsupportedMediaType, "content type must be "+mimeOffsetStream)
+	}
+
+	offset, err := strconv.ParseInt(req.Header.Get(headerUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		return c.JSON(http.StatusBadRequest, "invalid "+headerUploadOffset+" header")
+	}
+
+	id := c.Param("id")
+
+	session, err := s.uploads.Append(id, offset, req.Body)
+	if session.ID != "" {
+		setSession(c, session)
+	}
+	if err != nil {
+		return tusError(c, err)
+	}
+
+	if session.Complete() {
+		return s.tusImport(c, session)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// tusImport starts the background import of a complete upload session, deleting it once finished.
+// The import waits for a free slot rather than failing when the concurrent imports cap is reached,
+// since clients take an upload as done once every byte is committed.
+// Invalid uploads and duplicates of an imported one are deleted right away, the session is kept when the import
+// fails to start for another reason: as an extension to the protocol, a PATCH without data at the final offset
+// retries it.
+func (s *Server) tusImport(c echo.Context, session uploads.Session) error {
+	f, done, err := s.uploads.Open(session.ID)
+	if err != nil {
+		return tusError(c, err)
+	}
+
+	sum, err := checksum(f)
+	if err != nil {
+		done(false)
+		return err
+	}
+
+	src, err := newSource(f, metadataType(session.Metadata))
+	if err != nil {
+		done(true)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	opts, err := metadataOptions(session.Metadata)
+	if err != nil {
+		done(true)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	claim, dup, err := s.claim(c, sum, opts)
+	if err != nil {
+		done(false)
+		return claimError(c, err)
+	}
+	if dup != nil {
+		done(true)
+		return c.NoContent(http.StatusNoContent)
+	}
+
+	imp := s.register(c)
+	claim.Start(imp.ID)
+
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		defer done(true)
+
+		release, err := s.limits.Imports.Wait(s.ctx)
+		if err != nil {
+			imp.Finish(service.Report{}, err)
+			claim.Release()
+			return
+		}
+		defer release()
+
+		report := s.service.Process(s.ctx, src, opts.with(service.WithHooks(imp.Hooks()), service.WithImportID(imp.ID), service.WithSize(session.Size))...)
+		imp.Finish(report, s.ctx.Err())
+		claim.Finish("", report, s.ctx.Err())
+	}()
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// tusDelete discards an upload session.
+func (s *Server) tusDelete(c echo.Context) error {
+	if err := s.uploads.Remove(c.Param("id")); err != nil {
+		return tusError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func setSession(c echo.Context, session uploads.Session) {
+	h := c.Response().Header()
+	h.Set(headerUploadOffset, strconv.FormatInt(session.Offset, 10))
+	h.Set(headerUploadExpires, session.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+func tusError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, uploads.ErrNotFound):
+		return c.JSON(http.StatusNotFound, err.Error())
+	case errors.Is(err, uploads.ErrOffset):
+		return c.JSON(http.StatusConflict, err.Error())
+	case errors.Is(err, uploads.ErrLocked):
+		return c.JSON(http.StatusLocked, err.Error())
+	case errors.Is(err, uploads.ErrTooLarge):
+		return c.JSON(http.StatusRequestEntityTooLarge, err.Error())
+	}
+	return c.JSON(http.StatusBadRequest, err.Error())
+}
+
+// metadataType returns the media type of an upload from its filetype metadata, JSON by default.
+func metadataType(metadata string) string {
+	if metadataValue(metadata, "filetype") == mimeNDJSON {
+		return mimeNDJSON
+	}
+
+	return echo.MIMEApplicationJSON
+}
+
+// metadataOptions returns the import options of an upload from its source and conflict metadata.
+func metadataOptions(metadata string) (importOpts, error) {
+	return importOptions(metadataValue(metadata, "source"), metadataValue(metadata, "conflict"))
+}
+
+// metadataValue returns the decoded value of an Upload-Metadata key, empty if missing or invalid.
+func metadataValue(metadata, name string) string {
+	for _, pair := range strings.Split(metadata, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key != name {
+			continue
+		}
+
+		if b, err := base64.StdEncoding.DecodeString(value); err == nil {
+			return string(b)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,202 @@
+package rest
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/idempotency"
+	"github.com/agukrapo/ports/limits"
+	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/storage/memory"
+	"github.com/agukrapo/ports/uploads"
+	"github.com/stretchr/testify/require"
+)
+
+const testInput = `{"AEAJM": {"name": "Ajman", "coordinates": [55.5, 25.4]}, "AEAUH": {"name": "Abu Dhabi", "coordinates": [54.37, 24.47]}}`
+
+// newTestServer instantiates a Server deduplicating uploads, waiting for its background imports on cleanup.
+func newTestServer(t *testing.T, ttl time.Duration, lim limits.Limits) (*Server, *memory.Memory) {
+	store := memory.New()
+
+	sessions, err := uploads.NewStore(config.Uploads{Dir: t.TempDir(), SessionTTL: ttl, MaxSize: 1 << 20})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, sessions.Close()) })
+
+	s, err := New(config.REST{}, service.New(store), store, nil, lim, sessions, idempotency.New(store, time.Hour, time.Minute), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		s.cancel()
+		s.background.Wait()
+	})
+
+	return s, store
+}
+
+func serve(s *Server, method, target string, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(headerTusResumable, tusVersion)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, req)
+	return rec
+}
+
+// tusCreate creates an upload of the given size, returning its URL.
+func tusCreate(t *testing.T, s *Server, size int) string {
+	rec := serve(s, http.MethodPost, "/uploads", "", map[string]string{
+		headerUploadLength: strconv.Itoa(size),
+		headerUploadMeta:   "source " + base64.StdEncoding.EncodeToString([]byte("tus")),
+	})
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Equal(t, "0", rec.Header().Get(headerUploadOffset))
+	require.NotEmpty(t, rec.Header().Get(headerUploadExpires))
+
+	return rec.Header().Get("Location")
+}
+
+func tusPatch(s *Server, url string, offset int, data string) *httptest.ResponseRecorder {
+	return serve(s, http.MethodPatch, url, data, map[string]string{
+		headerUploadOffset: strconv.Itoa(offset),
+		"Content-Type":     mimeOffsetStream,
+	})
+}
+
+func TestServer_tus(t *testing.T) {
+	s, store := newTestServer(t, time.Hour, limits.Limits{Imports: limits.NewImports(1, 0)})
+
+	rec := serve(s, http.MethodOptions, "/uploads", "", nil)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, tusExtensions, rec.Header().Get(headerTusExtension))
+
+	rec = serve(s, http.MethodPost, "/uploads", "", map[string]string{headerTusResumable: "0.2.0", headerUploadLength: "1"})
+	require.Equal(t, http.StatusPreconditionFailed, rec.Code)
+
+	url := tusCreate(t, s, len(testInput))
+
+	rec = tusPatch(s, url, 0, testInput[:10])
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, "10", rec.Header().Get(headerUploadOffset))
+
+	rec = tusPatch(s, url, 0, testInput)
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = serve(s, http.MethodHead, url, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "10", rec.Header().Get(headerUploadOffset))
+	require.Equal(t, strconv.Itoa(len(testInput)), rec.Header().Get(headerUploadLength))
+
+	// The completing PATCH succeeds while every import slot is taken, the import waiting for one.
+	release, err := s.limits.Imports.Acquire(context.Background())
+	require.NoError(t, err)
+
+	rec = tusPatch(s, url, 10, testInput[10:])
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.NotEmpty(t, rec.Header().Get(headerImportID))
+
+	rec = serve(s, http.MethodHead, url, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	release()
+
+	require.Eventually(t, func() bool {
+		return serve(s, http.MethodHead, url, "", nil).Code == http.StatusNotFound
+	}, time.Second, 10*time.Millisecond)
+
+	sources, err := store.Sources(context.Background(), "AEAJM")
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	require.Equal(t, "tus", sources[0].Source)
+}
+
+func TestServer_tus_retry(t *testing.T) {
+	s, _ := newTestServer(t, time.Hour, limits.Limits{Imports: limits.NewImports(1, 0)})
+
+	first := tusCreate(t, s, len(testInput))
+	second := tusCreate(t, s, len(testInput))
+
+	release, err := s.limits.Imports.Acquire(context.Background())
+	require.NoError(t, err)
+
+	rec := tusPatch(s, first, 0, testInput)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	id := rec.Header().Get(headerImportID)
+
+	// The same content is still being imported, the second upload is kept to retry its import.
+	rec = tusPatch(s, second, 0, testInput)
+	require.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = serve(s, http.MethodHead, second, "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, strconv.Itoa(len(testInput)), rec.Header().Get(headerUploadOffset))
+
+	release()
+
+	require.Eventually(t, func() bool {
+		return serve(s, http.MethodHead, first, "", nil).Code == http.StatusNotFound
+	}, time.Second, 10*time.Millisecond)
+
+	rec = tusPatch(s, second, len(testInput), "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, id, rec.Header().Get(headerImportID))
+
+	rec = serve(s, http.MethodHead, second, "", nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_tus_invalid(t *testing.T) {
+	s, _ := newTestServer(t, time.Hour, limits.Limits{})
+
+	url := tusCreate(t, s, len("not json"))
+
+	rec := tusPatch(s, url, 0, "not json")
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = serve(s, http.MethodHead, url, "", nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_tus_delete(t *testing.T) {
+	s, _ := newTestServer(t, time.Hour, limits.Limits{})
+
+	url := tusCreate(t, s, len(testInput))
+
+	rec := serve(s, http.MethodDelete, url, "", nil)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = serve(s, http.MethodHead, url, "", nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = tusPatch(s, url, 0, testInput)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestServer_tus_expiry(t *testing.T) {
+	s, _ := newTestServer(t, 50*time.Millisecond, limits.Limits{})
+
+	url := tusCreate(t, s, len(testInput))
+
+	rec := tusPatch(s, url, 0, testInput[:10])
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	expires, err := http.ParseTime(rec.Header().Get(headerUploadExpires))
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), expires, 2*time.Second)
+
+	time.Sleep(100 * time.Millisecond)
+
+	rec = serve(s, http.MethodHead, url, "", nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = tusPatch(s, url, 10, testInput[10:])
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,363 @@
+package rest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/idempotency"
+	"github.com/agukrapo/ports/imports"
+	"github.com/agukrapo/ports/limits"
+	"github.com/agukrapo/ports/parser"
+	"github.com/agukrapo/ports/service"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	mimeNDJSON = "application/x-ndjson"
+
+	headerImportID = "X-Import-Id"
+)
+
+var errTooLarge = errors.New("request body too large")
+
+type accepted struct {
+	ID string `json:"id"`
+	// Report is only set for the duplicates of a finished import.
+	Report *service.Report `json:"report,omitempty"`
+}
+
+// upload imports a multipart/form-data file field, or a raw JSON or NDJSON body.
+func (s *Server) upload(c echo.Context) error {
+	if mediaType(c) == echo.MIMEMultipartForm {
+		return s.uploadMultipart(c)
+	}
+
+	return s.uploadRaw(c)
+}
+
+func (s *Server) uploadMultipart(c echo.Context) error {
+	req := c.Request()
+
+	opts, err := importOptions(c.QueryParam("source"), c.QueryParam("conflict"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if req.ContentLength > int64(s.cfg.MaxBodySize) {
+		return c.JSON(http.StatusRequestEntityTooLarge, errTooLarge.Error())
+	}
+	// The multipart reader does not wrap the body errors, which are kept by the limitedReader instead.
+	body := &limitedReader{r: req.Body, n: int64(s.cfg.MaxBodySize)}
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, req.Body}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(bodyStatus(body.err), err.Error())
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer safeClose(src)
+
+	if async(c) {
+		return s.importAsync(c, src, echo.MIMEApplicationJSON, opts)
+	}
+
+	sum, err := checksum(src)
+	if err != nil {
+		return err
+	}
+
+	p, err := parser.New(src)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	claim, dup, err := s.claim(c, sum, opts)
+	if err != nil {
+		return claimError(c, err)
+	}
+	if dup != nil {
+		return c.JSON(http.StatusOK, dup.Report)
+	}
+
+	imp, release, err := s.startImport(c)
+	if err != nil {
+		claim.Release()
+		return importError(c, err)
+	}
+	defer release()
+	claim.Start(imp.ID)
+
+	report := s.service.Process(req.Context(), p, opts.with(service.WithHooks(imp.Hooks()), service.WithImportID(imp.ID), service.WithSize(file.Size))...)
+	imp.Finish(report, req.Context().Err())
+	claim.Finish("", report, req.Context().Err())
+
+	return c.JSON(http.StatusOK, report)
+}
+
+// uploadRaw streams a JSON or NDJSON request body into the parser while it arrives.
+// Its checksum is only known once imported, so only the Idempotency-Key header deduplicates it.
+func (s *Server) uploadRaw(c echo.Context) error {
+	req := c.Request()
+
+	mt := mediaType(c)
+	if mt != echo.MIMEApplicationJSON && mt != mimeNDJSON {
+		return c.JSON(http.StatusUnsupportedMediaType, "content type must be "+echo.MIMEApplicationJSON+" or "+mimeNDJSON)
+	}
+
+	opts, err := importOptions(c.QueryParam("source"), c.QueryParam("conflict"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if req.ContentLength > int64(s.cfg.MaxBodySize) {
+		return c.JSON(http.StatusRequestEntityTooLarge, errTooLarge.Error())
+	}
+
+	body := &limitedReader{r: req.Body, n: int64(s.cfg.MaxBodySize)}
+
+	if async(c) {
+		return s.importAsync(c, body, mt, opts)
+	}
+
+	h := sha256.New()
+	tee := io.TeeReader(body, h)
+
+	src, err := newSource(tee, mt)
+	if err != nil {
+		return c.JSON(bodyStatus(body.err), err.Error())
+	}
+
+	claim, dup, err := s.claim(c, "", opts)
+	if err != nil {
+		return claimError(c, err)
+	}
+	if dup != nil {
+		return c.JSON(http.StatusOK, dup.Report)
+	}
+
+	imp, release, err := s.startImport(c)
+	if err != nil {
+		claim.Release()
+		return importError(c, err)
+	}
+	defer release()
+	claim.Start(imp.ID)
+
+	report := s.service.Process(req.Context(), src, opts.with(service.WithHooks(imp.Hooks()), service.WithImportID(imp.ID), service.WithSize(req.ContentLength))...)
+	imp.Finish(report, body.err)
+
+	// The parser may stop before the end of the body, whose rest still counts for the checksum.
+	_, _ = io.Copy(io.Discard, tee)
+
+	err = body.err
+	if err == nil {
+		err = req.Context().Err()
+	}
+	claim.Finish(hex.EncodeToString(h.Sum(nil)), report, err)
+
+	if body.err != nil {
+		return c.JSON(bodyStatus(body.err), report)
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+func newSource(r io.Reader, mediaType string) (parser.Source, error) {
+	if mediaType == mimeNDJSON {
+		return parser.NewNDJSON(r)
+	}
+	return parser.New(r)
+}
+
+// startImport registers an import once a concurrent import slot is available, the returned function releases it.
+func (s *Server) startImport(c echo.Context) (*imports.Import, func(), error) {
+	release, err := s.limits.Imports.Acquire(c.Request().Context())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.register(c), release, nil
+}
+
+// register registers an import, setting its ID in the response headers.
+func (s *Server) register(c echo.Context) *imports.Import {
+	imp := s.imports.Start("rest " + c.RealIP())
+	c.Response().Header().Set(headerImportID, imp.ID)
+	return imp
+}
+
+// importAsync spools the body to a temporary file and imports it in the background,
+// responding right away with the import ID.
+func (s *Server) importAsync(c echo.Context, body io.Reader, mediaType string, opts importOpts) error {
+	tmp, err := os.CreateTemp(os.TempDir(), "upload")
+	if err != nil {
+		return err
+	}
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), body)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		discard(tmp)
+		return c.JSON(bodyStatus(err), err.Error())
+	}
+
+	src, err := newSource(tmp, mediaType)
+	if err != nil {
+		discard(tmp)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	claim, dup, err := s.claim(c, hex.EncodeToString(h.Sum(nil)), opts)
+	if err != nil {
+		discard(tmp)
+		return claimError(c, err)
+	}
+	if dup != nil {
+		discard(tmp)
+		return c.JSON(http.StatusOK, accepted{ID: dup.ImportID, Report: &dup.Report})
+	}
+
+	imp, release, err := s.startImport(c)
+	if err != nil {
+		claim.Release()
+		discard(tmp)
+		return importError(c, err)
+	}
+
+	s.processAsync(imp, claim, src, size, opts, func() {
+		release()
+		discard(tmp)
+	})
+
+	c.Response().Header().Set(echo.HeaderLocation, "/imports/"+imp.ID)
+	return c.JSON(http.StatusAccepted, accepted{ID: imp.ID})
+}
+
+// processAsync runs an import, and its idempotency claim if any, in the background until the server shuts down,
+// calling done once finished.
+func (s *Server) processAsync(imp *imports.Import, claim *idempotency.Claim, src parser.Source, size int64, opts importOpts, done func()) {
+	claim.Start(imp.ID)
+
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		defer done()
+
+		report := s.service.Process(s.ctx, src, opts.with(service.WithHooks(imp.Hooks()), service.WithImportID(imp.ID), service.WithSize(size))...)
+		imp.Finish(report, s.ctx.Err())
+		claim.Finish("", report, s.ctx.Err())
+	}()
+}
+
+// importError responds 429 when the concurrent imports cap is reached, returning any other error as is.
+func importError(c echo.Context, err error) error {
+	if errors.Is(err, limits.ErrBusy) {
+		c.Response().Header().Set(headerRetryAfter, retryAfter)
+		return c.JSON(http.StatusTooManyRequests, err.Error())
+	}
+	return err
+}
+
+// discard closes and removes a temporary file.
+func discard(f *os.File) {
+	safeClose(f)
+	if err := os.Remove(f.Name()); err != nil {
+		log.Error().Err(err).Msg("Temporary file removal failed")
+	}
+}
+
+func async(c echo.Context) bool {
+	return c.QueryParam("async") == "true"
+}
+
+// importOpts represents the source and conflict policy of an upload.
+type importOpts struct {
+	source   string
+	conflict database.Conflict
+}
+
+// importOptions validates the source and conflict policy of an upload, given by its query parameters or tus metadata.
+func importOptions(source, conflict string) (importOpts, error) {
+	if err := service.ValidateSource(source); err != nil {
+		return importOpts{}, err
+	}
+	if source == "" {
+		source = database.DefaultSource
+	}
+
+	c, err := database.ParseConflict(conflict)
+	if err != nil {
+		return importOpts{}, err
+	}
+
+	return importOpts{source: source, conflict: c}, nil
+}
+
+// with returns the service.Process options of an upload along with others.
+func (o importOpts) with(opts ...service.Option) []service.Option {
+	return append(opts, service.WithSource(o.source), service.WithConflict(o.conflict))
+}
+
+func mediaType(c echo.Context) string {
+	mt, _, err := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if err != nil {
+		return ""
+	}
+	return mt
+}
+
+// bodyStatus returns 413 for errTooLarge, 400 otherwise.
+func bodyStatus(err error) int {
+	if errors.Is(err, errTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// limitedReader fails with errTooLarge once more than n bytes are read, keeping the first read error.
+type limitedReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+
+	if l.n < 0 {
+		l.err = errTooLarge
+		return 0, l.err
+	}
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		l.err = err
+	}
+
+	return n, err
+}
@@ -0,0 +1,54 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/imports"
+	"github.com/agukrapo/ports/limits"
+	"github.com/stretchr/testify/require"
+)
+
+// multipartRequest returns an upload request of a file field.
+func multipartRequest(t *testing.T, ctx context.Context, data string) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	f, err := w.CreateFormFile("file", "ports.json")
+	require.NoError(t, err)
+	_, err = f.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPut, "/upload", &body).WithContext(ctx)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestServer_uploadMultipart_canceled(t *testing.T) {
+	s, _ := newTestServer(t, time.Hour, limits.Limits{})
+	s.cfg.MaxBodySize = 1 << 20
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rec := httptest.NewRecorder()
+	s.e.ServeHTTP(rec, multipartRequest(t, ctx, testInput))
+
+	imp, ok := s.imports.Get(rec.Header().Get(headerImportID))
+	require.True(t, ok)
+	require.Equal(t, imports.Failed, imp.State().Status, "an aborted import is not completed")
+
+	rec = httptest.NewRecorder()
+	s.e.ServeHTTP(rec, multipartRequest(t, context.Background(), testInput))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get(headerReplayed), "an aborted import is not replayed")
+
+	imp, ok = s.imports.Get(rec.Header().Get(headerImportID))
+	require.True(t, ok)
+	require.Equal(t, imports.Completed, imp.State().Status)
+}
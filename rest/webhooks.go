@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/webhooks"
+	"github.com/labstack/echo/v4"
+)
+
+const defaultDeliveries = 50
+
+func (s *Server) listWebhooks(c echo.Context) error {
+	hooks, err := s.webhooks.List(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	if hooks == nil {
+		hooks = []database.Webhook{}
+	}
+
+	return c.JSON(http.StatusOK, hooks)
+}
+
+// createWebhook registers a webhook, the response being the only one carrying its signing secret.
+func (s *Server) createWebhook(c echo.Context) error {
+	var in webhooks.Input
+	if err := c.Bind(&in); err != nil {
+		return c.JSON(http.StatusBadRequest, "invalid webhook JSON")
+	}
+
+	hook, err := s.webhooks.Create(c.Request().Context(), in)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, hook)
+}
+
+func (s *Server) getWebhook(c echo.Context) error {
+	hook, err := s.webhooks.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, hook)
+}
+
+func (s *Server) updateWebhook(c echo.Context) error {
+	var in webhooks.Input
+	if err := c.Bind(&in); err != nil {
+		return c.JSON(http.StatusBadRequest, "invalid webhook JSON")
+	}
+
+	hook, err := s.webhooks.Update(c.Request().Context(), c.Param("id"), in)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, hook)
+}
+
+func (s *Server) deleteWebhook(c echo.Context) error {
+	if err := s.webhooks.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return webhookError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// webhookDeliveries returns the delivery log of a webhook, newest first.
+func (s *Server) webhookDeliveries(c echo.Context) error {
+	limit, err := intParam(c, "limit", defaultDeliveries)
+	if err != nil || limit < 1 || limit > maxLimit {
+		return c.JSON(http.StatusBadRequest, "invalid limit, must be between 1 and "+strconv.Itoa(maxLimit))
+	}
+
+	deliveries, err := s.webhooks.Deliveries(c.Request().Context(), c.Param("id"), limit)
+	if err != nil {
+		return webhookError(c, err)
+	}
+
+	if deliveries == nil {
+		deliveries = []database.Delivery{}
+	}
+
+	return c.JSON(http.StatusOK, deliveries)
+}
+
+func webhookError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, webhooks.ErrNotFound):
+		return c.JSON(http.StatusNotFound, "webhook not found")
+	case errors.Is(err, webhooks.ErrInvalid):
+		return c.JSON(http.StatusBadRequest, err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,359 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agukrapo/ports/cdc"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/imports"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// wsSendBuffer is the number of messages queued per connection, a client falling further behind is dropped.
+	wsSendBuffer       = 256
+	wsWriteTimeout     = 10 * time.Second
+	wsMaxMessage       = 4 << 10
+	wsMaxSubscriptions = 32
+	// queryToken is the query parameter carrying the credentials of the clients unable to set headers, such as browsers.
+	queryToken = "access_token"
+)
+
+// WebSocket message types.
+const (
+	wsSubscribe    = "subscribe"
+	wsUnsubscribe  = "unsubscribe"
+	wsSubscribed   = "subscribed"
+	wsUnsubscribed = "unsubscribed"
+	wsPortChanged  = "port.changed"
+	wsHeartbeat    = "heartbeat"
+	wsError        = "error"
+)
+
+// WebSocket subscription topics.
+const (
+	topicPorts  = "ports"
+	topicImport = "import"
+)
+
+// errDropped is returned when a slow client is dropped.
+var errDropped = errors.New("client dropped")
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// Clients authenticate with tokens, never cookies, so any origin may connect.
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// wsRequest represents a message sent by a client.
+type wsRequest struct {
+	Type string `json:"type"`
+	// ID identifies the subscription, chosen by the client.
+	ID    string `json:"id"`
+	Topic string `json:"topic"`
+	// Keys, Country, City, BBox and ResumeToken apply to the ports topic.
+	Keys        []string `json:"keys"`
+	Country     string   `json:"country"`
+	City        string   `json:"city"`
+	BBox        string   `json:"bbox"`
+	ResumeToken string   `json:"resume_token"`
+	// ImportID applies to the import topic.
+	ImportID string `json:"import_id"`
+}
+
+// wsMessage represents a message sent to a client.
+type wsMessage struct {
+	Type        string      `json:"type"`
+	ID          string      `json:"id,omitempty"`
+	Data        interface{} `json:"data,omitempty"`
+	ResumeToken string      `json:"resume_token,omitempty"`
+	Error       string      `json:"error,omitempty"`
+}
+
+// wsConn represents a client connection, its messages being written by a single goroutine.
+type wsConn struct {
+	conn   *websocket.Conn
+	send   chan wsMessage
+	ctx    context.Context
+	cancel context.CancelFunc
+	slow   int32
+
+	mu   sync.Mutex
+	subs map[string]*wsSub
+	wg   sync.WaitGroup
+}
+
+// wsSub represents a subscription, whose ID may be reused by the client once it ends.
+type wsSub struct {
+	cancel context.CancelFunc
+}
+
+// remove removes a subscription, unless its ID was already reused by another one.
+func (w *wsConn) remove(id string, sub *wsSub) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.subs[id] == sub {
+		delete(w.subs, id)
+	}
+}
+
+// push queues a message without blocking, dropping the client when its queue is full.
+func (w *wsConn) push(m wsMessage) error {
+	select {
+	case w.send <- m:
+		return nil
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	default:
+		atomic.StoreInt32(&w.slow, 1)
+		w.cancel()
+		return errDropped
+	}
+}
+
+// fromQuery moves the credentials of the query token parameter to the Authorization header, out of the logged URL.
+func fromQuery(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		req := c.Request()
+		q := req.URL.Query()
+
+		if token := q.Get(queryToken); token != "" {
+			if req.Header.Get(echo.HeaderAuthorization) == "" {
+				req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+			}
+			q.Del(queryToken)
+			req.URL.RawQuery = q.Encode()
+		}
+
+		return next(c)
+	}
+}
+
+// websocket serves the live port changes and import progress events, as subscribed by the client.
+func (s *Server) websocket(c echo.Context) error {
+	conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		// The upgrader already responded.
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	w := &wsConn{
+		conn:   conn,
+		send:   make(chan wsMessage, wsSendBuffer),
+		ctx:    ctx,
+		cancel: cancel,
+		subs:   make(map[string]*wsSub),
+	}
+
+	go s.wsRead(w)
+	s.wsWrite(w)
+
+	cancel()
+	w.wg.Wait()
+
+	return nil
+}
+
+// wsRead handles the client messages until the connection fails.
+func (s *Server) wsRead(w *wsConn) {
+	defer w.cancel()
+
+	w.conn.SetReadLimit(wsMaxMessage)
+	extend := func() error {
+		return w.conn.SetReadDeadline(time.Now().Add(2 * heartbeatInterval))
+	}
+	_ = extend()
+	w.conn.SetPongHandler(func(string) error { return extend() })
+
+	for {
+		_, data, err := w.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = extend()
+
+		var req wsRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			if w.push(wsMessage{Type: wsError, Error: "invalid message JSON"}) != nil {
+				return
+			}
+			continue
+		}
+
+		if err := s.wsHandle(w, &req); err != nil {
+			if w.push(wsMessage{Type: wsError, ID: req.ID, Error: err.Error()}) != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) wsHandle(w *wsConn, req *wsRequest) error {
+	switch req.Type {
+	case wsSubscribe:
+		return s.wsSubscribe(w, req)
+	case wsUnsubscribe:
+		w.mu.Lock()
+		sub, ok := w.subs[req.ID]
+		w.mu.Unlock()
+
+		if !ok {
+			return errors.New("unknown subscription")
+		}
+		w.remove(req.ID, sub)
+		sub.cancel()
+
+		return w.push(wsMessage{Type: wsUnsubscribed, ID: req.ID})
+	default:
+		return errors.New("unknown message type, must be subscribe or unsubscribe")
+	}
+}
+
+func (s *Server) wsSubscribe(w *wsConn, req *wsRequest) error {
+	var run func(context.Context) error
+
+	switch req.Topic {
+	case topicPorts:
+		filter := database.Filter{Keys: req.Keys, Country: req.Country, City: req.City}
+		if req.BBox != "" {
+			b, err := database.ParseBBox(req.BBox)
+			if err != nil {
+				return err
+			}
+			filter.BBox = b
+		}
+		run = func(ctx context.Context) error { return s.wsPorts(ctx, w, req.ID, filter, req.ResumeToken) }
+	case topicImport:
+		imp, ok := s.imports.Get(req.ImportID)
+		if !ok {
+			return errors.New("import not found")
+		}
+		run = func(ctx context.Context) error { return wsImport(ctx, w, req.ID, imp) }
+	default:
+		return errors.New("unknown topic, must be ports or import")
+	}
+
+	if req.ID == "" {
+		return errors.New("subscription id missing")
+	}
+
+	ctx, cancel := context.WithCancel(w.ctx)
+	sub := &wsSub{cancel: cancel}
+
+	w.mu.Lock()
+	if _, ok := w.subs[req.ID]; ok {
+		w.mu.Unlock()
+		cancel()
+		return errors.New("subscription id already used")
+	}
+	if len(w.subs) >= wsMaxSubscriptions {
+		w.mu.Unlock()
+		cancel()
+		return errors.New("too many subscriptions")
+	}
+	w.subs[req.ID] = sub
+	w.mu.Unlock()
+
+	if err := w.push(wsMessage{Type: wsSubscribed, ID: req.ID}); err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer cancel()
+
+		err := run(ctx)
+		if err != nil && ctx.Err() == nil {
+			_ = w.push(wsMessage{Type: wsError, ID: req.ID, Error: err.Error()})
+		}
+
+		w.remove(req.ID, sub)
+	}()
+
+	return nil
+}
+
+// wsPorts pushes the port changes matching a filter, a change matching when the port does before or after it.
+// The changes are followed once for all the subscriptions of the server.
+func (s *Server) wsPorts(ctx context.Context, w *wsConn, id string, filter database.Filter, token string) error {
+	return s.changes.Follow(ctx, token, func(e *cdc.Event) error {
+		if (e.Before == nil || !filter.Match(e.Before)) && (e.After == nil || !filter.Match(e.After)) {
+			return nil
+		}
+
+		return w.push(wsMessage{Type: wsPortChanged, ID: id, Data: e, ResumeToken: e.Token()})
+	})
+}
+
+// wsImport pushes the progress events of an import, until its summary event.
+func wsImport(ctx context.Context, w *wsConn, id string, imp *imports.Import) error {
+	events, unsubscribe := imp.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			if err := w.push(wsMessage{Type: "import." + string(e.Type), ID: id, Data: e}); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// wsWrite writes the queued messages and the heartbeats, until the connection fails, is dropped or the server stops.
+func (s *Server) wsWrite(w *wsConn) {
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	defer w.conn.Close()
+
+	for {
+		select {
+		case m := <-w.send:
+			_ = w.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := w.conn.WriteJSON(m); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			deadline := time.Now().Add(wsWriteTimeout)
+			if err := w.conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				return
+			}
+			_ = w.conn.SetWriteDeadline(deadline)
+			if err := w.conn.WriteJSON(wsMessage{Type: wsHeartbeat}); err != nil {
+				return
+			}
+		case <-s.closing:
+			w.close(websocket.CloseGoingAway, "server shutting down")
+			return
+		case <-w.ctx.Done():
+			if atomic.LoadInt32(&w.slow) == 1 {
+				log.Warn().Str("client", w.conn.RemoteAddr().String()).Msg("WebSocket client too slow, dropped")
+				w.close(websocket.CloseTryAgainLater, "too slow")
+			}
+			return
+		}
+	}
+}
+
+func (w *wsConn) close(code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	_ = w.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteTimeout))
+}
@@ -0,0 +1,163 @@
+package rest
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/idempotency"
+	"github.com/agukrapo/ports/limits"
+	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/storage/memory"
+	"github.com/gorilla/websocket"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// newWSServer serves a Server keeping a change log over HTTP, returning its WebSocket URL along with its database.
+func newWSServer(t *testing.T) (string, *database.Database) {
+	cfg := config.Default().Database
+	cfg.DSN = "sqlite://" + filepath.Join(t.TempDir(), "ports.db")
+
+	m, err := database.NewMigrator(cfg)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+
+	db, err := database.New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	s, err := New(config.REST{}, service.New(db), db, nil, limits.Limits{}, nil, idempotency.New(nil, 0, 0), nil)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(s.e)
+	t.Cleanup(func() {
+		srv.Close()
+		s.cancel()
+	})
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws", db
+}
+
+func wsDial(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func wsSend(t *testing.T, conn *websocket.Conn, req wsRequest) {
+	require.NoError(t, conn.WriteJSON(req))
+}
+
+func wsReceive(t *testing.T, conn *websocket.Conn) wsMessage {
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	var m wsMessage
+	require.NoError(t, conn.ReadJSON(&m))
+	return m
+}
+
+func TestServer_websocket_ports(t *testing.T) {
+	ctx := context.Background()
+	url, db := newWSServer(t)
+
+	first := wsDial(t, url)
+	second := wsDial(t, url)
+
+	wsSend(t, first, wsRequest{Type: wsSubscribe, ID: "uae", Topic: topicPorts, Country: "United Arab Emirates"})
+	require.Equal(t, wsMessage{Type: wsSubscribed, ID: "uae"}, wsReceive(t, first))
+	wsSend(t, second, wsRequest{Type: wsSubscribe, ID: "all", Topic: topicPorts})
+	require.Equal(t, wsMessage{Type: wsSubscribed, ID: "all"}, wsReceive(t, second))
+
+	// The subscriptions follow the changes from now on, once the follower starts.
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, db.Merge(ctx, &database.Port{Key: "ARBUE", Name: "Buenos Aires", Country: "Argentina", Unlocs: pq.StringArray{}, Alias: pq.StringArray{}}, database.MergeOptions{}))
+	require.NoError(t, db.Merge(ctx, &database.Port{Key: "AEAJM", Name: "Ajman", Country: "United Arab Emirates", Unlocs: pq.StringArray{}, Alias: pq.StringArray{}}, database.MergeOptions{}))
+	_, err := db.SequenceChanges(ctx, 10)
+	require.NoError(t, err)
+
+	m := wsReceive(t, first)
+	require.Equal(t, wsPortChanged, m.Type)
+	require.Equal(t, "uae", m.ID)
+	require.Equal(t, "AEAJM", m.Data.(map[string]interface{})["key"])
+	token := m.ResumeToken
+
+	for _, key := range []string{"ARBUE", "AEAJM"} {
+		m := wsReceive(t, second)
+		require.Equal(t, wsPortChanged, m.Type)
+		require.Equal(t, key, m.Data.(map[string]interface{})["key"])
+	}
+
+	// A subscription resuming from a token receives the changes following it.
+	wsSend(t, second, wsRequest{Type: wsSubscribe, ID: "resumed", Topic: topicPorts, ResumeToken: "0"})
+	require.Equal(t, wsMessage{Type: wsSubscribed, ID: "resumed"}, wsReceive(t, second))
+	for _, key := range []string{"ARBUE", "AEAJM"} {
+		m = wsReceive(t, second)
+		require.Equal(t, "resumed", m.ID)
+		require.Equal(t, key, m.Data.(map[string]interface{})["key"])
+	}
+	require.Equal(t, token, m.ResumeToken)
+
+	wsSend(t, second, wsRequest{Type: wsSubscribe, ID: "bad", Topic: topicPorts, ResumeToken: "x"})
+	require.Equal(t, wsMessage{Type: wsSubscribed, ID: "bad"}, wsReceive(t, second))
+	m = wsReceive(t, second)
+	require.Equal(t, wsError, m.Type)
+	require.Equal(t, "bad", m.ID)
+}
+
+// slowLog represents a storage whose change log is slow to read, ignoring cancellation.
+type slowLog struct {
+	*memory.Memory
+}
+
+func (slowLog) Changes(context.Context, int64, int) ([]database.Change, error) {
+	time.Sleep(100 * time.Millisecond)
+	return nil, nil
+}
+
+func (slowLog) ChangeLogBounds(context.Context) (int64, int64, error) {
+	time.Sleep(100 * time.Millisecond)
+	return 0, 0, nil
+}
+
+func TestServer_websocket_reuse(t *testing.T) {
+	store := slowLog{memory.New()}
+
+	s, err := New(config.REST{}, service.New(store), store, nil, limits.Limits{}, nil, idempotency.New(nil, 0, 0), nil)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(s.e)
+	t.Cleanup(func() {
+		srv.Close()
+		s.cancel()
+	})
+
+	conn := wsDial(t, "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws")
+
+	wsSend(t, conn, wsRequest{Type: wsSubscribe, ID: "a", Topic: topicPorts})
+	require.Equal(t, wsMessage{Type: wsSubscribed, ID: "a"}, wsReceive(t, conn))
+	wsSend(t, conn, wsRequest{Type: wsUnsubscribe, ID: "a"})
+	require.Equal(t, wsMessage{Type: wsUnsubscribed, ID: "a"}, wsReceive(t, conn))
+
+	// The ID is reused while the unsubscribed subscription still ends, which leaves the new one in place.
+	wsSend(t, conn, wsRequest{Type: wsSubscribe, ID: "a", Topic: topicPorts})
+	require.Equal(t, wsMessage{Type: wsSubscribed, ID: "a"}, wsReceive(t, conn))
+
+	time.Sleep(300 * time.Millisecond)
+
+	wsSend(t, conn, wsRequest{Type: wsSubscribe, ID: "a", Topic: topicPorts})
+	require.Equal(t, wsMessage{Type: wsError, ID: "a", Error: "subscription id already used"}, wsReceive(t, conn))
+
+	wsSend(t, conn, wsRequest{Type: wsUnsubscribe, ID: "a"})
+	require.Equal(t, wsMessage{Type: wsUnsubscribed, ID: "a"}, wsReceive(t, conn))
+}
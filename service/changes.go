@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/agukrapo/ports/database"
+)
+
+// ErrNoChangeLog is returned when reading the changes of a Service whose storage keeps no change log.
+var ErrNoChangeLog = errors.New("change log unsupported by the storage")
+
+// changeLog represents a storage keeping the port changes, implemented by the database backend.
+type changeLog interface {
+	Changes(context.Context, int64, int) ([]database.Change, error)
+	ChangeLogBounds(context.Context) (int64, int64, error)
+}
+
+// Changes returns the port changes following a sequence number, in commit order, up to limit.
+func (s *Service) Changes(ctx context.Context, after int64, limit int) ([]database.Change, error) {
+	log, ok := s.storage.(changeLog)
+	if !ok {
+		return nil, ErrNoChangeLog
+	}
+
+	return log.Changes(ctx, after, limit)
+}
+
+// ChangeLogBounds returns the sequence numbers of the first and last port changes kept, zero when there are none.
+func (s *Service) ChangeLogBounds(ctx context.Context) (int64, int64, error) {
+	log, ok := s.storage.(changeLog)
+	if !ok {
+		return 0, 0, ErrNoChangeLog
+	}
+
+	return log.ChangeLogBounds(ctx)
+}
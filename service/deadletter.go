@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNoDeadLetters is returned when reading the rejected records of a Service without dead-letter sink.
+var ErrNoDeadLetters = errors.New("dead-letter sink disabled")
+
+// DeadLetter represents a rejected record, as stored in a dead-letter sink.
+type DeadLetter struct {
+	ImportID string `json:"import_id"`
+	// Source and Conflict are the ones the record was imported with, empty for the defaults.
+	Source   string            `json:"source,omitempty"`
+	Conflict database.Conflict `json:"conflict,omitempty"`
+	Key      string            `json:"key,omitempty"`
+	Error    string            `json:"error"`
+	// Record holds the original JSON fragment, missing when the input could not be read.
+	Record     json.RawMessage `json:"record,omitempty"`
+	RejectedAt time.Time       `json:"rejected_at"`
+}
+
+// DeadLetters represents a dead-letter sink, storing the rejected records.
+type DeadLetters interface {
+	Write(context.Context, *DeadLetter) error
+	// Each calls fn for every DeadLetter of an import, in rejection order.
+	Each(ctx context.Context, importID string, fn func(*DeadLetter) error) error
+}
+
+// WithDeadLetters writes the records rejected by every Process call to a sink.
+func WithDeadLetters(sink DeadLetters) ServiceOption {
+	return func(s *Service) {
+		s.deadLetters = sink
+	}
+}
+
+// WithImportID identifies the dead letters of a Process call.
+func WithImportID(id string) Option {
+	return func(o *options) {
+		o.importID = id
+	}
+}
+
+// DeadLetters calls fn for every record rejected by an import, or returns ErrNoDeadLetters.
+func (s *Service) DeadLetters(ctx context.Context, importID string, fn func(*DeadLetter) error) error {
+	if s.deadLetters == nil {
+		return ErrNoDeadLetters
+	}
+
+	return s.deadLetters.Each(ctx, importID, fn)
+}
+
+func (s *Service) deadLetter(ctx context.Context, o *options, key string, record json.RawMessage, err error) {
+	if s.deadLetters == nil {
+		return
+	}
+
+	letter := &DeadLetter{
+		ImportID:   o.importID,
+		Source:     o.source,
+		Conflict:   o.conflict,
+		Key:        key,
+		Error:      err.Error(),
+		Record:     record,
+		RejectedAt: time.Now().UTC(),
+	}
+
+	if err := s.deadLetters.Write(ctx, letter); err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Dead letter write failed")
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Duplicates tells how a key already processed from another source is handled.
+type Duplicates string
+
+// Available Duplicates policies.
+const (
+	// DuplicatesFirst keeps the Port from the first source, skipping the rest.
+	DuplicatesFirst Duplicates = "first"
+	// DuplicatesLast stores every Port, so the last processed source wins.
+	DuplicatesLast Duplicates = "last"
+	// DuplicatesError rejects the Port from every source but the first.
+	DuplicatesError Duplicates = "error"
+)
+
+// ParseDuplicates validates a Duplicates policy name.
+func ParseDuplicates(s string) (Duplicates, error) {
+	switch d := Duplicates(s); d {
+	case DuplicatesFirst, DuplicatesLast, DuplicatesError:
+		return d, nil
+	default:
+		return "", fmt.Errorf("invalid duplicates policy %q, must be %s, %s or %s", s, DuplicatesFirst, DuplicatesLast, DuplicatesError)
+	}
+}
+
+// Keys tracks the keys processed across several sources, it is safe for concurrent use.
+type Keys struct {
+	policy Duplicates
+
+	mu     sync.Mutex
+	owners map[string]string
+}
+
+// NewKeys instantiates a new Keys.
+func NewKeys(policy Duplicates) *Keys {
+	return &Keys{
+		policy: policy,
+		owners: make(map[string]string),
+	}
+}
+
+// claim registers a key for a source, telling if its Port must be stored.
+// The returned function undoes the registration, to be called when the Port fails to be stored.
+func (k *Keys) claim(key, source string) (bool, func(), error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	owner, ok := k.owners[key]
+	switch {
+	case ok && owner == source:
+		return true, func() {}, nil
+	case ok && k.policy == DuplicatesFirst:
+		return false, nil, nil
+	case ok && k.policy == DuplicatesError:
+		return false, nil, fmt.Errorf("key %s: duplicated, already imported from %s", key, owner)
+	}
+
+	k.owners[key] = source
+
+	return true, func() { k.release(key, source, owner) }, nil
+}
+
+// release gives a key claimed by a source back to its previous owner, none if empty.
+func (k *Keys) release(key, source, previous string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.owners[key] != source {
+		return
+	}
+
+	if previous == "" {
+		delete(k.owners, key)
+	} else {
+		k.owners[key] = previous
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"time"
+)
+
+// progressInterval is the minimum time between two Hooks.Progress calls.
+const progressInterval = 500 * time.Millisecond
+
+// Progress represents the state of a running Process call.
+type Progress struct {
+	Processed int     `json:"processed"`
+	Rejected  int     `json:"rejected"`
+	Rate      float64 `json:"rate"`
+	// ETA is the estimated remaining time, zero when the source size is unknown.
+	ETA time.Duration `json:"eta"`
+}
+
+// Rejection represents a Port a Process call could not store.
+type Rejection struct {
+	Key   string `json:"key,omitempty"`
+	Error string `json:"error"`
+}
+
+// Hooks are called by Process while it runs, they must not block.
+type Hooks struct {
+	// Progress is called periodically and once more when the source is exhausted.
+	Progress func(Progress)
+	// Reject is called for every rejected Port.
+	Reject func(Rejection)
+}
+
+// WithHooks sets the Hooks a Process call reports to.
+func WithHooks(h Hooks) Option {
+	return func(o *options) {
+		o.hooks = h
+	}
+}
+
+// WithSize sets the source size in bytes, enabling ETA estimations for sources reporting their read offset.
+func WithSize(size int64) Option {
+	return func(o *options) {
+		o.size = size
+	}
+}
+
+type offsetter interface {
+	Offset() int64
+}
+
+// tracker calls the Progress hook at most once per progressInterval.
+type tracker struct {
+	hooks  Hooks
+	src    source
+	size   int64
+	start  time.Time
+	last   time.Time
+	report *Report
+}
+
+func (t *tracker) tick(force bool) {
+	if t.hooks.Progress == nil {
+		return
+	}
+
+	now := time.Now()
+	if !force && now.Sub(t.last) < progressInterval {
+		return
+	}
+	t.last = now
+
+	elapsed := now.Sub(t.start)
+
+	p := Progress{
+		Processed: t.report.Processed,
+		Rejected:  t.report.Rejected,
+	}
+
+	if elapsed > 0 {
+		p.Rate = float64(t.report.Processed) / elapsed.Seconds()
+	}
+
+	if o, ok := t.src.(offsetter); ok && t.size > 0 && !force {
+		if read := o.Offset(); read > 0 && read < t.size {
+			p.ETA = time.Duration(float64(elapsed) * float64(t.size-read) / float64(read))
+		}
+	}
+
+	t.hooks.Progress(p)
+}
+
+func (t *tracker) reject(key string, err error) {
+	if t.hooks.Reject != nil {
+		t.hooks.Reject(Rejection{Key: key, Error: err.Error()})
+	}
+}
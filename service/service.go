@@ -3,11 +3,15 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/export"
 	"github.com/agukrapo/ports/parser"
 	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
 )
 
 type source interface {
@@ -15,40 +19,250 @@ type source interface {
 }
 
 type storage interface {
-	Upsert(*database.Port) error
+	Merge(context.Context, *database.Port, database.MergeOptions) error
+	Sources(context.Context, string) ([]database.SourcePort, error)
+	List(context.Context, database.Filter, int, int) ([]database.Port, error)
+	Each(context.Context, database.Filter, func(*database.Port) error) error
 }
 
 // Service represents a process that moves ports from a source to a destination.
 type Service struct {
-	storage storage
+	storage     storage
+	writes      *rate.Limiter
+	retry       database.Backoff
+	deadLetters DeadLetters
+	precedence  database.Precedence
+}
+
+// ServiceOption customizes a Service.
+type ServiceOption func(*Service)
+
+// WithWriteRate caps the ports written per second by all the Process calls together, zero means unlimited.
+func WithWriteRate(perSecond int) ServiceOption {
+	return func(s *Service) {
+		if perSecond > 0 {
+			s.writes = rate.NewLimiter(rate.Limit(perSecond), perSecond)
+		}
+	}
+}
+
+// WithRetries retries the writes failing with a transient error according to a backoff policy.
+func WithRetries(b database.Backoff) ServiceOption {
+	return func(s *Service) {
+		s.retry = b
+	}
+}
+
+// WithPrecedence merges the ports imported from several sources according to a precedence policy.
+func WithPrecedence(p database.Precedence) ServiceOption {
+	return func(s *Service) {
+		s.precedence = p
+	}
 }
 
 // New instantiates a new Service.
-func New(storage storage) *Service {
-	return &Service{
+func New(storage storage, opts ...ServiceOption) *Service {
+	s := &Service{
 		storage: storage,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Report represents the outcome of a Process call.
+type Report struct {
+	// Source names the source the ports were imported from.
+	Source string `json:"source,omitempty"`
+	// Conflict is the policy the ports were written with.
+	Conflict  database.Conflict `json:"conflict,omitempty"`
+	Processed int               `json:"processed"`
+	Upserted  int               `json:"upserted"`
+	Rejected  int               `json:"rejected"`
+	Skipped   int               `json:"skipped"`
+	// Retries counts the writes retried after a transient error.
+	Retries  int           `json:"retries"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Add accumulates the counters of another Report into this one, the Duration is left as is.
+func (r *Report) Add(other Report) {
+	r.Processed += other.Processed
+	r.Upserted += other.Upserted
+	r.Rejected += other.Rejected
+	r.Skipped += other.Skipped
+	r.Retries += other.Retries
 }
 
-// Process moves a Port from a source to the storage.
-func (s *Service) Process(ctx context.Context, src source) {
-	var out database.Port
+// Option customizes a Process call.
+type Option func(*options)
+
+type options struct {
+	keys     *Keys
+	name     string
+	hooks    Hooks
+	size     int64
+	importID string
+	source   string
+	conflict database.Conflict
+}
+
+// WithKeys resolves keys already processed from other sources according to the Keys policy,
+// name identifies the current source.
+func WithKeys(keys *Keys, name string) Option {
+	return func(o *options) {
+		o.keys = keys
+		o.name = name
+	}
+}
+
+// WithSource tags the imported ports with a source name, database.DefaultSource if empty, see ValidateSource.
+func WithSource(name string) Option {
+	return func(o *options) {
+		o.source = name
+	}
+}
+
+// WithConflict writes the imported ports according to a Conflict policy, database.ConflictOverwrite if empty.
+// The ports it skips count as skipped, and the ones it rejects as rejected.
+func WithConflict(c database.Conflict) Option {
+	return func(o *options) {
+		o.conflict = c
+	}
+}
+
+// Process moves a Port from a source to the storage, merging it with the values of its other sources.
+func (s *Service) Process(ctx context.Context, src source, opts ...Option) Report {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.source == "" {
+		o.source = database.DefaultSource
+	}
+	if o.conflict == "" {
+		o.conflict = database.ConflictOverwrite
+	}
+
+	var (
+		out    database.Port
+		report = Report{Source: o.source, Conflict: o.conflict}
+		start  = time.Now()
+		merge  = database.MergeOptions{Source: o.source, Precedence: s.precedence, Conflict: o.conflict}
+	)
+
+	t := &tracker{hooks: o.hooks, src: src, size: o.size, start: start, last: start, report: &report}
+
+	reject := func(in parser.Packet, err error, msg string) {
+		log.Error().Err(err).Msg(msg)
+		report.Rejected++
+		t.reject(in.Key, err)
+		s.deadLetter(ctx, &o, in.Key, in.Raw, err)
+	}
 
 	for in := range src.Stream(ctx) {
+		report.Processed++
+		t.tick(false)
+
 		if in.Err != nil {
-			log.Error().Err(in.Err).Msg("Port parse failed")
+			reject(in, in.Err, "Port parse failed")
 			continue
 		}
 
 		if err := translate(in.Port, &out); err != nil {
-			log.Error().Err(err).Msg("Port translation failed")
+			reject(in, err, "Port translation failed")
 			continue
 		}
 
-		if err := s.storage.Upsert(&out); err != nil {
-			log.Error().Err(err).Msg("Port upsert failed")
+		// A key is only owned by the sources storing it, so a Port failing to be stored gives it back.
+		release := func() {}
+		if o.keys != nil {
+			store, undo, err := o.keys.claim(in.Port.Key, o.name)
+			if err != nil {
+				reject(in, err, "Port duplicated")
+				continue
+			}
+			if !store {
+				report.Skipped++
+				continue
+			}
+			release = undo
+		}
+
+		if s.writes != nil {
+			if err := s.writes.Wait(ctx); err != nil {
+				release()
+				reject(in, fmt.Errorf("key %s: %w", in.Port.Key, err), "Port write throttling failed")
+				continue
+			}
+		}
+
+		retries, err := s.retry.Retry(ctx, func() error { return s.storage.Merge(ctx, &out, merge) })
+		report.Retries += retries
+		if errors.Is(err, database.ErrSkipped) {
+			report.Skipped++
+			continue
 		}
+		if err != nil {
+			release()
+			reject(in, fmt.Errorf("key %s: %w", in.Port.Key, err), "Port upsert failed")
+			continue
+		}
+
+		report.Upserted++
 	}
+
+	report.Duration = time.Since(start)
+	t.tick(true)
+
+	return report
+}
+
+// List returns a page of the stored Ports matching a filter.
+func (s *Service) List(ctx context.Context, filter database.Filter, limit, offset int) ([]database.Port, error) {
+	return s.storage.List(ctx, filter, limit, offset)
+}
+
+// Sources returns the values of every source of a stored Port, ordered by source, or database.ErrNotFound.
+func (s *Service) Sources(ctx context.Context, key string) ([]database.SourcePort, error) {
+	return s.storage.Sources(ctx, key)
+}
+
+// Export writes every stored Port matching a filter to an encoder, closing it when done.
+func (s *Service) Export(ctx context.Context, filter database.Filter, enc export.Encoder) error {
+	if err := s.storage.Each(ctx, filter, enc.Encode); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+// Validate checks every Port from a source without storing them, returning the checked count and the found errors.
+func Validate(ctx context.Context, src source) (int, []error) {
+	var (
+		out   database.Port
+		count int
+		errs  []error
+	)
+
+	for in := range src.Stream(ctx) {
+		count++
+
+		if in.Err != nil {
+			errs = append(errs, in.Err)
+			continue
+		}
+
+		if err := translate(in.Port, &out); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return count, errs
 }
 
 func translate(in *parser.Port, out *database.Port) error {
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/parser"
+	"github.com/stretchr/testify/require"
+)
+
+type storageMock struct {
+	ports   map[string]database.Port
+	fail    string
+	sources []string
+}
+
+func (s *storageMock) Merge(_ context.Context, port *database.Port, opts database.MergeOptions) error {
+	if port.Key == s.fail {
+		return errors.New("upsert failed")
+	}
+	if _, ok := s.ports[port.Key]; ok {
+		switch opts.Conflict {
+		case database.ConflictInsertOnly:
+			return database.ErrSkipped
+		case database.ConflictReject:
+			return database.ErrConflict
+		}
+	}
+	s.ports[port.Key] = *port
+	s.sources = append(s.sources, opts.Source)
+	return nil
+}
+
+func (s *storageMock) Sources(context.Context, string) ([]database.SourcePort, error) {
+	return nil, nil
+}
+
+func (s *storageMock) List(context.Context, database.Filter, int, int) ([]database.Port, error) {
+	return nil, nil
+}
+
+func (s *storageMock) Each(context.Context, database.Filter, func(*database.Port) error) error {
+	return nil
+}
+
+func iterator(t *testing.T, input string) *parser.Iterator {
+	t.Helper()
+
+	src, err := parser.New(strings.NewReader(input))
+	require.NoError(t, err)
+
+	return src
+}
+
+func TestService_Process(t *testing.T) {
+	storage := &storageMock{ports: make(map[string]database.Port), fail: "FAIL"}
+
+	report := New(storage).Process(context.Background(), iterator(t, `{
+		"OK": {"name": "ok", "coordinates": [1, 2]},
+		"BAD": {"name": "bad", "coordinates": []},
+		"FAIL": {"name": "fail", "coordinates": [1, 2]}
+	}`))
+
+	require.Equal(t, 3, report.Processed)
+	require.Equal(t, 1, report.Upserted)
+	require.Equal(t, 2, report.Rejected)
+	require.Equal(t, map[string]database.Port{
+		"OK": {Key: "OK", Name: "ok", Latitude: 1, Longitude: 2},
+	}, storage.ports)
+}
+
+func TestService_Process_source(t *testing.T) {
+	storage := &storageMock{ports: make(map[string]database.Port)}
+	svc := New(storage)
+
+	report := svc.Process(context.Background(), iterator(t, `{"A": {"coordinates": [1, 2]}}`), WithSource("supplier"))
+	require.Equal(t, "supplier", report.Source)
+
+	report = svc.Process(context.Background(), iterator(t, `{"A": {"coordinates": [1, 2]}}`))
+	require.Equal(t, database.DefaultSource, report.Source)
+
+	require.Equal(t, []string{"supplier", database.DefaultSource}, storage.sources)
+}
+
+func TestService_Process_conflict(t *testing.T) {
+	const input = `{"A": {"name": "new", "coordinates": [1, 2]}, "B": {"name": "new", "coordinates": [1, 2]}}`
+
+	tests := []struct {
+		conflict database.Conflict
+		want     Report
+	}{
+		{database.ConflictOverwrite, Report{Processed: 2, Upserted: 2}},
+		{database.ConflictInsertOnly, Report{Processed: 2, Upserted: 1, Skipped: 1}},
+		{database.ConflictReject, Report{Processed: 2, Upserted: 1, Rejected: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.conflict), func(t *testing.T) {
+			storage := &storageMock{ports: map[string]database.Port{"A": {Key: "A", Name: "old"}}}
+
+			report := New(storage).Process(context.Background(), iterator(t, input), WithConflict(tt.conflict))
+			report.Duration = 0
+
+			tt.want.Source = database.DefaultSource
+			tt.want.Conflict = tt.conflict
+			require.Equal(t, tt.want, report)
+		})
+	}
+}
+
+func TestValidateSource(t *testing.T) {
+	require.NoError(t, ValidateSource(""))
+	require.NoError(t, ValidateSource("supplier-1.v2"))
+	require.ErrorIs(t, ValidateSource("a b"), ErrInvalidSource)
+	require.ErrorIs(t, ValidateSource(strings.Repeat("a", 65)), ErrInvalidSource)
+}
+
+func TestService_Process_duplicates(t *testing.T) {
+	const (
+		first  = `{"A": {"name": "first", "coordinates": [1, 2]}, "B": {"name": "first", "coordinates": [1, 2]}}`
+		second = `{"B": {"name": "second", "coordinates": [1, 2]}, "C": {"name": "second", "coordinates": [1, 2]}}`
+	)
+
+	tests := []struct {
+		policy Duplicates
+		names  map[string]string
+		second Report
+	}{
+		{
+			policy: DuplicatesFirst,
+			names:  map[string]string{"A": "first", "B": "first", "C": "second"},
+			second: Report{Source: database.DefaultSource, Conflict: database.ConflictOverwrite, Processed: 2, Upserted: 1, Skipped: 1},
+		},
+		{
+			policy: DuplicatesLast,
+			names:  map[string]string{"A": "first", "B": "second", "C": "second"},
+			second: Report{Source: database.DefaultSource, Conflict: database.ConflictOverwrite, Processed: 2, Upserted: 2},
+		},
+		{
+			policy: DuplicatesError,
+			names:  map[string]string{"A": "first", "B": "first", "C": "second"},
+			second: Report{Source: database.DefaultSource, Conflict: database.ConflictOverwrite, Processed: 2, Upserted: 1, Rejected: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			storage := &storageMock{ports: make(map[string]database.Port)}
+			svc := New(storage)
+			keys := NewKeys(tt.policy)
+
+			svc.Process(context.Background(), iterator(t, first), WithKeys(keys, "first"))
+			report := svc.Process(context.Background(), iterator(t, second), WithKeys(keys, "second"))
+			report.Duration = 0
+
+			require.Equal(t, tt.second, report)
+
+			names := make(map[string]string)
+			for k, p := range storage.ports {
+				names[k] = p.Name
+			}
+			require.Equal(t, tt.names, names)
+		})
+	}
+}
+
+func TestService_Process_duplicatesFailed(t *testing.T) {
+	const (
+		first  = `{"A": {"name": "first", "coordinates": [1, 2]}, "B": {"name": "first", "coordinates": []}}`
+		second = `{"A": {"name": "second", "coordinates": [1, 2]}, "B": {"name": "second", "coordinates": [1, 2]}}`
+	)
+
+	for _, policy := range []Duplicates{DuplicatesFirst, DuplicatesError} {
+		t.Run(string(policy), func(t *testing.T) {
+			storage := &storageMock{ports: make(map[string]database.Port), fail: "A"}
+			svc := New(storage)
+			keys := NewKeys(policy)
+
+			// Neither key is stored from the first file, A failing to be written and B to be translated.
+			report := svc.Process(context.Background(), iterator(t, first), WithKeys(keys, "first"))
+			require.Equal(t, 2, report.Rejected)
+
+			storage.fail = ""
+			report = svc.Process(context.Background(), iterator(t, second), WithKeys(keys, "second"))
+			report.Duration = 0
+
+			require.Equal(t, Report{Source: database.DefaultSource, Conflict: database.ConflictOverwrite, Processed: 2, Upserted: 2}, report)
+			require.Equal(t, "second", storage.ports["A"].Name)
+			require.Equal(t, "second", storage.ports["B"].Name)
+		})
+	}
+}
+
+func TestService_Process_writeRate(t *testing.T) {
+	storage := &storageMock{ports: make(map[string]database.Port)}
+	svc := New(storage, WithWriteRate(20))
+
+	input := "{"
+	for i := 0; i < 30; i++ {
+		if i > 0 {
+			input += ","
+		}
+		input += fmt.Sprintf(`"K%d": {"coordinates": [1, 2]}`, i)
+	}
+	input += "}"
+
+	report := svc.Process(context.Background(), iterator(t, input))
+
+	require.Equal(t, 30, report.Upserted)
+	// The first 20 writes use the burst, the other 10 take half a second at 20 per second.
+	require.GreaterOrEqual(t, report.Duration, 400*time.Millisecond)
+}
+
+type deadLettersMock []DeadLetter
+
+func (d *deadLettersMock) Write(_ context.Context, l *DeadLetter) error {
+	*d = append(*d, *l)
+	return nil
+}
+
+func (d *deadLettersMock) Each(_ context.Context, importID string, fn func(*DeadLetter) error) error {
+	for i := range *d {
+		if (*d)[i].ImportID == importID {
+			if err := fn(&(*d)[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestService_Process_deadLetters(t *testing.T) {
+	storage := &storageMock{ports: make(map[string]database.Port), fail: "FAIL"}
+	sink := &deadLettersMock{}
+	svc := New(storage, WithDeadLetters(sink))
+
+	report := svc.Process(context.Background(), iterator(t, `{
+		"OK": {"name": "ok", "coordinates": [1, 2]},
+		"BAD": {"name": "bad", "coordinates": []},
+		"TYPE": {"name": 1},
+		"FAIL": {"name": "fail", "coordinates": [1, 2]}
+	}`), WithImportID("import-1"), WithSource("supplier-a"))
+	require.Equal(t, 3, report.Rejected)
+
+	var got []DeadLetter
+	require.NoError(t, svc.DeadLetters(context.Background(), "import-1", func(l *DeadLetter) error {
+		got = append(got, *l)
+		return nil
+	}))
+
+	require.Len(t, got, 3)
+	for i, key := range []string{"BAD", "TYPE", "FAIL"} {
+		require.Equal(t, "import-1", got[i].ImportID)
+		require.Equal(t, "supplier-a", got[i].Source)
+		require.Equal(t, database.ConflictOverwrite, got[i].Conflict)
+		require.Equal(t, key, got[i].Key)
+		require.NotEmpty(t, got[i].Error)
+		require.False(t, got[i].RejectedAt.IsZero())
+	}
+	require.JSONEq(t, `{"name": "bad", "coordinates": []}`, string(got[0].Record))
+	require.JSONEq(t, `{"name": 1}`, string(got[1].Record))
+
+	err := New(storage).DeadLetters(context.Background(), "import-1", nil)
+	require.ErrorIs(t, err, ErrNoDeadLetters)
+}
+
+// flakyStorage fails the first writes of every key with a transient error.
+type flakyStorage struct {
+	storageMock
+	failures map[string]int
+}
+
+func (s *flakyStorage) Merge(ctx context.Context, port *database.Port, opts database.MergeOptions) error {
+	if s.failures[port.Key] > 0 {
+		s.failures[port.Key]--
+		return driver.ErrBadConn
+	}
+	return s.storageMock.Merge(ctx, port, opts)
+}
+
+func TestService_Process_retries(t *testing.T) {
+	storage := &flakyStorage{
+		storageMock: storageMock{ports: make(map[string]database.Port)},
+		failures:    map[string]int{"ONCE": 1, "TWICE": 2, "ALWAYS": 10},
+	}
+	svc := New(storage, WithRetries(database.Backoff{Retries: 2, Base: time.Millisecond}))
+
+	report := svc.Process(context.Background(), iterator(t, `{
+		"ONCE": {"coordinates": [1, 2]},
+		"TWICE": {"coordinates": [1, 2]},
+		"ALWAYS": {"coordinates": [1, 2]}
+	}`))
+
+	require.Equal(t, 2, report.Upserted)
+	require.Equal(t, 1, report.Rejected)
+	require.Equal(t, 1+2+2, report.Retries)
+}
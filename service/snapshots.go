@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/agukrapo/ports/database"
+)
+
+var (
+	// ErrNoSnapshots is returned when managing the snapshots of a Service whose storage keeps none.
+	ErrNoSnapshots = errors.New("snapshots unsupported by the storage")
+	// ErrInvalidSnapshotName is returned when creating a snapshot with an invalid name.
+	ErrInvalidSnapshotName = errors.New("invalid snapshot name, must be 1 to 64 letters, digits, dots, dashes or underscores")
+	// ErrInvalidSource is returned by ValidateSource.
+	ErrInvalidSource = errors.New("invalid source name, must be 1 to 64 letters, digits, dots, dashes or underscores")
+)
+
+// ValidateSource checks an import source name, empty standing for database.DefaultSource.
+func ValidateSource(name string) error {
+	if name != "" && !validName.MatchString(name) {
+		return ErrInvalidSource
+	}
+	return nil
+}
+
+// validName matches the snapshot and source names.
+var validName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// snapshotter represents a storage keeping named copies of every port, implemented by every backend.
+type snapshotter interface {
+	CreateSnapshot(context.Context, string, string) (*database.Snapshot, error)
+	Snapshots(context.Context) ([]database.Snapshot, error)
+	RestoreSnapshot(context.Context, string) (*database.Restore, error)
+	DeleteSnapshot(context.Context, string) error
+}
+
+func (s *Service) snapshotter() (snapshotter, error) {
+	out, ok := s.storage.(snapshotter)
+	if !ok {
+		return nil, ErrNoSnapshots
+	}
+
+	return out, nil
+}
+
+// CreateSnapshot copies every stored port to a new named snapshot, createdBy identifying who asked for it.
+func (s *Service) CreateSnapshot(ctx context.Context, name, createdBy string) (*database.Snapshot, error) {
+	if !validName.MatchString(name) {
+		return nil, ErrInvalidSnapshotName
+	}
+
+	st, err := s.snapshotter()
+	if err != nil {
+		return nil, err
+	}
+
+	return st.CreateSnapshot(ctx, name, createdBy)
+}
+
+// Snapshots returns every snapshot, oldest first.
+func (s *Service) Snapshots(ctx context.Context) ([]database.Snapshot, error) {
+	st, err := s.snapshotter()
+	if err != nil {
+		return nil, err
+	}
+
+	return st.Snapshots(ctx)
+}
+
+// RestoreSnapshot replaces every stored port with the ones of a snapshot, atomically.
+func (s *Service) RestoreSnapshot(ctx context.Context, name string) (*database.Restore, error) {
+	st, err := s.snapshotter()
+	if err != nil {
+		return nil, err
+	}
+
+	return st.RestoreSnapshot(ctx, name)
+}
+
+// DeleteSnapshot removes a snapshot.
+func (s *Service) DeleteSnapshot(ctx context.Context, name string) error {
+	st, err := s.snapshotter()
+	if err != nil {
+		return err
+	}
+
+	return st.DeleteSnapshot(ctx, name)
+}
@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+)
+
+// ClaimIdempotencyKey inserts a row, unless another one created after since holds its key, which is returned instead.
+// Rows created before since are deleted, along with the pending ones whose lease ended before row was created.
+func (m *Memory) ClaimIdempotencyKey(_ context.Context, row *database.IdempotencyKey, since time.Time) (*database.IdempotencyKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k, r := range m.keys {
+		if r.CreatedAt.Before(since) || r.Status == database.IdempotencyPending && r.PendingUntil.Before(row.CreatedAt) {
+			delete(m.keys, k)
+		}
+	}
+
+	if r, ok := m.keys[row.Key]; ok {
+		return &r, nil
+	}
+
+	m.keys[row.Key] = *row
+
+	return nil, nil
+}
+
+// SaveIdempotencyKey updates a claimed row.
+func (m *Memory) SaveIdempotencyKey(_ context.Context, row *database.IdempotencyKey) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.keys[row.Key] = *row
+
+	return nil
+}
+
+// RenewIdempotencyKey extends the lease of a pending row.
+func (m *Memory) RenewIdempotencyKey(_ context.Context, key string, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if r, ok := m.keys[key]; ok && r.Status == database.IdempotencyPending {
+		r.PendingUntil = until
+		m.keys[key] = r
+	}
+
+	return nil
+}
+
+// DeleteIdempotencyKey releases a claimed row, so its key can be claimed again.
+func (m *Memory) DeleteIdempotencyKey(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.keys, key)
+
+	return nil
+}
@@ -0,0 +1,179 @@
+// Package memory includes an in-memory port storage, meant for tests.
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+)
+
+// Memory represents an in-memory storage, it is safe for concurrent use.
+type Memory struct {
+	mu    sync.RWMutex
+	ports map[string]database.Port
+	keys  map[string]database.IdempotencyKey
+	// history holds every version of each Port, oldest first.
+	history map[string][]version
+	// sources holds the values of each Port by source.
+	sources map[string]map[string]database.SourcePort
+
+	rejects []database.Reject
+
+	webhooks     map[string]database.Webhook
+	deliveries   []database.Delivery
+	lastDelivery int64
+
+	snapshots map[string]snapshot
+}
+
+// New instantiates a new empty Memory.
+func New() *Memory {
+	return &Memory{
+		ports:   make(map[string]database.Port),
+		keys:    make(map[string]database.IdempotencyKey),
+		history: make(map[string][]version),
+		sources: make(map[string]map[string]database.SourcePort),
+
+		webhooks: make(map[string]database.Webhook),
+
+		snapshots: make(map[string]snapshot),
+	}
+}
+
+// version represents a Port as stored from a time until another one, zero while current.
+type version struct {
+	port     database.Port
+	from, to time.Time
+}
+
+// record closes the current version of a changed Port and opens the next one, if not deleted.
+func (m *Memory) record(key string, port *database.Port) {
+	now := time.Now()
+
+	versions := m.history[key]
+	if n := len(versions); n > 0 && versions[n-1].to.IsZero() {
+		versions[n-1].to = now
+	}
+	if port != nil {
+		versions = append(versions, version{port: clone(port), from: now})
+	}
+
+	m.history[key] = versions
+}
+
+// Delete removes the Port with the given key and its source values, or returns database.ErrNotFound.
+func (m *Memory) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.ports[key]; !ok {
+		return database.ErrNotFound
+	}
+
+	delete(m.ports, key)
+	delete(m.sources, key)
+	m.record(key, nil)
+
+	return nil
+}
+
+// Get returns the Port with the given key, or database.ErrNotFound.
+func (m *Memory) Get(_ context.Context, key string) (*database.Port, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.ports[key]
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+
+	out := clone(&p)
+	return &out, nil
+}
+
+// List returns a page of the Ports matching the Filter, ordered by key.
+func (m *Memory) List(_ context.Context, filter database.Filter, limit, offset int) ([]database.Port, error) {
+	matches := m.match(filter)
+
+	if offset >= len(matches) {
+		return nil, nil
+	}
+	matches = matches[offset:]
+
+	if limit >= 0 && limit < len(matches) {
+		matches = matches[:limit]
+	}
+
+	return matches, nil
+}
+
+// Each calls fn for every Port matching the Filter, ordered by key.
+func (m *Memory) Each(ctx context.Context, filter database.Filter, fn func(*database.Port) error) error {
+	for _, p := range m.match(filter) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		p := p
+		if err := fn(&p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// match returns a copy of the Ports matching the Filter, ordered by key.
+func (m *Memory) match(filter database.Filter) []database.Port {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []database.Port
+	add := func(p database.Port) {
+		if filter.Match(&p) {
+			out = append(out, clone(&p))
+		}
+	}
+
+	if filter.AsOf.IsZero() {
+		for _, p := range m.ports {
+			add(p)
+		}
+	} else {
+		for _, versions := range m.history {
+			for _, v := range versions {
+				if !v.from.After(filter.AsOf) && (v.to.IsZero() || v.to.After(filter.AsOf)) {
+					add(v.port)
+				}
+			}
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+
+	return out
+}
+
+// Check always succeeds.
+func (m *Memory) Check(context.Context) error {
+	return nil
+}
+
+// Close does nothing.
+func (m *Memory) Close() error {
+	return nil
+}
+
+func clone(p *database.Port) database.Port {
+	out := *p
+	if p.Unlocs != nil {
+		out.Unlocs = append([]string{}, p.Unlocs...)
+	}
+	if p.Alias != nil {
+		out.Alias = append([]string{}, p.Alias...)
+	}
+	return out
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/agukrapo/ports/storage"
+	"github.com/agukrapo/ports/storage/memory"
+	"github.com/agukrapo/ports/storage/storagetest"
+)
+
+func TestMemory(t *testing.T) {
+	storagetest.Run(t, func(*testing.T) storage.Storage {
+		return memory.New()
+	})
+}
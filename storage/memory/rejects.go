@@ -0,0 +1,42 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/agukrapo/ports/database"
+)
+
+// InsertReject stores a new Reject.
+func (m *Memory) InsertReject(_ context.Context, reject *database.Reject) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reject.ID = int64(len(m.rejects) + 1)
+	m.rejects = append(m.rejects, *reject)
+
+	return nil
+}
+
+// EachReject calls fn for every Reject of an import, in insertion order.
+func (m *Memory) EachReject(ctx context.Context, importID string, fn func(*database.Reject) error) error {
+	m.mu.RLock()
+	var matches []database.Reject
+	for _, r := range m.rejects {
+		if r.ImportID == importID {
+			matches = append(matches, r)
+		}
+	}
+	m.mu.RUnlock()
+
+	for i := range matches {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := fn(&matches[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
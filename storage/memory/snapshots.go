@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+)
+
+// snapshot represents a database.Snapshot along with its Ports, ordered by key, and their source values.
+type snapshot struct {
+	meta    database.Snapshot
+	ports   []database.Port
+	sources map[string]map[string]database.SourcePort
+}
+
+// CreateSnapshot copies every Port to a new Snapshot, or returns database.ErrSnapshotExists.
+func (m *Memory) CreateSnapshot(_ context.Context, name, createdBy string) (*database.Snapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.snapshots[name]; ok {
+		return nil, database.ErrSnapshotExists
+	}
+
+	ports := make([]database.Port, 0, len(m.ports))
+	for _, p := range m.ports {
+		p := p
+		ports = append(ports, clone(&p))
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Key < ports[j].Key })
+
+	sum := database.NewChecksum()
+	for i := range ports {
+		if err := sum.Add(&ports[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	meta := database.Snapshot{
+		Name:      name,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: createdBy,
+		Rows:      int64(len(ports)),
+		Checksum:  sum.Sum(),
+	}
+	m.snapshots[name] = snapshot{meta: meta, ports: ports, sources: copySources(m.sources)}
+
+	return &meta, nil
+}
+
+// Snapshots returns every Snapshot, oldest first.
+func (m *Memory) Snapshots(_ context.Context) ([]database.Snapshot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]database.Snapshot, 0, len(m.snapshots))
+	for _, s := range m.snapshots {
+		out = append(out, s.meta)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].Name < out[j].Name
+	})
+
+	return out, nil
+}
+
+// RestoreSnapshot replaces every Port and source value with the ones of a Snapshot, or returns database.ErrSnapshotNotFound.
+func (m *Memory) RestoreSnapshot(_ context.Context, name string) (*database.Restore, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.snapshots[name]
+	if !ok {
+		return nil, database.ErrSnapshotNotFound
+	}
+
+	var out database.Restore
+
+	restored := make(map[string]bool, len(s.ports))
+	for i := range s.ports {
+		port := &s.ports[i]
+		restored[port.Key] = true
+
+		before, ok := m.ports[port.Key]
+		switch {
+		case !ok:
+			out.Created++
+		case len(database.Diff(&before, port)) == 0:
+			out.Unchanged++
+			continue
+		default:
+			out.Updated++
+		}
+
+		m.ports[port.Key] = clone(port)
+		m.record(port.Key, port)
+	}
+
+	for key := range m.ports {
+		if !restored[key] {
+			delete(m.ports, key)
+			m.record(key, nil)
+			out.Deleted++
+		}
+	}
+
+	m.sources = copySources(s.sources)
+
+	return &out, nil
+}
+
+// DeleteSnapshot removes a Snapshot, or returns database.ErrSnapshotNotFound.
+func (m *Memory) DeleteSnapshot(_ context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.snapshots[name]; !ok {
+		return database.ErrSnapshotNotFound
+	}
+
+	delete(m.snapshots, name)
+
+	return nil
+}
+
+func copySources(in map[string]map[string]database.SourcePort) map[string]map[string]database.SourcePort {
+	out := make(map[string]map[string]database.SourcePort, len(in))
+	for key, rows := range in {
+		out[key] = make(map[string]database.SourcePort, len(rows))
+		for _, s := range sortedSources(rows) {
+			out[key][s.Source] = s
+		}
+	}
+
+	return out
+}
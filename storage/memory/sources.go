@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+)
+
+// Merge stores the values of a Port imported from a source, then stores the Port merged from every source of its key.
+// It returns database.ErrSkipped or database.ErrConflict, storing nothing, when the Conflict policy says so.
+func (m *Memory) Merge(_ context.Context, port *database.Port, opts database.MergeOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	source := opts.Source
+	if source == "" {
+		source = database.DefaultSource
+	}
+
+	rows := m.sources[port.Key]
+	stored := make(map[string][]string, len(rows))
+	for _, s := range rows {
+		stored[s.Source] = s.Supplies
+	}
+
+	row := database.SourcePort{Port: clone(port), Source: source, UpdatedAt: time.Now().UTC()}
+	sources := []database.SourcePort{row}
+	for _, s := range sortedSources(rows) {
+		if s.Source != source {
+			sources = append(sources, s)
+		}
+	}
+
+	var before *database.Port
+	if p, ok := m.ports[port.Key]; ok {
+		before = &p
+	}
+
+	merged := database.Merge(sources, opts.Precedence)
+	if err := opts.Conflict.Apply(before, &merged, sources, stored); err != nil {
+		return err
+	}
+
+	var previous *database.Port
+	if s, ok := rows[source]; ok {
+		previous = &s.Port
+	}
+	opts.Conflict.Keep(before, &sources[0].Port, previous)
+
+	if rows == nil {
+		rows = make(map[string]database.SourcePort)
+		m.sources[port.Key] = rows
+	}
+	for _, s := range sources {
+		rows[s.Source] = s
+	}
+
+	if before != nil && len(database.Diff(before, &merged)) == 0 {
+		return nil
+	}
+
+	m.ports[port.Key] = merged
+	m.record(port.Key, &merged)
+
+	return nil
+}
+
+// Sources returns the values of every source of a key, ordered by source, or database.ErrNotFound.
+func (m *Memory) Sources(_ context.Context, key string) ([]database.SourcePort, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rows, ok := m.sources[key]
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+
+	return sortedSources(rows), nil
+}
+
+// sortedSources returns a copy of the source values of a key, ordered by source.
+func sortedSources(rows map[string]database.SourcePort) []database.SourcePort {
+	out := make([]database.SourcePort, 0, len(rows))
+	for _, s := range rows {
+		s.Port = clone(&s.Port)
+		s.Supplies = append([]string{}, s.Supplies...)
+		out = append(out, s)
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Source < out[j].Source })
+
+	return out
+}
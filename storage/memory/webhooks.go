@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+)
+
+// CreateWebhook stores a new Webhook.
+func (m *Memory) CreateWebhook(_ context.Context, hook *database.Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.webhooks[hook.ID] = *hook
+
+	return nil
+}
+
+// Webhooks returns every Webhook, oldest first.
+func (m *Memory) Webhooks(_ context.Context) ([]database.Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make([]database.Webhook, 0, len(m.webhooks))
+	for _, h := range m.webhooks {
+		out = append(out, h)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+
+	return out, nil
+}
+
+// GetWebhook returns the Webhook with the given ID, or database.ErrNotFound.
+func (m *Memory) GetWebhook(_ context.Context, id string) (*database.Webhook, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	h, ok := m.webhooks[id]
+	if !ok {
+		return nil, database.ErrNotFound
+	}
+
+	return &h, nil
+}
+
+// UpdateWebhook replaces the URL, events and secret of a Webhook, or returns database.ErrNotFound.
+func (m *Memory) UpdateWebhook(_ context.Context, hook *database.Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.webhooks[hook.ID]
+	if !ok {
+		return database.ErrNotFound
+	}
+
+	h.URL, h.Events, h.Secret, h.UpdatedAt = hook.URL, hook.Events, hook.Secret, hook.UpdatedAt
+	m.webhooks[hook.ID] = h
+
+	return nil
+}
+
+// DeleteWebhook removes the Webhook with the given ID and its Deliveries, or returns database.ErrNotFound.
+func (m *Memory) DeleteWebhook(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.webhooks[id]; !ok {
+		return database.ErrNotFound
+	}
+	delete(m.webhooks, id)
+
+	kept := m.deliveries[:0]
+	for _, d := range m.deliveries {
+		if d.WebhookID != id {
+			kept = append(kept, d)
+		}
+	}
+	m.deliveries = kept
+
+	return nil
+}
+
+// InsertDeliveries stores new Deliveries, setting their IDs.
+func (m *Memory) InsertDeliveries(_ context.Context, deliveries []database.Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range deliveries {
+		m.lastDelivery++
+		deliveries[i].ID = m.lastDelivery
+		m.deliveries = append(m.deliveries, deliveries[i])
+	}
+
+	return nil
+}
+
+// ClaimDeliveries returns the oldest pending Deliveries due at a time, up to limit, postponing their next attempt by lease.
+func (m *Memory) ClaimDeliveries(_ context.Context, now time.Time, lease time.Duration, limit int) ([]database.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []database.Delivery
+	for i := range m.deliveries {
+		d := &m.deliveries[i]
+		if len(out) == limit {
+			break
+		}
+
+		if d.Status == database.DeliveryPending && !d.NextAttemptAt.After(now) {
+			d.NextAttemptAt = now.Add(lease)
+			out = append(out, *d)
+		}
+	}
+
+	return out, nil
+}
+
+// SaveDelivery stores the outcome of a Delivery attempt, unless deleted meanwhile.
+func (m *Memory) SaveDelivery(_ context.Context, delivery *database.Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := range m.deliveries {
+		if m.deliveries[i].ID == delivery.ID {
+			m.deliveries[i] = *delivery
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Deliveries returns the last Deliveries of a Webhook, newest first, up to limit.
+func (m *Memory) Deliveries(_ context.Context, webhookID string, limit int) ([]database.Delivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var out []database.Delivery
+	for i := len(m.deliveries) - 1; i >= 0 && len(out) < limit; i-- {
+		if d := m.deliveries[i]; d.WebhookID == webhookID {
+			out = append(out, d)
+		}
+	}
+
+	return out, nil
+}
+
+// PruneDeliveries deletes the finished Deliveries created before a time, returning the number deleted.
+func (m *Memory) PruneDeliveries(_ context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	kept := m.deliveries[:0]
+	for _, d := range m.deliveries {
+		if d.Status != database.DeliveryPending && d.CreatedAt.Before(before) {
+			n++
+			continue
+		}
+		kept = append(kept, d)
+	}
+	m.deliveries = kept
+
+	return n, nil
+}
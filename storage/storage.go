@@ -0,0 +1,54 @@
+// Package storage includes the port storage abstraction and its backend selection.
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/storage/memory"
+)
+
+// Storage represents a ports storage backend.
+type Storage interface {
+	// Merge stores the values of a Port imported from a source, then stores the Port merged from every source of its key.
+	Merge(context.Context, *database.Port, database.MergeOptions) error
+	// Sources returns the values of every source of a key, ordered by source, or database.ErrNotFound.
+	Sources(context.Context, string) ([]database.SourcePort, error)
+	// Delete removes the Port with the given key, or returns database.ErrNotFound.
+	Delete(context.Context, string) error
+	// Get returns the Port with the given key, or database.ErrNotFound.
+	Get(context.Context, string) (*database.Port, error)
+	// List returns a page of the Ports matching the Filter, ordered by key.
+	List(context.Context, database.Filter, int, int) ([]database.Port, error)
+	// Each calls a function for every Port matching the Filter, ordered by key.
+	Each(context.Context, database.Filter, func(*database.Port) error) error
+	// Check tells if the Storage is ready to be used.
+	Check(context.Context) error
+	// Close releases the Storage resources.
+	Close() error
+}
+
+// MemoryScheme is the DSN scheme of the in-memory backend, meant for tests.
+const MemoryScheme = "memory"
+
+// Open instantiates the Storage selected by the DSN scheme:
+// postgres:// (or a key=value connection string), sqlite://PATH or memory://.
+func Open(cfg config.Database) (Storage, error) {
+	if cfg.DSN == "" {
+		return nil, errors.New("database DSN missing")
+	}
+
+	if IsMemory(cfg.DSN) {
+		return memory.New(), nil
+	}
+
+	return database.New(cfg)
+}
+
+// IsMemory tells if a DSN selects the in-memory backend.
+func IsMemory(dsn string) bool {
+	return strings.HasPrefix(dsn, MemoryScheme+"://")
+}
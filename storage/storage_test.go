@@ -0,0 +1,107 @@
+package storage_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/storage"
+	"github.com/agukrapo/ports/storage/storagetest"
+	"github.com/stretchr/testify/require"
+)
+
+// postgresEnv holds the DSN of a disposable Postgres database, its ports table is emptied by the tests.
+const postgresEnv = "PORTS_TEST_POSTGRES_DSN"
+
+func migrated(t *testing.T, dsn string) config.Database {
+	t.Helper()
+
+	cfg := config.Default().Database
+	cfg.DSN = dsn
+
+	m, err := database.NewMigrator(cfg)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, m.Close()) }()
+
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+
+	return cfg
+}
+
+func TestSQLite(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		cfg := migrated(t, "sqlite://"+filepath.Join(t.TempDir(), "ports.db"))
+
+		s, err := storage.Open(cfg)
+		require.NoError(t, err)
+
+		return s
+	})
+}
+
+func TestSQLite_inMemory(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.DSN = "sqlite://:memory:"
+
+	_, err := storage.Open(cfg)
+	require.ErrorIs(t, err, database.ErrSchemaVersion, "an in-memory database is never migrated")
+}
+
+func TestPostgres(t *testing.T) {
+	dsn, ok := os.LookupEnv(postgresEnv)
+	if !ok {
+		t.Skipf("%s not set", postgresEnv)
+	}
+
+	storagetest.Run(t, func(t *testing.T) storage.Storage {
+		cfg := migrated(t, dsn)
+
+		s, err := storage.Open(cfg)
+		require.NoError(t, err)
+
+		truncate(t, s)
+
+		return s
+	})
+}
+
+func truncate(t *testing.T, s storage.Storage) {
+	t.Helper()
+
+	ctx := context.Background()
+	ports, err := s.List(ctx, database.Filter{}, 1_000_000, 0)
+	require.NoError(t, err)
+
+	for _, p := range ports {
+		require.NoError(t, s.Delete(ctx, p.Key))
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		dsn string
+		err string
+	}{
+		{dsn: "memory://"},
+		{dsn: "mysql://localhost/ports", err: `unsupported database scheme "mysql"`},
+		{dsn: "", err: "database DSN missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			cfg := config.Default().Database
+			cfg.DSN = tt.dsn
+
+			s, err := storage.Open(cfg)
+			if tt.err != "" {
+				require.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, s.Close())
+		})
+	}
+}
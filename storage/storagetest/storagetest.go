@@ -0,0 +1,408 @@
+// Package storagetest includes the conformance test suite every storage.Storage backend must pass.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/storage"
+	"github.com/stretchr/testify/require"
+)
+
+// Open returns an empty Storage, closed by the suite.
+type Open func(t *testing.T) storage.Storage
+
+var fixtures = []database.Port{
+	{Key: "AEAJM", Code: "52000", Name: "Ajman", City: "Ajman", Province: "Ajman", Country: "United Arab Emirates", Timezone: "Asia/Dubai", Latitude: 55.5136433, Longitude: 25.4052165, Unlocs: []string{"AEAJM"}, Alias: []string{}},
+	{Key: "AEAUH", Code: "52001", Name: "Abu Dhabi", City: "Abu Dhabi", Country: "United Arab Emirates", Latitude: 54.37, Longitude: 24.47, Unlocs: []string{"AEAUH", "AEAUX"}},
+	{Key: "ARBUE", Code: "35701", Name: "Buenos Aires", City: "Buenos Aires", Country: "Argentina", Latitude: -58.3815591, Longitude: -34.6036844, Unlocs: []string{"ARBUE"}, Alias: []string{"BA", "CABA"}},
+}
+
+// Run runs the conformance suite, each test on a new Storage.
+func Run(t *testing.T, open Open) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, storage.Storage)
+	}{
+		{"merge and get", testMergeGet},
+		{"delete", testDelete},
+		{"list", testList},
+		{"each", testEach},
+		{"as of", testAsOf},
+		{"snapshots", testSnapshots},
+		{"merge", testMerge},
+		{"conflicts", testConflicts},
+		{"fill empty sources", testFillEmptySources},
+		{"check", testCheck},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := open(t)
+			t.Cleanup(func() { require.NoError(t, s.Close()) })
+
+			tt.fn(t, s)
+		})
+	}
+}
+
+func seed(t *testing.T, s storage.Storage) {
+	t.Helper()
+
+	for i := range fixtures {
+		p := fixtures[i]
+		require.NoError(t, s.Merge(context.Background(), &p, database.MergeOptions{}))
+	}
+}
+
+func testMergeGet(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	_, err := s.Get(ctx, "AEAJM")
+	require.ErrorIs(t, err, database.ErrNotFound)
+
+	seed(t, s)
+
+	for _, want := range fixtures {
+		got, err := s.Get(ctx, want.Key)
+		require.NoError(t, err)
+		require.Equal(t, want, *got)
+	}
+
+	updated := fixtures[0]
+	updated.Name = "Ajman updated"
+	updated.Alias = []string{"AJ"}
+	updated.Unlocs = nil
+	require.NoError(t, s.Merge(ctx, &updated, database.MergeOptions{}))
+
+	got, err := s.Get(ctx, updated.Key)
+	require.NoError(t, err)
+	require.Equal(t, updated, *got)
+
+	got.Name = "mutated"
+	again, err := s.Get(ctx, updated.Key)
+	require.NoError(t, err)
+	require.Equal(t, updated, *again, "returned ports must not share state with the storage")
+}
+
+func testDelete(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	require.ErrorIs(t, s.Delete(ctx, "AEAJM"), database.ErrNotFound)
+
+	seed(t, s)
+
+	require.NoError(t, s.Delete(ctx, "AEAJM"))
+
+	_, err := s.Get(ctx, "AEAJM")
+	require.ErrorIs(t, err, database.ErrNotFound)
+	require.ErrorIs(t, s.Delete(ctx, "AEAJM"), database.ErrNotFound)
+
+	all, err := s.List(ctx, database.Filter{}, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, fixtures[1:], all)
+}
+
+func testList(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	empty, err := s.List(ctx, database.Filter{}, 10, 0)
+	require.NoError(t, err)
+	require.Empty(t, empty)
+
+	seed(t, s)
+
+	tests := []struct {
+		name   string
+		filter database.Filter
+		limit  int
+		offset int
+		want   []database.Port
+	}{
+		{name: "all", limit: 10, want: fixtures},
+		{name: "limit", limit: 2, want: fixtures[:2]},
+		{name: "offset", limit: 2, offset: 2, want: fixtures[2:]},
+		{name: "offset past the end", limit: 2, offset: 3},
+		{name: "keys", filter: database.Filter{Keys: []string{"ARBUE", "AEAJM", "NOPE"}}, limit: 10, want: []database.Port{fixtures[0], fixtures[2]}},
+		{name: "country", filter: database.Filter{Country: "United Arab Emirates"}, limit: 10, want: fixtures[:2]},
+		{name: "city", filter: database.Filter{City: "Buenos Aires"}, limit: 10, want: fixtures[2:]},
+		{name: "bbox", filter: database.Filter{BBox: &database.BBox{MinX: 54, MinY: 24, MaxX: 55, MaxY: 25}}, limit: 10, want: fixtures[1:2]},
+		{name: "no match", filter: database.Filter{Country: "Argentina", City: "Ajman"}, limit: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.List(ctx, tt.filter, tt.limit, tt.offset)
+			require.NoError(t, err)
+
+			if len(tt.want) == 0 {
+				require.Empty(t, got)
+				return
+			}
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func testEach(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	seed(t, s)
+
+	var got []database.Port
+	require.NoError(t, s.Each(ctx, database.Filter{}, func(p *database.Port) error {
+		got = append(got, *p)
+		return nil
+	}))
+	require.Equal(t, fixtures, got)
+
+	got = nil
+	require.NoError(t, s.Each(ctx, database.Filter{Country: "Argentina"}, func(p *database.Port) error {
+		got = append(got, *p)
+		return nil
+	}))
+	require.Equal(t, fixtures[2:], got)
+
+	stop := errors.New("stop")
+	calls := 0
+	err := s.Each(ctx, database.Filter{}, func(*database.Port) error {
+		calls++
+		return stop
+	})
+	require.ErrorIs(t, err, stop)
+	require.Equal(t, 1, calls)
+}
+
+// instant returns a time strictly between the writes made before and after it.
+func instant() time.Time {
+	time.Sleep(5 * time.Millisecond)
+	defer time.Sleep(5 * time.Millisecond)
+
+	return time.Now()
+}
+
+func testAsOf(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	empty := instant()
+	seed(t, s)
+	seeded := instant()
+
+	updated := fixtures[0]
+	updated.Name = "Ajman updated"
+	require.NoError(t, s.Merge(ctx, &updated, database.MergeOptions{}))
+	require.NoError(t, s.Delete(ctx, "ARBUE"))
+	changed := instant()
+
+	unchanged := fixtures[1]
+	require.NoError(t, s.Merge(ctx, &unchanged, database.MergeOptions{}))
+
+	tests := []struct {
+		name   string
+		filter database.Filter
+		want   []database.Port
+	}{
+		{name: "before any write", filter: database.Filter{AsOf: empty}},
+		{name: "after the seed", filter: database.Filter{AsOf: seeded}, want: fixtures},
+		{name: "after the changes", filter: database.Filter{AsOf: changed}, want: []database.Port{updated, fixtures[1]}},
+		{name: "in the future", filter: database.Filter{AsOf: time.Now().Add(time.Hour)}, want: []database.Port{updated, fixtures[1]}},
+		{name: "filtered", filter: database.Filter{AsOf: seeded, Country: "Argentina"}, want: fixtures[2:]},
+		{name: "other time zone", filter: database.Filter{AsOf: seeded.In(time.FixedZone("UTC-3", -3*60*60))}, want: fixtures},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.List(ctx, tt.filter, 10, 0)
+			require.NoError(t, err)
+
+			var each []database.Port
+			require.NoError(t, s.Each(ctx, tt.filter, func(p *database.Port) error {
+				each = append(each, *p)
+				return nil
+			}))
+
+			if len(tt.want) == 0 {
+				require.Empty(t, got)
+				require.Empty(t, each)
+				return
+			}
+			require.Equal(t, tt.want, got)
+			require.Equal(t, tt.want, each)
+		})
+	}
+}
+
+func testSnapshots(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+
+	type snapshotter interface {
+		CreateSnapshot(context.Context, string, string) (*database.Snapshot, error)
+		Snapshots(context.Context) ([]database.Snapshot, error)
+		RestoreSnapshot(context.Context, string) (*database.Restore, error)
+		DeleteSnapshot(context.Context, string) error
+	}
+	st, ok := s.(snapshotter)
+	require.True(t, ok, "every backend must keep snapshots")
+
+	seed(t, s)
+
+	snap, err := st.CreateSnapshot(ctx, "seeded", "tester")
+	require.NoError(t, err)
+	require.Equal(t, "seeded", snap.Name)
+	require.Equal(t, "tester", snap.CreatedBy)
+	require.EqualValues(t, len(fixtures), snap.Rows)
+	require.Len(t, snap.Checksum, 64)
+
+	_, err = st.CreateSnapshot(ctx, "seeded", "tester")
+	require.ErrorIs(t, err, database.ErrSnapshotExists)
+
+	updated := fixtures[0]
+	updated.Name = "Ajman updated"
+	require.NoError(t, s.Merge(ctx, &updated, database.MergeOptions{}))
+	require.NoError(t, s.Delete(ctx, "ARBUE"))
+	extra := database.Port{Key: "ZZZZZ", Name: "Extra", Unlocs: []string{"ZZZZZ"}}
+	require.NoError(t, s.Merge(ctx, &extra, database.MergeOptions{}))
+
+	changed, err := st.CreateSnapshot(ctx, "changed", "")
+	require.NoError(t, err)
+	require.NotEqual(t, snap.Checksum, changed.Checksum)
+
+	list, err := st.Snapshots(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "seeded", list[0].Name)
+	require.Equal(t, snap.Checksum, list[0].Checksum)
+
+	restore, err := st.RestoreSnapshot(ctx, "seeded")
+	require.NoError(t, err)
+	require.Equal(t, database.Restore{Created: 1, Updated: 1, Deleted: 1, Unchanged: 1}, *restore)
+
+	all, err := s.List(ctx, database.Filter{}, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, fixtures, all)
+
+	again, err := st.CreateSnapshot(ctx, "again", "")
+	require.NoError(t, err)
+	require.Equal(t, snap.Checksum, again.Checksum)
+
+	_, err = st.RestoreSnapshot(ctx, "nope")
+	require.ErrorIs(t, err, database.ErrSnapshotNotFound)
+
+	require.NoError(t, st.DeleteSnapshot(ctx, "seeded"))
+	require.ErrorIs(t, st.DeleteSnapshot(ctx, "seeded"), database.ErrSnapshotNotFound)
+	_, err = st.RestoreSnapshot(ctx, "seeded")
+	require.ErrorIs(t, err, database.ErrSnapshotNotFound)
+
+	list, err = st.Snapshots(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+}
+
+func testMerge(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	precedence := database.Precedence{Order: []string{"supplier"}, Fields: map[string][]string{"coordinates": {"geo"}}}
+
+	supplier := database.Port{Key: "AEAJM", Name: "Ajman", Country: "United Arab Emirates", Latitude: 1, Longitude: 2, Unlocs: []string{"AEAJM"}, Alias: []string{"Ajman"}}
+	require.NoError(t, s.Merge(ctx, &supplier, database.MergeOptions{Source: "supplier", Precedence: precedence}))
+
+	geo := database.Port{Key: "AEAJM", Name: "Ajman Port", City: "Ajman", Latitude: 55.5, Longitude: 25.4, Unlocs: []string{"AEAJM", "AEAJX"}}
+	require.NoError(t, s.Merge(ctx, &geo, database.MergeOptions{Source: "geo", Precedence: precedence}))
+
+	got, err := s.List(ctx, database.Filter{}, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, []database.Port{{
+		Key: "AEAJM", Name: "Ajman", City: "Ajman", Country: "United Arab Emirates", Latitude: 55.5, Longitude: 25.4,
+		Unlocs: []string{"AEAJM", "AEAJX"}, Alias: []string{"Ajman"},
+	}}, got)
+
+	sources, err := s.Sources(ctx, "AEAJM")
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	require.Equal(t, "geo", sources[0].Source)
+	require.Equal(t, geo.Name, sources[0].Name)
+	require.Equal(t, []string{"city", "coordinates", "unlocs"}, []string(sources[0].Supplies))
+	require.Equal(t, "supplier", sources[1].Source)
+	require.Equal(t, []string{"name", "country", "alias", "unlocs"}, []string(sources[1].Supplies))
+
+	// Importing the same values again changes nothing.
+	require.NoError(t, s.Merge(ctx, &geo, database.MergeOptions{Source: "geo", Precedence: precedence}))
+	again, err := s.List(ctx, database.Filter{}, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, got, again)
+
+	_, err = s.Sources(ctx, "ARBUE")
+	require.ErrorIs(t, err, database.ErrNotFound)
+
+	require.NoError(t, s.Delete(ctx, "AEAJM"))
+	_, err = s.Sources(ctx, "AEAJM")
+	require.ErrorIs(t, err, database.ErrNotFound)
+}
+
+func testConflicts(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	merge := func(p database.Port, c database.Conflict) error {
+		return s.Merge(ctx, &p, database.MergeOptions{Conflict: c})
+	}
+	get := func(key string) database.Port {
+		p, err := s.Get(ctx, key)
+		require.NoError(t, err)
+		return *p
+	}
+
+	stored := database.Port{Key: "AEAJM", Name: "Ajman", Unlocs: []string{"AEAJM"}, Alias: []string{}}
+	require.NoError(t, merge(stored, ""))
+
+	changed := database.Port{Key: "AEAJM", Name: "Ajman Port", City: "Ajman", Unlocs: []string{"AEAJX"}, Alias: []string{"Ajman"}}
+	extra := database.Port{Key: "ZZZZZ", Name: "Extra", Unlocs: []string{"ZZZZZ"}, Alias: []string{}}
+
+	require.ErrorIs(t, merge(changed, database.ConflictInsertOnly), database.ErrSkipped)
+	require.ErrorIs(t, merge(extra, database.ConflictUpdateOnly), database.ErrSkipped)
+	require.ErrorIs(t, merge(changed, database.ConflictReject), database.ErrConflict)
+	require.NoError(t, merge(stored, database.ConflictReject))
+	require.Equal(t, stored, get("AEAJM"))
+
+	_, err := s.Sources(ctx, "ZZZZZ")
+	require.ErrorIs(t, err, database.ErrNotFound)
+
+	require.NoError(t, merge(changed, database.ConflictFillEmpty))
+	require.Equal(t, database.Port{Key: "AEAJM", Name: "Ajman", City: "Ajman", Unlocs: []string{"AEAJM"}, Alias: []string{"Ajman"}}, get("AEAJM"))
+
+	require.NoError(t, merge(changed, database.ConflictUpdateOnly))
+	require.Equal(t, changed, get("AEAJM"))
+
+	require.NoError(t, merge(extra, database.ConflictInsertOnly))
+	require.Equal(t, extra, get("ZZZZZ"))
+}
+
+func testFillEmptySources(t *testing.T, s storage.Storage) {
+	ctx := context.Background()
+	precedence := database.Precedence{Order: []string{"geo", "supplier", "registry"}}
+	merge := func(p database.Port, source string, c database.Conflict) {
+		require.NoError(t, s.Merge(ctx, &p, database.MergeOptions{Source: source, Precedence: precedence, Conflict: c}))
+	}
+
+	merge(database.Port{Key: "AEAJM", Name: "Ajman", Country: "United Arab Emirates", Unlocs: []string{"AEAJM"}, Alias: []string{}}, "registry", "")
+	merge(database.Port{Key: "AEAJM", Name: "Ajman Port", City: "Ajman", Unlocs: []string{"AEAJX"}, Alias: []string{"Ajman"}}, "supplier", database.ConflictFillEmpty)
+
+	want := database.Port{Key: "AEAJM", Name: "Ajman", City: "Ajman", Country: "United Arab Emirates", Unlocs: []string{"AEAJM"}, Alias: []string{"Ajman"}}
+	got, err := s.Get(ctx, "AEAJM")
+	require.NoError(t, err)
+	require.Equal(t, want, *got)
+
+	sources, err := s.Sources(ctx, "AEAJM")
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	require.Equal(t, "supplier", sources[1].Source)
+	require.Equal(t, database.Port{Key: "AEAJM", City: "Ajman", Unlocs: []string{}, Alias: []string{"Ajman"}}, sources[1].Port, "only the filled fields are recorded")
+
+	// The values the fill-empty import did not write stay out of the later merges.
+	merge(database.Port{Key: "AEAJM", Timezone: "Asia/Dubai", Unlocs: []string{}, Alias: []string{}}, "geo", "")
+
+	want.Timezone = "Asia/Dubai"
+	got, err = s.Get(ctx, "AEAJM")
+	require.NoError(t, err)
+	require.Equal(t, want, *got)
+}
+
+func testCheck(t *testing.T, s storage.Storage) {
+	require.NoError(t, s.Check(context.Background()))
+}
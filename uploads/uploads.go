@@ -0,0 +1,329 @@
+// Package uploads includes the resumable upload sessions shared by the servers.
+package uploads
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/rs/zerolog/log"
+)
+
+// gcInterval is the time between two abandoned sessions collections.
+const gcInterval = time.Minute
+
+var (
+	// ErrNotFound is returned for unknown or expired sessions.
+	ErrNotFound = errors.New("upload session not found")
+	// ErrOffset is returned when data is not appended at the committed offset.
+	ErrOffset = errors.New("offset does not match the committed offset")
+	// ErrTooLarge is returned when data exceeds the session size, or a session the maximum size.
+	ErrTooLarge = errors.New("upload too large")
+	// ErrIncomplete is returned when opening a session not received in full.
+	ErrIncomplete = errors.New("upload incomplete")
+	// ErrLocked is returned when the session is in use by another request.
+	ErrLocked = errors.New("upload session in use")
+)
+
+// Session represents the state of a resumable upload.
+type Session struct {
+	ID string
+	// Size is the total upload size in bytes.
+	Size int64
+	// Offset is the number of bytes received and committed so far.
+	Offset   int64
+	Metadata string
+	// ExpiresAt is the time the session is collected if it gets no more data.
+	ExpiresAt time.Time
+}
+
+// Complete tells whether every byte was received.
+func (s Session) Complete() bool {
+	return s.Offset == s.Size
+}
+
+type entry struct {
+	Session
+	busy bool
+}
+
+// Store keeps the upload sessions data in a directory, it is safe for concurrent use.
+type Store struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+
+	mu       sync.Mutex
+	sessions map[string]*entry
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewStore instantiates a Store in a new directory under the configured one, and starts collecting abandoned sessions.
+func NewStore(cfg config.Uploads) (*Store, error) {
+	dir, err := os.MkdirTemp(cfg.Dir, "ports-uploads")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		dir:      dir,
+		ttl:      cfg.SessionTTL,
+		maxSize:  int64(cfg.MaxSize),
+		sessions: make(map[string]*entry),
+		done:     make(chan struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.gc()
+
+	return s, nil
+}
+
+// MaxSize returns the maximum session size in bytes.
+func (s *Store) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Create starts a session of a given size.
+func (s *Store) Create(size int64, metadata string) (Session, error) {
+	if size <= 0 {
+		return Session{}, errors.New("upload size must be positive")
+	}
+	if size > s.maxSize {
+		return Session{}, fmt.Errorf("%w: maximum size is %d bytes", ErrTooLarge, s.maxSize)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return Session{}, err
+	}
+
+	f, err := os.Create(s.path(id))
+	if err != nil {
+		return Session{}, err
+	}
+	if err := f.Close(); err != nil {
+		return Session{}, err
+	}
+
+	e := &entry{Session: Session{ID: id, Size: size, Metadata: metadata, ExpiresAt: time.Now().Add(s.ttl)}}
+
+	s.mu.Lock()
+	s.sessions[id] = e
+	s.mu.Unlock()
+
+	return e.Session, nil
+}
+
+// Get returns a session state.
+func (s *Store) Get(id string) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.entry(id)
+	if err != nil {
+		return Session{}, err
+	}
+	return e.Session, nil
+}
+
+// Append writes the data read from r at the committed offset, which must match offset.
+// The bytes written before a read error stay committed, so the upload can be resumed from there,
+// whereas data exceeding the session size is rejected as a whole, leaving the offset as is.
+func (s *Store) Append(id string, offset int64, r io.Reader) (Session, error) {
+	e, err := s.acquire(id)
+	if err != nil {
+		return Session{}, err
+	}
+	defer s.release(e)
+
+	if offset != e.Offset {
+		return e.Session, fmt.Errorf("%w: got %d, committed %d", ErrOffset, offset, e.Offset)
+	}
+
+	f, err := os.OpenFile(s.path(id), os.O_WRONLY, 0)
+	if err != nil {
+		return e.Session, err
+	}
+	defer safeClose(f)
+
+	if _, err := f.Seek(e.Offset, io.SeekStart); err != nil {
+		return e.Session, err
+	}
+
+	// Reading one byte more than the remaining size detects oversized data.
+	remaining := e.Size - e.Offset
+	n, err := io.Copy(f, io.LimitReader(r, remaining+1))
+	if n > remaining {
+		n = 0
+		err = ErrTooLarge
+		if terr := f.Truncate(e.Offset); terr != nil {
+			err = terr
+		}
+	}
+
+	s.mu.Lock()
+	e.Offset += n
+	e.ExpiresAt = time.Now().Add(s.ttl)
+	out := e.Session
+	s.mu.Unlock()
+
+	return out, err
+}
+
+// Open returns a complete session data, and a function to call once done with it, which removes the session
+// or keeps it to be opened again, extending its expiration.
+func (s *Store) Open(id string) (*os.File, func(remove bool), error) {
+	e, err := s.acquire(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !e.Complete() {
+		s.release(e)
+		return nil, nil, fmt.Errorf("%w: received %d of %d bytes", ErrIncomplete, e.Offset, e.Size)
+	}
+
+	f, err := os.Open(s.path(id))
+	if err != nil {
+		s.release(e)
+		return nil, nil, err
+	}
+
+	return f, func(remove bool) {
+		safeClose(f)
+		if remove {
+			s.remove(id)
+			return
+		}
+
+		s.mu.Lock()
+		e.busy = false
+		e.ExpiresAt = time.Now().Add(s.ttl)
+		s.mu.Unlock()
+	}, nil
+}
+
+// Remove deletes a session and its data.
+func (s *Store) Remove(id string) error {
+	e, err := s.acquire(id)
+	if err != nil {
+		return err
+	}
+
+	s.remove(e.ID)
+	return nil
+}
+
+// Close stops collecting sessions and deletes every session data.
+func (s *Store) Close() error {
+	close(s.done)
+	s.wg.Wait()
+
+	return os.RemoveAll(s.dir)
+}
+
+func (s *Store) entry(id string) (*entry, error) {
+	e, ok := s.sessions[id]
+	if !ok || time.Now().After(e.ExpiresAt) {
+		return nil, ErrNotFound
+	}
+	return e, nil
+}
+
+// acquire marks a session in use, failing if it already is.
+func (s *Store) acquire(id string) (*entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, err := s.entry(id)
+	if err != nil {
+		return nil, err
+	}
+	if e.busy {
+		return nil, ErrLocked
+	}
+
+	e.busy = true
+	return e, nil
+}
+
+func (s *Store) release(e *entry) {
+	s.mu.Lock()
+	e.busy = false
+	s.mu.Unlock()
+}
+
+func (s *Store) remove(id string) {
+	s.mu.Lock()
+	delete(s.sessions, id)
+	s.mu.Unlock()
+
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Error().Err(err).Msg("Upload session removal failed")
+	}
+}
+
+// gc periodically removes the expired sessions not in use.
+func (s *Store) gc() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(gcInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.collect(time.Now())
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Store) collect(now time.Time) {
+	var expired []string
+
+	s.mu.Lock()
+	for id, e := range s.sessions {
+		if !e.busy && now.After(e.ExpiresAt) {
+			expired = append(expired, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range expired {
+		s.remove(id)
+	}
+
+	if len(expired) > 0 {
+		log.Info().Int("sessions", len(expired)).Msg("Abandoned upload sessions collected")
+	}
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func safeClose(c io.Closer) {
+	if err := c.Close(); err != nil {
+		log.Error().Err(err).Msg("Close failed")
+	}
+}
@@ -0,0 +1,113 @@
+package uploads
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/agukrapo/ports/config"
+	"github.com/stretchr/testify/require"
+)
+
+func newStore(t *testing.T) *Store {
+	s, err := NewStore(config.Uploads{Dir: t.TempDir(), SessionTTL: time.Hour, MaxSize: 100})
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, s.Close()) })
+	return s
+}
+
+func TestStore_resume(t *testing.T) {
+	s := newStore(t)
+
+	_, err := s.Create(101, "")
+	require.ErrorIs(t, err, ErrTooLarge)
+
+	session, err := s.Create(10, "meta")
+	require.NoError(t, err)
+
+	_, err = s.Append(session.ID, 0, io.MultiReader(strings.NewReader("0123"), iotest.ErrReader(errors.New("dropped"))))
+	require.EqualError(t, err, "dropped")
+
+	got, err := s.Get(session.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), got.Offset)
+	require.Equal(t, "meta", got.Metadata)
+
+	_, err = s.Append(session.ID, 0, strings.NewReader("0123"))
+	require.ErrorIs(t, err, ErrOffset)
+
+	_, _, err = s.Open(session.ID)
+	require.ErrorIs(t, err, ErrIncomplete)
+
+	got, err = s.Append(session.ID, 4, strings.NewReader("456789"))
+	require.NoError(t, err)
+	require.True(t, got.Complete())
+
+	f, done, err := s.Open(session.ID)
+	require.NoError(t, err)
+
+	_, _, err = s.Open(session.ID)
+	require.ErrorIs(t, err, ErrLocked)
+
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(data))
+
+	done(false)
+	f, done, err = s.Open(session.ID)
+	require.NoError(t, err)
+
+	data, err = io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(data))
+
+	done(true)
+	_, err = s.Get(session.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_Append_tooLarge(t *testing.T) {
+	s := newStore(t)
+
+	session, err := s.Create(4, "")
+	require.NoError(t, err)
+
+	got, err := s.Append(session.ID, 0, strings.NewReader("01"))
+	require.NoError(t, err)
+
+	// The oversized chunk is not committed, the upload resumes from the previous offset.
+	got, err = s.Append(session.ID, 2, strings.NewReader("23456"))
+	require.ErrorIs(t, err, ErrTooLarge)
+	require.Equal(t, int64(2), got.Offset)
+
+	_, _, err = s.Open(session.ID)
+	require.ErrorIs(t, err, ErrIncomplete)
+
+	got, err = s.Append(session.ID, 2, strings.NewReader("23"))
+	require.NoError(t, err)
+	require.True(t, got.Complete())
+
+	f, done, err := s.Open(session.ID)
+	require.NoError(t, err)
+	defer done(true)
+
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "0123", string(data))
+}
+
+func TestStore_collect(t *testing.T) {
+	s := newStore(t)
+
+	session, err := s.Create(4, "")
+	require.NoError(t, err)
+
+	s.collect(time.Now().Add(2 * time.Hour))
+
+	_, err = s.Get(session.ID)
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoFileExists(t, s.path(session.ID))
+}
@@ -0,0 +1,208 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/agukrapo/ports/database"
+	"github.com/rs/zerolog/log"
+)
+
+// Delivery request headers.
+const (
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	// claimBatch is the number of deliveries attempted concurrently.
+	claimBatch = 20
+	// maxBackoff caps the delay between two attempts of a delivery.
+	maxBackoff    = time.Hour
+	pruneInterval = time.Hour
+	// maxResponse is the number of response body bytes kept in a failed delivery error.
+	maxResponse = 256
+)
+
+// Sign returns the signature of a payload sent at a Unix time: "t=<time>,v1=<hex HMAC-SHA256 of "<time>.<payload>">".
+func Sign(secret string, timestamp int64, payload []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(payload)
+
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Run attempts the due deliveries every poll interval until the context is done, and prunes the delivery log.
+func (r *Registry) Run(ctx context.Context) {
+	if !r.Enabled() {
+		return
+	}
+
+	client := &http.Client{Timeout: r.cfg.Timeout}
+
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	var pruned time.Time
+	for {
+		if err := r.Dispatch(ctx, client); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("Webhook dispatch failed")
+		}
+
+		if r.cfg.Retention > 0 && time.Since(pruned) >= pruneInterval {
+			r.prune(ctx)
+			pruned = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch attempts the due deliveries until none is left.
+// It returns once the attempts already started are saved, even when failing.
+func (r *Registry) Dispatch(ctx context.Context, client *http.Client) error {
+	for {
+		// A claimed delivery is not attempted again before the current attempt times out.
+		deliveries, err := r.store.ClaimDeliveries(ctx, time.Now(), 2*client.Timeout, claimBatch)
+		if err != nil {
+			return err
+		}
+
+		hooks := make(map[string]*database.Webhook)
+		var wg sync.WaitGroup
+
+		for i := range deliveries {
+			d := &deliveries[i]
+
+			hook, ok := hooks[d.WebhookID]
+			if !ok {
+				hook, err = r.store.GetWebhook(ctx, d.WebhookID)
+				if errors.Is(err, database.ErrNotFound) {
+					// Deleted along with its deliveries.
+					err = nil
+					continue
+				}
+				if err != nil {
+					break
+				}
+				hooks[d.WebhookID] = hook
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				r.attempt(ctx, client, hook, d)
+
+				if err := r.store.SaveDelivery(ctx, d); err != nil {
+					log.Error().Err(err).Int64("delivery", d.ID).Msg("Webhook delivery save failed")
+				}
+			}()
+		}
+
+		wg.Wait()
+
+		if err != nil {
+			return err
+		}
+		if len(deliveries) < claimBatch || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// attempt posts a delivery payload, recording the outcome and scheduling the next attempt on failure.
+func (r *Registry) attempt(ctx context.Context, client *http.Client, hook *database.Webhook, d *database.Delivery) {
+	d.Attempts++
+	d.ResponseCode = 0
+	d.Error = ""
+
+	err := post(ctx, client, hook, d)
+	now := time.Now().UTC()
+
+	switch {
+	case err == nil:
+		d.Status = database.DeliveryDelivered
+		d.DeliveredAt = &now
+	case d.Attempts >= r.cfg.MaxAttempts:
+		d.Status = database.DeliveryFailed
+		d.Error = err.Error()
+		log.Warn().Err(err).Str("webhook", hook.ID).Int64("delivery", d.ID).Int("attempts", d.Attempts).Msg("Webhook delivery failed")
+	default:
+		d.Error = err.Error()
+		d.NextAttemptAt = now.Add(backoff(r.cfg.RetryBackoff, d.Attempts))
+	}
+}
+
+func post(ctx context.Context, client *http.Client, hook *database.Webhook, d *database.Delivery) error {
+	payload := []byte(d.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, time.Now().Unix(), payload))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	d.ResponseCode = res.StatusCode
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxResponse))
+		return fmt.Errorf("unexpected status %s: %s", res.Status, bytes.TrimSpace(body))
+	}
+
+	return nil
+}
+
+// backoff returns the delay before the next attempt of a delivery, doubled after each one and jittered.
+func backoff(base time.Duration, attempts int) time.Duration {
+	d := maxBackoff
+	if shift := attempts - 1; shift < 32 && base<<shift < maxBackoff {
+		d = base << shift
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (r *Registry) prune(ctx context.Context) {
+	n, err := r.store.PruneDeliveries(ctx, time.Now().Add(-r.cfg.Retention))
+	if err != nil {
+		log.Error().Err(err).Msg("Webhook deliveries prune failed")
+		return
+	}
+
+	if n > 0 {
+		log.Info().Int64("deliveries", n).Msg("Webhook deliveries pruned")
+	}
+}
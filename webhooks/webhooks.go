@@ -0,0 +1,307 @@
+// Package webhooks includes the registry of the webhook subscriptions and the dispatcher of their deliveries.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/agukrapo/ports/cdc"
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/imports"
+	"github.com/rs/zerolog/log"
+)
+
+// Event types.
+const (
+	ImportCompleted = "import.completed"
+	ImportFailed    = "import.failed"
+	PortChanged     = "port.changed"
+)
+
+// notifyTimeout bounds the queueing of the deliveries of an import event.
+const notifyTimeout = 5 * time.Second
+
+var (
+	// ErrInvalid is returned when a Webhook fails validation.
+	ErrInvalid = errors.New("invalid webhook")
+	// ErrNotFound is returned when a Webhook does not exist.
+	ErrNotFound = database.ErrNotFound
+)
+
+// Store represents the storage of the webhooks and their deliveries, implemented by the database and memory backends.
+type Store interface {
+	CreateWebhook(context.Context, *database.Webhook) error
+	Webhooks(context.Context) ([]database.Webhook, error)
+	GetWebhook(context.Context, string) (*database.Webhook, error)
+	UpdateWebhook(context.Context, *database.Webhook) error
+	DeleteWebhook(context.Context, string) error
+	InsertDeliveries(context.Context, []database.Delivery) error
+	ClaimDeliveries(context.Context, time.Time, time.Duration, int) ([]database.Delivery, error)
+	SaveDelivery(context.Context, *database.Delivery) error
+	Deliveries(context.Context, string, int) ([]database.Delivery, error)
+	PruneDeliveries(context.Context, time.Time) (int64, error)
+}
+
+// Input represents the fields of a Webhook set by its owner, an empty Secret being generated on creation
+// and kept on update.
+type Input struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+func (in Input) validate() error {
+	u, err := url.Parse(in.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalid)
+	}
+
+	if len(in.Events) == 0 {
+		return fmt.Errorf("%w: events must not be empty", ErrInvalid)
+	}
+
+	for _, e := range in.Events {
+		switch e {
+		case ImportCompleted, ImportFailed, PortChanged:
+		default:
+			return fmt.Errorf("%w: unknown event %q, must be %s, %s or %s", ErrInvalid, e, ImportCompleted, ImportFailed, PortChanged)
+		}
+	}
+
+	return nil
+}
+
+// Payload represents the JSON body posted to the webhooks, its ID being shared by the deliveries of a same event.
+type Payload struct {
+	ID    string          `json:"id"`
+	Event string          `json:"event"`
+	Time  time.Time       `json:"time"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Registry manages the webhooks and queues their deliveries, it is a no-op without a Store.
+type Registry struct {
+	store Store
+	cfg   config.Webhooks
+}
+
+// New instantiates a new Registry, a nil Store disabling it.
+func New(store Store, cfg config.Webhooks) *Registry {
+	return &Registry{store: store, cfg: cfg}
+}
+
+// Enabled tells whether the Registry has a Store.
+func (r *Registry) Enabled() bool {
+	return r != nil && r.store != nil
+}
+
+// Create stores a new Webhook, returned along with its secret.
+func (r *Registry) Create(ctx context.Context, in Input) (*database.Webhook, error) {
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+
+	if in.Secret == "" {
+		in.Secret = newID(32)
+	}
+
+	now := time.Now().UTC()
+	hook := &database.Webhook{
+		ID:        newID(16),
+		URL:       in.URL,
+		Events:    in.Events,
+		Secret:    in.Secret,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := r.store.CreateWebhook(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	return hook, nil
+}
+
+// List returns every Webhook, without its secret.
+func (r *Registry) List(ctx context.Context) ([]database.Webhook, error) {
+	out, err := r.store.Webhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range out {
+		out[i].Secret = ""
+	}
+
+	return out, nil
+}
+
+// Get returns the Webhook with the given ID, without its secret, or ErrNotFound.
+func (r *Registry) Get(ctx context.Context, id string) (*database.Webhook, error) {
+	out, err := r.store.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Secret = ""
+	return out, nil
+}
+
+// Update replaces the fields of a Webhook, returned without its secret, or returns ErrNotFound.
+func (r *Registry) Update(ctx context.Context, id string, in Input) (*database.Webhook, error) {
+	if err := in.validate(); err != nil {
+		return nil, err
+	}
+
+	hook, err := r.store.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	hook.URL = in.URL
+	hook.Events = in.Events
+	if in.Secret != "" {
+		hook.Secret = in.Secret
+	}
+	hook.UpdatedAt = time.Now().UTC()
+
+	if err := r.store.UpdateWebhook(ctx, hook); err != nil {
+		return nil, err
+	}
+
+	hook.Secret = ""
+	return hook, nil
+}
+
+// Delete removes the Webhook with the given ID and its deliveries, or returns ErrNotFound.
+func (r *Registry) Delete(ctx context.Context, id string) error {
+	return r.store.DeleteWebhook(ctx, id)
+}
+
+// Deliveries returns the last deliveries of a Webhook, newest first, or ErrNotFound.
+func (r *Registry) Deliveries(ctx context.Context, id string, limit int) ([]database.Delivery, error) {
+	if _, err := r.store.GetWebhook(ctx, id); err != nil {
+		return nil, err
+	}
+
+	return r.store.Deliveries(ctx, id, limit)
+}
+
+// Notify queues a delivery of an event to every Webhook subscribed to it.
+func (r *Registry) Notify(ctx context.Context, event string, data interface{}) error {
+	return r.notify(ctx, func(emit func(string, interface{}) error) error {
+		return emit(event, data)
+	})
+}
+
+// notify queues the deliveries of the events emitted by fn, reading the webhooks once.
+func (r *Registry) notify(ctx context.Context, fn func(emit func(string, interface{}) error) error) error {
+	if !r.Enabled() {
+		return nil
+	}
+
+	hooks, err := r.store.Webhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	var deliveries []database.Delivery
+
+	emit := func(event string, data interface{}) error {
+		var payload []byte
+		for _, h := range hooks {
+			if !subscribed(h, event) {
+				continue
+			}
+
+			if payload == nil {
+				raw, err := json.Marshal(data)
+				if err != nil {
+					return err
+				}
+
+				if payload, err = json.Marshal(Payload{ID: newID(16), Event: event, Time: now, Data: raw}); err != nil {
+					return err
+				}
+			}
+
+			deliveries = append(deliveries, database.Delivery{
+				WebhookID:     h.ID,
+				Event:         event,
+				Payload:       string(payload),
+				Status:        database.DeliveryPending,
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			})
+		}
+		return nil
+	}
+
+	if err := fn(emit); err != nil {
+		return err
+	}
+
+	return r.store.InsertDeliveries(ctx, deliveries)
+}
+
+func subscribed(h database.Webhook, event string) bool {
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportFinished notifies the import.completed or import.failed event, it is meant to observe an imports.Registry.
+func (r *Registry) ImportFinished(s imports.State) {
+	if !r.Enabled() {
+		return
+	}
+
+	event := ImportCompleted
+	if s.Status == imports.Failed {
+		event = ImportFailed
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	if err := r.Notify(ctx, event, s); err != nil {
+		log.Error().Err(err).Str("import", s.ID).Msg("Webhook notification failed")
+	}
+}
+
+// Publish notifies the port.changed event for every change, the Registry being a cdc.Publisher.
+func (r *Registry) Publish(ctx context.Context, events []*cdc.Event) error {
+	return r.notify(ctx, func(emit func(string, interface{}) error) error {
+		for _, e := range events {
+			if err := emit(PortChanged, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close does nothing.
+func (r *Registry) Close() error {
+	return nil
+}
+
+// newID returns a random hex string of n bytes.
+func newID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
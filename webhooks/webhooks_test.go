@@ -0,0 +1,285 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agukrapo/ports/cdc"
+	"github.com/agukrapo/ports/config"
+	"github.com/agukrapo/ports/database"
+	"github.com/agukrapo/ports/imports"
+	"github.com/agukrapo/ports/service"
+	"github.com/agukrapo/ports/storage/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func sqliteStore(t *testing.T) Store {
+	cfg := config.Default().Database
+	cfg.DSN = "sqlite://" + filepath.Join(t.TempDir(), "ports.db")
+
+	m, err := database.NewMigrator(cfg)
+	require.NoError(t, err)
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+
+	db, err := database.New(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, db.Close()) })
+
+	return db
+}
+
+var stores = map[string]func(*testing.T) Store{
+	"memory": func(*testing.T) Store { return memory.New() },
+	"sqlite": sqliteStore,
+}
+
+func TestRegistry_crud(t *testing.T) {
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			r := New(open(t), config.Default().Webhooks)
+
+			_, err := r.Create(ctx, Input{URL: "ftp://example.com", Events: []string{ImportCompleted}})
+			require.ErrorIs(t, err, ErrInvalid)
+			_, err = r.Create(ctx, Input{URL: "https://example.com"})
+			require.ErrorIs(t, err, ErrInvalid)
+			_, err = r.Create(ctx, Input{URL: "https://example.com", Events: []string{"import.started"}})
+			require.ErrorIs(t, err, ErrInvalid)
+
+			hook, err := r.Create(ctx, Input{URL: "https://example.com/hook", Events: []string{ImportCompleted, PortChanged}})
+			require.NoError(t, err)
+			require.NotEmpty(t, hook.ID)
+			require.Len(t, hook.Secret, 64)
+
+			got, err := r.Get(ctx, hook.ID)
+			require.NoError(t, err)
+			require.Equal(t, hook.URL, got.URL)
+			require.Equal(t, []string{ImportCompleted, PortChanged}, []string(got.Events))
+			require.Empty(t, got.Secret)
+
+			updated, err := r.Update(ctx, hook.ID, Input{URL: "https://example.com/other", Events: []string{ImportFailed}})
+			require.NoError(t, err)
+			require.Equal(t, "https://example.com/other", updated.URL)
+
+			stored, err := r.store.GetWebhook(ctx, hook.ID)
+			require.NoError(t, err)
+			require.Equal(t, hook.Secret, stored.Secret, "an empty secret keeps the current one")
+
+			list, err := r.List(ctx)
+			require.NoError(t, err)
+			require.Len(t, list, 1)
+			require.Equal(t, []string{ImportFailed}, []string(list[0].Events))
+			require.Empty(t, list[0].Secret)
+
+			require.NoError(t, r.Delete(ctx, hook.ID))
+			require.ErrorIs(t, r.Delete(ctx, hook.ID), ErrNotFound)
+			_, err = r.Get(ctx, hook.ID)
+			require.ErrorIs(t, err, ErrNotFound)
+			_, err = r.Update(ctx, hook.ID, Input{URL: "https://example.com", Events: []string{ImportFailed}})
+			require.ErrorIs(t, err, ErrNotFound)
+			_, err = r.Deliveries(ctx, hook.ID, 10)
+			require.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+}
+
+// receiver records the requests posted to it, failing the first ones of an event.
+type receiver struct {
+	mu       sync.Mutex
+	event    string
+	failures int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.requests = append(rc.requests, req)
+	rc.bodies = append(rc.bodies, body)
+
+	if rc.failures > 0 && req.Header.Get(EventHeader) == rc.event {
+		rc.failures--
+		http.Error(w, "try later", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestRegistry_Dispatch(t *testing.T) {
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			rc := &receiver{event: ImportCompleted, failures: 1}
+			srv := httptest.NewServer(rc)
+			defer srv.Close()
+
+			cfg := config.Default().Webhooks
+			cfg.RetryBackoff = 0
+			r := New(open(t), cfg)
+
+			imported, err := r.Create(ctx, Input{URL: srv.URL, Events: []string{ImportCompleted}, Secret: "s3cr3t"})
+			require.NoError(t, err)
+			changed, err := r.Create(ctx, Input{URL: srv.URL, Events: []string{PortChanged}})
+			require.NoError(t, err)
+
+			r.ImportFinished(imports.State{ID: "imp", Status: imports.Completed, Summary: &imports.Summary{Report: service.Report{Upserted: 2}}})
+			r.ImportFinished(imports.State{ID: "imp2", Status: imports.Failed})
+			require.NoError(t, r.Publish(ctx, []*cdc.Event{{ID: 1, Key: "AEAJM", Type: database.ChangeCreate}}))
+
+			client := &http.Client{Timeout: time.Second}
+			require.NoError(t, r.Dispatch(ctx, client))
+			require.Len(t, rc.requests, 2)
+
+			deliveries, err := r.Deliveries(ctx, imported.ID, 10)
+			require.NoError(t, err)
+			require.Len(t, deliveries, 1)
+			d := deliveries[0]
+			require.Equal(t, database.DeliveryPending, d.Status)
+			require.Equal(t, 1, d.Attempts)
+			require.Equal(t, http.StatusServiceUnavailable, d.ResponseCode)
+			require.Contains(t, d.Error, "try later")
+
+			require.NoError(t, r.Dispatch(ctx, client))
+			require.Len(t, rc.requests, 3)
+
+			deliveries, err = r.Deliveries(ctx, imported.ID, 10)
+			require.NoError(t, err)
+			d = deliveries[0]
+			require.Equal(t, database.DeliveryDelivered, d.Status)
+			require.Equal(t, 2, d.Attempts)
+			require.NotNil(t, d.DeliveredAt)
+
+			req, body := rc.requests[2], rc.bodies[2]
+			require.Equal(t, ImportCompleted, req.Header.Get(EventHeader))
+			require.Equal(t, strconv.FormatInt(d.ID, 10), req.Header.Get(DeliveryHeader))
+
+			var ts int64
+			_, err = fmt.Sscanf(req.Header.Get(SignatureHeader), "t=%d,", &ts)
+			require.NoError(t, err)
+			require.Equal(t, Sign("s3cr3t", ts, body), req.Header.Get(SignatureHeader))
+
+			var payload Payload
+			require.NoError(t, json.Unmarshal(body, &payload))
+			require.Equal(t, ImportCompleted, payload.Event)
+			require.JSONEq(t, `"imp"`, string(mustField(t, payload.Data, "id")))
+
+			deliveries, err = r.Deliveries(ctx, changed.ID, 10)
+			require.NoError(t, err)
+			require.Len(t, deliveries, 1)
+			require.Equal(t, PortChanged, deliveries[0].Event)
+			require.Equal(t, database.DeliveryDelivered, deliveries[0].Status)
+		})
+	}
+}
+
+func TestRegistry_Dispatch_maxAttempts(t *testing.T) {
+	ctx := context.Background()
+
+	rc := &receiver{event: ImportFailed, failures: 10}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	cfg := config.Default().Webhooks
+	cfg.RetryBackoff = 0
+	cfg.MaxAttempts = 2
+	r := New(memory.New(), cfg)
+
+	hook, err := r.Create(ctx, Input{URL: srv.URL, Events: []string{ImportFailed}})
+	require.NoError(t, err)
+	require.NoError(t, r.Notify(ctx, ImportFailed, map[string]string{"id": "imp"}))
+
+	client := &http.Client{Timeout: time.Second}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, r.Dispatch(ctx, client))
+	}
+	require.Len(t, rc.requests, 2)
+
+	deliveries, err := r.Deliveries(ctx, hook.ID, 10)
+	require.NoError(t, err)
+	require.Equal(t, database.DeliveryFailed, deliveries[0].Status)
+	require.Equal(t, 2, deliveries[0].Attempts)
+}
+
+// failingStore fails the lookups of a webhook.
+type failingStore struct {
+	Store
+	id string
+}
+
+func (f failingStore) GetWebhook(ctx context.Context, id string) (*database.Webhook, error) {
+	if id == f.id {
+		return nil, errors.New("connection lost")
+	}
+	return f.Store.GetWebhook(ctx, id)
+}
+
+func TestRegistry_Dispatch_storeFailure(t *testing.T) {
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	store := memory.New()
+	r := New(store, config.Default().Webhooks)
+
+	completed, err := r.Create(ctx, Input{URL: srv.URL, Events: []string{ImportCompleted}})
+	require.NoError(t, err)
+	failed, err := r.Create(ctx, Input{URL: srv.URL, Events: []string{ImportFailed}})
+	require.NoError(t, err)
+
+	require.NoError(t, r.Notify(ctx, ImportCompleted, map[string]string{"id": "imp"}))
+	require.NoError(t, r.Notify(ctx, ImportFailed, map[string]string{"id": "imp2"}))
+
+	r = New(failingStore{Store: store, id: failed.ID}, config.Default().Webhooks)
+	require.EqualError(t, r.Dispatch(ctx, &http.Client{Timeout: time.Second}), "connection lost")
+
+	// The attempt started before the failure is saved by the time Dispatch returns.
+	deliveries, err := r.Deliveries(ctx, completed.ID, 10)
+	require.NoError(t, err)
+	require.Equal(t, database.DeliveryDelivered, deliveries[0].Status)
+}
+
+func TestRegistry_disabled(t *testing.T) {
+	r := New(nil, config.Default().Webhooks)
+	require.False(t, r.Enabled())
+	require.NoError(t, r.Notify(context.Background(), ImportCompleted, nil))
+	r.ImportFinished(imports.State{})
+
+	var nilRegistry *Registry
+	require.False(t, nilRegistry.Enabled())
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 40: maxBackoff} {
+		got := backoff(time.Second, attempts)
+		require.GreaterOrEqual(t, got, want/2)
+		require.LessOrEqual(t, got, want)
+	}
+	require.Zero(t, backoff(0, 3))
+}
+
+func mustField(t *testing.T, data json.RawMessage, name string) json.RawMessage {
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(data, &fields))
+	return fields[name]
+}